@@ -10,6 +10,7 @@ type Config struct {
 	DBPath      string
 	CORSOrigins []string
 	APIKey      string
+	ImportDir   string // where uploads for background import jobs are kept
 }
 
 func Load() Config {
@@ -17,6 +18,7 @@ func Load() Config {
 		Port:        ":8080",
 		DBPath:      "budgetting.db",
 		CORSOrigins: []string{"http://localhost:5173"},
+		ImportDir:   "imports",
 	}
 
 	if p := os.Getenv("PORT"); p != "" {
@@ -31,6 +33,9 @@ func Load() Config {
 	if k := os.Getenv("API_KEY"); k != "" {
 		cfg.APIKey = k
 	}
+	if d := os.Getenv("IMPORT_DIR"); d != "" {
+		cfg.ImportDir = d
+	}
 
 	return cfg
 }
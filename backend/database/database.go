@@ -13,15 +13,31 @@ func Connect(dbPath string) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&models.Account{}, &models.Category{}, &models.Transaction{}, &models.BudgetAllocation{}, &models.CategoryTarget{})
+	err = db.AutoMigrate(&models.Account{}, &models.CategoryGroup{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.BudgetAllocation{}, &models.CategoryTarget{}, &models.RecurringTransaction{}, &models.Rule{}, &models.ImportPreview{}, &models.ImportProfile{}, &models.ImportBatch{}, &models.ImportJob{}, &models.ReconciliationCheckpoint{}, &models.BudgetMove{})
 	if err != nil {
 		return nil, err
 	}
 
+	if err := migrateStartingBalances(db); err != nil {
+		return nil, err
+	}
 	seedCategories(db)
 	return db, nil
 }
 
+// migrateStartingBalances marks the "Starting balance" income or expense that
+// account creation used to write as the account's opening balance. Accounts
+// that already have one are left alone.
+func migrateStartingBalances(db *gorm.DB) error {
+	first := db.Model(&models.Transaction{}).Select("MIN(id)").
+		Where("description = ? AND category_id IS NULL AND transfer_id IS NULL AND import_batch_id IS NULL AND recurring_id IS NULL", "Starting balance").
+		Group("account_id")
+	opened := db.Model(&models.Transaction{}).Select("account_id").Where("kind = ?", "opening_balance")
+	return db.Model(&models.Transaction{}).
+		Where("id IN (?) AND account_id NOT IN (?)", first, opened).
+		Update("kind", "opening_balance").Error
+}
+
 func seedCategories(db *gorm.DB) {
 	var count int64
 	db.Model(&models.Category{}).Count(&count)
@@ -29,22 +45,38 @@ func seedCategories(db *gorm.DB) {
 		return
 	}
 
-	defaults := []models.Category{
-		{Name: "Housing", Colour: "#6366F1"},
-		{Name: "Bills & Utilities", Colour: "#EC4899"},
-		{Name: "Food & Drink", Colour: "#F59E0B"},
-		{Name: "Transport", Colour: "#3B82F6"},
-		{Name: "Shopping", Colour: "#8B5CF6"},
-		{Name: "Entertainment", Colour: "#EF4444"},
-		{Name: "Health", Colour: "#10B981"},
-		{Name: "Personal Care", Colour: "#F97316"},
-		{Name: "Education", Colour: "#06B6D4"},
-		{Name: "Savings", Colour: "#22C55E"},
-		{Name: "Salary", Colour: "#14B8A6"},
-		{Name: "Subscriptions", Colour: "#A855F7"},
-		{Name: "Eating Out", Colour: "#D946EF"},
-		{Name: "General", Colour: "#6B7280"},
+	groups := []struct {
+		name       string
+		categories []models.Category
+	}{
+		{"Fixed Bills", []models.Category{
+			{Name: "Housing", Colour: "#6366F1"},
+			{Name: "Bills & Utilities", Colour: "#EC4899"},
+			{Name: "Subscriptions", Colour: "#A855F7"},
+		}},
+		{"Everyday", []models.Category{
+			{Name: "Food & Drink", Colour: "#F59E0B"},
+			{Name: "Eating Out", Colour: "#D946EF"},
+			{Name: "Transport", Colour: "#3B82F6"},
+			{Name: "Shopping", Colour: "#8B5CF6"},
+			{Name: "Entertainment", Colour: "#EF4444"},
+			{Name: "Health", Colour: "#10B981"},
+			{Name: "Personal Care", Colour: "#F97316"},
+			{Name: "Education", Colour: "#06B6D4"},
+			{Name: "General", Colour: "#6B7280"},
+		}},
+		{"Savings Goals", []models.Category{
+			{Name: "Savings", Colour: "#22C55E"},
+		}},
 	}
-
-	db.Create(&defaults)
+	for i, g := range groups {
+		group := models.CategoryGroup{Name: g.name, SortOrder: i}
+		db.Create(&group)
+		for j := range g.categories {
+			g.categories[j].GroupID, g.categories[j].SortOrder = &group.ID, j
+		}
+		db.Create(&g.categories)
+	}
+	// Income isn't budgeted, so it stays out of the groups
+	db.Create(&models.Category{Name: "Salary", Colour: "#14B8A6"})
 }
@@ -3,7 +3,9 @@ package handlers
 import (
 	"budgetting-app/backend/services"
 	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -18,7 +20,7 @@ func NewAccountHandler(svc *services.AccountService) *AccountHandler {
 }
 
 func (h *AccountHandler) List(c *gin.Context) {
-	results, err := h.service.List()
+	results, err := h.service.List(c.Query("include_closed") == "true")
 	if err != nil {
 		respondServerError(c, err, "Failed to list accounts")
 		return
@@ -36,6 +38,14 @@ func (h *AccountHandler) Create(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "Invalid account type. Must be one of: checking, savings, credit, cash")
 		return
 	}
+	if input.OpeningDate != "" && !validateDate(input.OpeningDate) {
+		respondError(c, http.StatusBadRequest, "Invalid opening_date format. Must be YYYY-MM-DD")
+		return
+	}
+	if input.OpeningBalanceBudget != "" && !validateOpeningBalanceBudget(input.OpeningBalanceBudget) {
+		respondError(c, http.StatusBadRequest, "Invalid opening_balance_budget. Must be one of: ready_to_assign, excluded")
+		return
+	}
 	account, err := h.service.Create(input)
 	if err != nil {
 		respondServerError(c, err, "Failed to create account")
@@ -50,8 +60,9 @@ func (h *AccountHandler) Update(c *gin.Context) {
 		return
 	}
 	var input struct {
-		Name string `json:"name" binding:"required"`
-		Type string `json:"type" binding:"required"`
+		Name      string `json:"name" binding:"required"`
+		Type      string `json:"type" binding:"required"`
+		OffBudget *bool  `json:"off_budget"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		respondError(c, http.StatusBadRequest, err.Error())
@@ -61,7 +72,7 @@ func (h *AccountHandler) Update(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "Invalid account type. Must be one of: checking, savings, credit, cash")
 		return
 	}
-	account, err := h.service.Update(id, input.Name, input.Type)
+	account, err := h.service.Update(id, input.Name, input.Type, input.OffBudget)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			respondError(c, http.StatusNotFound, "Account not found")
@@ -73,6 +84,259 @@ func (h *AccountHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, account)
 }
 
+func (h *AccountHandler) SetDefaultImportProfile(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		ProfileID *uint `json:"profile_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	account, err := h.service.SetDefaultImportProfile(id, input.ProfileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account or import profile not found")
+			return
+		}
+		respondServerError(c, err, "Failed to set default import profile")
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *AccountHandler) GetOpeningBalance(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	balance, err := h.service.GetOpeningBalance(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		respondServerError(c, err, "Failed to load opening balance")
+		return
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+func (h *AccountHandler) SetOpeningBalance(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.OpeningBalanceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateDate(input.Date) {
+		respondError(c, http.StatusBadRequest, "Invalid date format. Must be YYYY-MM-DD")
+		return
+	}
+	if input.Budget != "" && !validateOpeningBalanceBudget(input.Budget) {
+		respondError(c, http.StatusBadRequest, "Invalid budget. Must be one of: ready_to_assign, excluded")
+		return
+	}
+	balance, err := h.service.SetOpeningBalance(id, input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		if errors.Is(err, services.ErrTransactionReconciled) {
+			respondError(c, http.StatusConflict, "Opening balance is reconciled. Unlock it to change its amount or date")
+			return
+		}
+		respondServerError(c, err, "Failed to set opening balance")
+		return
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+func (h *AccountHandler) Reconcile(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.ReconcileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateDate(input.StatementDate) {
+		respondError(c, http.StatusBadRequest, "Invalid statement_date. Must be YYYY-MM-DD")
+		return
+	}
+	result, err := h.service.Reconcile(id, input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Account is closed")
+			return
+		}
+		if errors.Is(err, services.ErrReconcileMismatch) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":           "Cleared balance does not match the statement. Use adjust=true to record the difference",
+				"cleared_balance": result.ClearedBalance,
+				"difference":      result.Difference,
+			})
+			return
+		}
+		respondServerError(c, err, "Failed to reconcile account")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *AccountHandler) Close(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Date string `json:"date"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.Date == "" {
+		input.Date = time.Now().Format("2006-01-02")
+	}
+	if !validateDate(input.Date) {
+		respondError(c, http.StatusBadRequest, "Invalid date format. Must be YYYY-MM-DD")
+		return
+	}
+	account, err := h.service.Close(id, input.Date)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		respondServerError(c, err, "Failed to close account")
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *AccountHandler) Reopen(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	account, err := h.service.Reopen(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		respondServerError(c, err, "Failed to reopen account")
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *AccountHandler) Merge(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		TargetID uint `json:"target_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.service.Merge(id, input.TargetID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		if errors.Is(err, services.ErrMergeIntoSelf) {
+			respondError(c, http.StatusBadRequest, "Cannot merge an account into itself")
+			return
+		}
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Target account is closed")
+			return
+		}
+		if errors.Is(err, services.ErrTransactionReconciled) {
+			respondError(c, http.StatusConflict, "The merge would change reconciled transactions. Unlock them first")
+			return
+		}
+		if errors.Is(err, services.ErrMergeBudgetMismatch) {
+			respondError(c, http.StatusBadRequest, "Cannot merge a tracking account with an on-budget account")
+			return
+		}
+		respondServerError(c, err, "Failed to merge accounts")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *AccountHandler) BalanceHistory(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	params := services.BalanceHistoryParams{Interval: c.DefaultQuery("interval", "day"), From: c.Query("from"), To: c.Query("to")}
+	if !validateBalanceInterval(params.Interval) {
+		respondError(c, http.StatusBadRequest, "Invalid interval. Must be one of: day, month")
+		return
+	}
+	if (params.From != "" && !validateDate(params.From)) || (params.To != "" && !validateDate(params.To)) {
+		respondError(c, http.StatusBadRequest, "Invalid date format. Must be YYYY-MM-DD")
+		return
+	}
+	if params.From != "" && params.To != "" && params.From > params.To {
+		respondError(c, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+
+	series, err := h.service.BalanceHistory(id, params)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		if errors.Is(err, services.ErrRangeTooLarge) {
+			respondError(c, http.StatusBadRequest, "Too many points. Narrow the range or use interval=month")
+			return
+		}
+		respondServerError(c, err, "Failed to load balance history")
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+func (h *AccountHandler) Checkpoints(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	checkpoints, err := h.service.Checkpoints(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		respondServerError(c, err, "Failed to list reconciliation checkpoints")
+		return
+	}
+	c.JSON(http.StatusOK, checkpoints)
+}
+
 func (h *AccountHandler) Delete(c *gin.Context) {
 	id, ok := parseID(c)
 	if !ok {
@@ -85,7 +349,7 @@ func (h *AccountHandler) Delete(c *gin.Context) {
 			return
 		}
 		if errors.Is(err, services.ErrAccountHasTransactions) {
-			respondError(c, http.StatusConflict, "Cannot delete account with transactions")
+			respondError(c, http.StatusConflict, "Cannot delete account with transactions. Close it instead")
 			return
 		}
 		respondServerError(c, err, "Failed to delete account")
@@ -209,3 +209,90 @@ func (h *BudgetHandler) AllocateBudget(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+func (h *BudgetHandler) MoveMoney(c *gin.Context) {
+	var input services.MoveMoneyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateMonth(input.Month) {
+		respondError(c, http.StatusBadRequest, "invalid month format (YYYY-MM)")
+		return
+	}
+	if input.Amount <= 0 {
+		respondError(c, http.StatusBadRequest, "amount must be greater than 0")
+		return
+	}
+
+	move, err := h.service.MoveMoney(input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "category not found")
+			return
+		}
+		if errors.Is(err, services.ErrMoveToSelf) {
+			respondError(c, http.StatusBadRequest, "from_category_id and to_category_id must differ")
+			return
+		}
+		if errors.Is(err, services.ErrInsufficientFunds) {
+			respondError(c, http.StatusConflict, "not enough money in the source to move")
+			return
+		}
+		respondServerError(c, err, "Failed to move money")
+		return
+	}
+	c.JSON(http.StatusCreated, move)
+}
+
+func (h *BudgetHandler) ListMoves(c *gin.Context) {
+	month := c.Query("month")
+	if month != "" && !validateMonth(month) {
+		respondError(c, http.StatusBadRequest, "invalid month format (YYYY-MM)")
+		return
+	}
+	var categoryID *uint
+	if s := c.Query("category_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid category_id")
+			return
+		}
+		cid := uint(id)
+		categoryID = &cid
+	}
+
+	moves, err := h.service.ListMoves(month, categoryID)
+	if err != nil {
+		respondServerError(c, err, "Failed to list budget moves")
+		return
+	}
+	c.JSON(http.StatusOK, moves)
+}
+
+func (h *BudgetHandler) AutoAssign(c *gin.Context) {
+	var input services.AutoAssignInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateMonth(input.Month) {
+		respondError(c, http.StatusBadRequest, "invalid month format (YYYY-MM)")
+		return
+	}
+	if !validateAutoAssignStrategy(input.Strategy) {
+		respondError(c, http.StatusBadRequest, "strategy must be one of: underfunded, last_month, average, reset")
+		return
+	}
+
+	result, err := h.service.AutoAssign(input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "category not found")
+			return
+		}
+		respondServerError(c, err, "Failed to auto-assign budget")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
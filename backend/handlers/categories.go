@@ -38,6 +38,10 @@ func (h *CategoryHandler) Create(c *gin.Context) {
 		return
 	}
 	if err := h.service.Create(&category); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Category group not found")
+			return
+		}
 		respondServerError(c, err, "Failed to create category")
 		return
 	}
@@ -88,8 +92,52 @@ func (h *CategoryHandler) Delete(c *gin.Context) {
 			respondError(c, http.StatusConflict, "Cannot delete category with associated data")
 			return
 		}
+		if errors.Is(err, services.ErrPaymentCategory) {
+			respondError(c, http.StatusConflict, "Payment categories are removed with their credit account")
+			return
+		}
 		respondServerError(c, err, "Failed to delete category")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
 }
+
+func (h *CategoryHandler) Merge(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		TargetID    uint   `json:"target_id" binding:"required"`
+		KeepTargets string `json:"keep_targets"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.KeepTargets == "" {
+		input.KeepTargets = "target"
+	}
+	if !validateMergeTargets(input.KeepTargets) {
+		respondError(c, http.StatusBadRequest, "Invalid keep_targets. Must be one of: target, source")
+		return
+	}
+	result, err := h.service.Merge(id, input.TargetID, input.KeepTargets)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Category not found")
+			return
+		}
+		if errors.Is(err, services.ErrMergeIntoSelf) {
+			respondError(c, http.StatusBadRequest, "Cannot merge a category into itself")
+			return
+		}
+		if errors.Is(err, services.ErrPaymentCategory) {
+			respondError(c, http.StatusConflict, "Payment categories are merged with their credit account")
+			return
+		}
+		respondServerError(c, err, "Failed to merge categories")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"budgetting-app/backend/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CategoryGroupHandler struct {
+	service *services.CategoryGroupService
+}
+
+func NewCategoryGroupHandler(svc *services.CategoryGroupService) *CategoryGroupHandler {
+	return &CategoryGroupHandler{service: svc}
+}
+
+func (h *CategoryGroupHandler) List(c *gin.Context) {
+	groups, err := h.service.List()
+	if err != nil {
+		respondServerError(c, err, "Failed to list category groups")
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+func (h *CategoryGroupHandler) Create(c *gin.Context) {
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	group, err := h.service.Create(input.Name)
+	if err != nil {
+		respondServerError(c, err, "Failed to create category group")
+		return
+	}
+	c.JSON(http.StatusCreated, group)
+}
+
+func (h *CategoryGroupHandler) Update(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	group, err := h.service.Update(id, input.Name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Category group not found")
+			return
+		}
+		respondServerError(c, err, "Failed to update category group")
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *CategoryGroupHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Category group not found")
+			return
+		}
+		respondServerError(c, err, "Failed to delete category group")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Category group deleted"})
+}
+
+func (h *CategoryGroupHandler) Reorder(c *gin.Context) {
+	var input struct {
+		GroupIDs []uint `json:"group_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	groups, err := h.service.Reorder(input.GroupIDs)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Category group not found")
+			return
+		}
+		respondServerError(c, err, "Failed to reorder category groups")
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+func (h *CategoryGroupHandler) SetCategories(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		CategoryIDs []uint `json:"category_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	categories, err := h.service.SetCategories(id, input.CategoryIDs)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Category group or category not found")
+			return
+		}
+		respondServerError(c, err, "Failed to set the group's categories")
+		return
+	}
+	c.JSON(http.StatusOK, categories)
+}
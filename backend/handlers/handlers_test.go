@@ -4,14 +4,19 @@ import (
 	"budgetting-app/backend/models"
 	"budgetting-app/backend/services"
 	"budgetting-app/backend/testutil"
+	"bytes"
+	"context"
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -60,6 +65,50 @@ func TestAccountHandler_CreateInvalidType(t *testing.T) {
 	}
 }
 
+func TestAccountHandler_CreateInvalidOpeningBalance(t *testing.T) {
+	r, _ := setupAccountRouter(t)
+
+	for _, body := range []string{
+		`{"name":"Savings","type":"savings","starting_balance":1000,"opening_date":"01/06/2023"}`,
+		`{"name":"Savings","type":"savings","starting_balance":1000,"opening_balance_budget":"sometimes"}`,
+	} {
+		req := httptest.NewRequest("POST", "/accounts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestAccountHandler_UpdateOffBudget(t *testing.T) {
+	r, svc := setupAccountRouter(t)
+	account, _ := svc.Create(services.CreateAccountInput{Name: "Mortgage", Type: "checking"})
+	path := "/accounts/" + strconv.FormatUint(uint64(account.ID), 10)
+
+	for _, tc := range []struct {
+		body string
+		want bool
+	}{
+		{`{"name":"Mortgage","type":"checking","off_budget":true}`, true},
+		{`{"name":"Home loan","type":"checking"}`, true},
+		{`{"name":"Home loan","type":"checking","off_budget":false}`, false},
+	} {
+		req := httptest.NewRequest("PUT", path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.body, w.Code, w.Body.String())
+		}
+		list, _ := svc.List(false)
+		if list[0].OffBudget != tc.want {
+			t.Errorf("%s: expected off_budget %v, got %v", tc.body, tc.want, list[0].OffBudget)
+		}
+	}
+}
+
 func TestAccountHandler_DeleteNotFound(t *testing.T) {
 	r, _ := setupAccountRouter(t)
 
@@ -84,6 +133,125 @@ func TestAccountHandler_InvalidID(t *testing.T) {
 	}
 }
 
+func TestAccountHandler_Reconcile(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	accounts := NewAccountHandler(services.NewAccountService(db))
+	txns := NewTransactionHandler(services.NewTransactionService(db))
+	r := gin.New()
+	r.POST("/accounts/:id/reconcile", accounts.Reconcile)
+	r.PUT("/transactions/:id", txns.Update)
+	r.POST("/transactions/:id/unlock", txns.Unlock)
+
+	account := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&account)
+	txn := models.Transaction{AccountID: account.ID, Amount: 5000, Description: "Deposit", Date: "2024-01-02", Type: "income", Status: "cleared"}
+	db.Create(&txn)
+	path := "/accounts/" + strconv.FormatUint(uint64(account.ID), 10) + "/reconcile"
+	txnPath := "/transactions/" + strconv.FormatUint(uint64(txn.ID), 10)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("POST", path, `{"statement_date":"31/01/2024","statement_balance":5000}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid date, got %d: %s", w.Code, w.Body.String())
+	}
+	w := send("POST", path, `{"statement_date":"2024-01-31","statement_balance":4000}`)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"difference":-1000`) {
+		t.Fatalf("expected 409 with the difference, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send("POST", path, `{"statement_date":"2024-01-31","statement_balance":5000}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := send("PUT", txnPath, `{"amount":6000}`); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 editing a reconciled transaction, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send("PUT", txnPath, `{"status":"reconciled"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 setting reconciled directly, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send("POST", txnPath+"/unlock", ``); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 unlocking, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send("PUT", txnPath, `{"amount":6000}`); w.Code != http.StatusOK {
+		t.Errorf("expected 200 after unlocking, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAccountHandler_BalanceHistoryValidation(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewAccountHandler(services.NewAccountService(db))
+	r := gin.New()
+	r.GET("/accounts/:id/balance-history", h.BalanceHistory)
+	account := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&account)
+	path := "/accounts/" + strconv.FormatUint(uint64(account.ID), 10) + "/balance-history"
+
+	for _, query := range []string{"?interval=week", "?from=2024-13-01", "?from=2024-02-01&to=2024-01-01", "?from=1990-01-01&to=2024-01-01"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/accounts/999/balance-history", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAccountHandler_Close(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewAccountHandler(services.NewAccountService(db))
+	r := gin.New()
+	r.GET("/accounts", h.List)
+	r.POST("/accounts/:id/close", h.Close)
+	r.POST("/accounts/:id/reopen", h.Reopen)
+	account := models.Account{Name: "Old bank", Type: "savings"}
+	db.Create(&account)
+	path := "/accounts/" + strconv.FormatUint(uint64(account.ID), 10)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", path+"/close", strings.NewReader(`{"date":"31/12/2023"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad date, got %d", w.Code)
+	}
+
+	// Without a body the account closes today
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", path+"/close", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var closed models.Account
+	json.Unmarshal(w.Body.Bytes(), &closed)
+	if closed.ClosedDate == nil {
+		t.Error("expected a closed date")
+	}
+
+	for query, want := range map[string]int{"": 0, "?include_closed=true": 1} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/accounts"+query, nil))
+		var list []services.AccountResponse
+		json.Unmarshal(w.Body.Bytes(), &list)
+		if len(list) != want {
+			t.Errorf("GET /accounts%s: expected %d accounts, got %d", query, want, len(list))
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", path+"/reopen", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func setupTransactionRouter(t *testing.T) *gin.Engine {
 	t.Helper()
 	db := testutil.SetupTestDB(t)
@@ -92,9 +260,15 @@ func setupTransactionRouter(t *testing.T) *gin.Engine {
 
 	r := gin.New()
 	r.GET("/transactions", h.List)
+	r.GET("/transactions/export", h.Export)
 	r.POST("/transactions", h.Create)
+	r.POST("/transactions/transfer", h.CreateTransfer)
 	r.PUT("/transactions/:id", h.Update)
 	r.DELETE("/transactions/:id", h.Delete)
+	r.POST("/transactions/import", h.ImportCSV)
+	r.POST("/transactions/import/preview", h.PreviewImport)
+	r.PUT("/transactions/import/preview/:token", h.UpdatePreview)
+	r.POST("/transactions/import/preview/:token/confirm", h.ConfirmPreview)
 	return r
 }
 
@@ -144,6 +318,202 @@ func TestTransactionHandler_UpdateNotFound(t *testing.T) {
 	}
 }
 
+func TestTransactionHandler_CreateTransferSameAccount(t *testing.T) {
+	r := setupTransactionRouter(t)
+
+	body := `{"from_account_id":1,"to_account_id":1,"amount":1000,"date":"2024-01-15"}`
+	req := httptest.NewRequest("POST", "/transactions/transfer", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTransactionHandler_CreateTransferAccountNotFound(t *testing.T) {
+	r := setupTransactionRouter(t)
+
+	body := `{"from_account_id":1,"to_account_id":2,"amount":1000,"date":"2024-01-15"}`
+	req := httptest.NewRequest("POST", "/transactions/transfer", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTransactionHandler_CreateSplitValidation(t *testing.T) {
+	r := setupTransactionRouter(t)
+
+	body := `{"account_id":1,"amount":1000,"description":"Tesco","date":"2024-01-15","type":"expense","splits":[{"amount":1000}]}`
+	req := httptest.NewRequest("POST", "/transactions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for split without category, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTransactionHandler_CreateSplitUnknownCategory(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewTransactionHandler(services.NewTransactionService(db))
+	r := gin.New()
+	r.POST("/transactions", h.Create)
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+
+	body := `{"account_id":` + strconv.FormatUint(uint64(account.ID), 10) + `,"amount":1000,"description":"Tesco","date":"2024-01-15","type":"expense","splits":[{"category_id":99,"amount":1000}]}`
+	req := httptest.NewRequest("POST", "/transactions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown split category, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func newImportRequest(t *testing.T, path string, fields map[string]string, csv string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	fw, err := mw.CreateFormFile("file", "import.csv")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	fw.Write([]byte(csv))
+	mw.Close()
+
+	req := httptest.NewRequest("POST", path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestTransactionHandler_ImportInvalidDuplicateMode(t *testing.T) {
+	r := setupTransactionRouter(t)
+
+	req := newImportRequest(t, "/transactions/import", map[string]string{"account_id": "1", "duplicates": "merge"},
+		"date,description,amount\n2024-01-15,Coffee,-3.50\n")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTransactionHandler_ImportReportsRows(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewTransactionHandler(services.NewTransactionService(db))
+	r := gin.New()
+	r.POST("/transactions/import", h.ImportCSV)
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	accountID := strconv.FormatUint(uint64(account.ID), 10)
+
+	csv := "date,description,amount\n2024-01-15,Coffee,-3.50\n2024-01-16,Lunch,-8.00\n"
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, newImportRequest(t, "/transactions/import", map[string]string{"account_id": accountID}, csv))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	var result services.ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Imported != 0 || result.Skipped != 2 {
+		t.Errorf("expected second import to skip both rows, got %+v", result)
+	}
+	if len(result.Rows) != 2 || result.Rows[1].Status != "skipped" {
+		t.Errorf("expected per-row statuses, got %+v", result.Rows)
+	}
+}
+
+func TestTransactionHandler_ImportOFX(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewTransactionHandler(services.NewTransactionService(db))
+	r := gin.New()
+	r.POST("/transactions/import", h.ImportCSV)
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	fields := map[string]string{"account_id": strconv.FormatUint(uint64(account.ID), 10)}
+	ofx := "OFXHEADER:100\n<OFX><STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240115<TRNAMT>-3.50<FITID>X1<NAME>Coffee</STMTTRN></OFX>"
+
+	for i, want := range []int{1, 0} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newImportRequest(t, "/transactions/import", fields, ofx))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var result services.ImportResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if result.Imported != want {
+			t.Errorf("import %d: expected %d imported, got %+v", i+1, want, result)
+		}
+	}
+}
+
+func TestTransactionHandler_ExportQIF(t *testing.T) {
+	r := setupTransactionRouter(t)
+
+	req := httptest.NewRequest("GET", "/transactions/export", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "transactions.qif") {
+		t.Errorf("expected a QIF attachment, got %q", w.Header().Get("Content-Disposition"))
+	}
+
+	req = httptest.NewRequest("GET", "/transactions/export?format=xlsx", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown format, got %d", w.Code)
+	}
+}
+
+func TestTransactionHandler_ConfirmUnknownPreview(t *testing.T) {
+	r := setupTransactionRouter(t)
+
+	req := httptest.NewRequest("POST", "/transactions/import/preview/nope/confirm", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTransactionHandler_UpdatePreviewValidation(t *testing.T) {
+	r := setupTransactionRouter(t)
+
+	body := `{"rows":[{"row":1,"date":"15/01/2024","description":"Coffee","amount":350,"type":"expense"}]}`
+	req := httptest.NewRequest("PUT", "/transactions/import/preview/abc", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func TestTransactionHandler_ErrorFormat(t *testing.T) {
 	r := setupTransactionRouter(t)
 
@@ -264,6 +634,489 @@ func TestCategoryHandler_InvalidID(t *testing.T) {
 	}
 }
 
+func TestCategoryHandler_Merge(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewCategoryHandler(services.NewCategoryService(db))
+	r := gin.New()
+	r.POST("/categories/:id/merge", h.Merge)
+
+	source := models.Category{Name: "Eating Out", Colour: "#FF0000"}
+	target := models.Category{Name: "Restaurants", Colour: "#00FF00"}
+	db.Create(&source)
+	db.Create(&target)
+	db.Create(&models.BudgetAllocation{Month: "2024-01", CategoryID: source.ID, Amount: 5000})
+	path := "/categories/" + strconv.FormatUint(uint64(source.ID), 10) + "/merge"
+	targetID := strconv.FormatUint(uint64(target.ID), 10)
+
+	for body, code := range map[string]int{
+		`{}`: http.StatusBadRequest,
+		`{"target_id":` + targetID + `,"keep_targets":"both"}`: http.StatusBadRequest,
+		`{"target_id":999}`: http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		if w.Code != code {
+			t.Errorf("%s: expected %d, got %d: %s", body, code, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(`{"target_id":`+targetID+`}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result services.CategoryMergeResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Category.ID != target.ID || result.Allocations != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+// --- Category group handler tests ---
+
+func setupCategoryGroupRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	h := NewCategoryGroupHandler(services.NewCategoryGroupService(db))
+
+	r := gin.New()
+	r.GET("/category-groups", h.List)
+	r.POST("/category-groups", h.Create)
+	r.PUT("/category-groups/order", h.Reorder)
+	r.PUT("/category-groups/:id", h.Update)
+	r.DELETE("/category-groups/:id", h.Delete)
+	r.PUT("/category-groups/:id/categories", h.SetCategories)
+	return r
+}
+
+func TestCategoryGroupHandler_CreateAndReorder(t *testing.T) {
+	r := setupCategoryGroupRouter(t)
+
+	for _, name := range []string{"Fixed Bills", "Everyday"} {
+		req := httptest.NewRequest("POST", "/category-groups", strings.NewReader(`{"name":"`+name+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("PUT", "/category-groups/order", strings.NewReader(`{"group_ids":[2,1]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var groups []models.CategoryGroup
+	json.Unmarshal(w.Body.Bytes(), &groups)
+	if len(groups) != 2 || groups[0].Name != "Everyday" {
+		t.Errorf("expected Everyday first, got %+v", groups)
+	}
+
+	req = httptest.NewRequest("PUT", "/category-groups/1/categories", strings.NewReader(`{"category_ids":[99]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown category, got %d", w.Code)
+	}
+}
+
+// --- Recurring handler tests ---
+
+func setupRecurringRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	svc := services.NewRecurringService(db, services.NewTransactionService(db))
+	h := NewRecurringHandler(svc)
+
+	r := gin.New()
+	r.GET("/recurring", h.List)
+	r.POST("/recurring", h.Create)
+	r.PUT("/recurring/:id", h.Update)
+	r.DELETE("/recurring/:id", h.Delete)
+	return r
+}
+
+func TestRecurringHandler_CreateInvalidFrequency(t *testing.T) {
+	r := setupRecurringRouter(t)
+
+	body := `{"account_id":1,"amount":1000,"description":"Rent","type":"expense","frequency":"daily","start_date":"2024-01-01"}`
+	req := httptest.NewRequest("POST", "/recurring", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRecurringHandler_CreateEndBeforeStart(t *testing.T) {
+	r := setupRecurringRouter(t)
+
+	body := `{"account_id":1,"amount":1000,"description":"Rent","type":"expense","frequency":"monthly","start_date":"2024-02-01","end_date":"2024-01-01"}`
+	req := httptest.NewRequest("POST", "/recurring", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRecurringHandler_CreateUnknownAccount(t *testing.T) {
+	r := setupRecurringRouter(t)
+
+	body := `{"account_id":99,"amount":1000,"description":"Rent","type":"expense","frequency":"monthly","start_date":"2024-01-01"}`
+	req := httptest.NewRequest("POST", "/recurring", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRecurringHandler_DeleteNotFound(t *testing.T) {
+	r := setupRecurringRouter(t)
+
+	req := httptest.NewRequest("DELETE", "/recurring/999", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+// --- Rule handler tests ---
+
+func setupRuleRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	h := NewRuleHandler(services.NewRuleService(db))
+
+	r := gin.New()
+	r.GET("/rules", h.List)
+	r.POST("/rules", h.Create)
+	r.PUT("/rules/:id", h.Update)
+	r.DELETE("/rules/:id", h.Delete)
+	r.POST("/rules/reapply", h.Reapply)
+	return r
+}
+
+func TestRuleHandler_CreateInvalidRegex(t *testing.T) {
+	r := setupRuleRouter(t)
+
+	body := `{"name":"Bad","description_op":"regex","description_value":"(unclosed","set_category_id":1}`
+	req := httptest.NewRequest("POST", "/rules", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRuleHandler_CreateWithoutAction(t *testing.T) {
+	r := setupRuleRouter(t)
+
+	body := `{"name":"Nothing","description_op":"contains","description_value":"tesco"}`
+	req := httptest.NewRequest("POST", "/rules", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRuleHandler_CreateUnknownCategory(t *testing.T) {
+	r := setupRuleRouter(t)
+
+	body := `{"name":"Groceries","description_op":"contains","description_value":"tesco","set_category_id":99}`
+	req := httptest.NewRequest("POST", "/rules", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRuleHandler_Reapply(t *testing.T) {
+	r := setupRuleRouter(t)
+
+	req := httptest.NewRequest("POST", "/rules/reapply", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// --- Import profile handler tests ---
+
+func setupImportProfileRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	h := NewImportProfileHandler(services.NewImportProfileService(db))
+
+	r := gin.New()
+	r.GET("/import-profiles", h.List)
+	r.POST("/import-profiles", h.Create)
+	r.PUT("/import-profiles/:id", h.Update)
+	r.DELETE("/import-profiles/:id", h.Delete)
+	return r
+}
+
+func TestImportProfileHandler_CreateValidation(t *testing.T) {
+	r := setupImportProfileRouter(t)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no amount columns", `{"name":"Bank","date_column":"Date","description_column":"Memo"}`},
+		{"amount and paid columns", `{"name":"Bank","date_column":"Date","description_column":"Memo","amount_column":"Amount","paid_in_column":"In","paid_out_column":"Out"}`},
+		{"only paid in", `{"name":"Bank","date_column":"Date","description_column":"Memo","paid_in_column":"In"}`},
+		{"bad date format", `{"name":"Bank","date_column":"Date","description_column":"Memo","amount_column":"Amount","date_format":"dd/mm/yyyy"}`},
+		{"long delimiter", `{"name":"Bank","date_column":"Date","description_column":"Memo","amount_column":"Amount","delimiter":";;"}`},
+		{"bad sign convention", `{"name":"Bank","date_column":"Date","description_column":"Memo","amount_column":"Amount","sign_convention":"debit"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/import-profiles", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestImportProfileHandler_Create(t *testing.T) {
+	r := setupImportProfileRouter(t)
+
+	body := `{"name":"Bank","date_column":"Date","description_column":"Memo","paid_in_column":"In","paid_out_column":"Out","date_format":"DD/MM/YYYY","delimiter":";","decimal_separator":","}`
+	req := httptest.NewRequest("POST", "/import-profiles", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTransactionHandler_ImportWithProfile(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewTransactionHandler(services.NewTransactionService(db))
+	r := gin.New()
+	r.POST("/transactions/import", h.ImportCSV)
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	profile, _ := services.NewImportProfileService(db).Create(services.ImportProfileInput{
+		Name: "UK", DateColumn: "Date", DescriptionColumn: "Memo", AmountColumn: "Amount", DateFormat: "DD/MM/YYYY",
+	})
+	fields := map[string]string{
+		"account_id": strconv.FormatUint(uint64(account.ID), 10),
+		"profile_id": strconv.FormatUint(uint64(profile.ID), 10),
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newImportRequest(t, "/transactions/import", fields, "Date,Memo,Amount\n15/01/2024,Coffee,-3.50\n"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	fields["profile_id"] = "999"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newImportRequest(t, "/transactions/import", fields, "Date,Memo,Amount\n15/01/2024,Coffee,-3.50\n"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown profile, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTransactionHandler_ImportLenient(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewTransactionHandler(services.NewTransactionService(db))
+	r := gin.New()
+	r.POST("/transactions/import", h.ImportCSV)
+	r.GET("/import-batches/:id/rejected", NewImportBatchHandler(services.NewImportBatchService(db)).Rejected)
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	fields := map[string]string{"account_id": strconv.FormatUint(uint64(account.ID), 10)}
+	csv := "date,description,amount\n2024-01-15,Coffee,-3.50\n2024-01-16,Typo,abc\n"
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newImportRequest(t, "/transactions/import", fields, csv))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected strict mode to reject the file, got %d: %s", w.Code, w.Body.String())
+	}
+
+	fields["mode"] = "loose"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newImportRequest(t, "/transactions/import", fields, csv))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid mode, got %d: %s", w.Code, w.Body.String())
+	}
+
+	fields["mode"] = "lenient"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newImportRequest(t, "/transactions/import", fields, csv))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var result services.ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Imported != 1 || result.Rejected != 1 || len(result.Errors) != 1 || result.Errors[0].Line != 3 {
+		t.Fatalf("unexpected result: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/import-batches/"+strconv.FormatUint(uint64(*result.BatchID), 10)+"/rejected", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "date,description,amount,import_error\n2024-01-16,Typo,abc,invalid amount\n" {
+		t.Errorf("unexpected rejected CSV: %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/import-batches/999/rejected", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// --- Import batch handler tests ---
+
+func TestImportBatchHandler_RollbackNotFound(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewImportBatchHandler(services.NewImportBatchService(db))
+	r := gin.New()
+	r.POST("/import-batches/:id/rollback", h.Rollback)
+
+	req := httptest.NewRequest("POST", "/import-batches/999/rollback", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImportBatchHandler_RollbackEditedConflict(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	h := NewImportBatchHandler(services.NewImportBatchService(db))
+	r := gin.New()
+	r.POST("/import-batches/:id/rollback", h.Rollback)
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	txnSvc := services.NewTransactionService(db)
+	result, _ := txnSvc.ImportCSV([]models.Transaction{
+		{AccountID: account.ID, Amount: 350, Description: "Coffee", Date: "2024-01-03", Type: "expense"},
+	}, services.ImportOptions{Duplicates: "skip"})
+	desc := "Edited"
+	txnSvc.Update(*result.Rows[0].TransactionID, services.UpdateTransactionInput{Description: &desc})
+
+	path := "/import-batches/" + strconv.FormatUint(uint64(*result.BatchID), 10) + "/rollback"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", path+"?force=true", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 when forced, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// --- Import job handler tests ---
+
+func setupImportJobRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	// Each connection to an in-memory database sees its own empty database,
+	// so the background job must share the test's connection
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	h := NewImportJobHandler(services.NewImportJobService(db, t.TempDir()), context.Background())
+
+	r := gin.New()
+	r.POST("/import-jobs", h.Create)
+	r.GET("/import-jobs/:id", h.Get)
+	return r, db
+}
+
+func TestImportJobHandler_Create(t *testing.T) {
+	r, db := setupImportJobRouter(t)
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	fields := map[string]string{"account_id": strconv.FormatUint(uint64(account.ID), 10)}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newImportRequest(t, "/import-jobs", fields, "date,description,amount\n2024-01-15,Coffee,-3.50\n"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var job models.ImportJob
+	json.Unmarshal(w.Body.Bytes(), &job)
+
+	// Poll until the background job finishes
+	for i := 0; i < 100 && job.Status != "completed" && job.Status != "failed"; i++ {
+		time.Sleep(10 * time.Millisecond)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/import-jobs/"+strconv.FormatUint(uint64(job.ID), 10), nil))
+		json.Unmarshal(w.Body.Bytes(), &job)
+	}
+	if job.Status != "completed" || job.Imported != 1 {
+		t.Errorf("expected completed job with 1 import, got %+v", job)
+	}
+}
+
+func TestImportJobHandler_CreateValidation(t *testing.T) {
+	r, _ := setupImportJobRouter(t)
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   int
+	}{
+		{"missing account", map[string]string{}, http.StatusBadRequest},
+		{"bad duplicates mode", map[string]string{"account_id": "1", "duplicates": "merge"}, http.StatusBadRequest},
+		{"bad mode", map[string]string{"account_id": "1", "mode": "loose"}, http.StatusBadRequest},
+		{"unknown account", map[string]string{"account_id": "999"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, newImportRequest(t, "/import-jobs", tt.fields, "date,description,amount\n"))
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 // --- Budget handler tests ---
 
 func setupBudgetRouter(t *testing.T) *gin.Engine {
@@ -276,7 +1129,9 @@ func setupBudgetRouter(t *testing.T) *gin.Engine {
 	r.GET("/budget", h.GetBudget)
 	r.PUT("/budget/allocate", h.AllocateBudget)
 	r.PUT("/budget/allocate-bulk", h.AllocateBulk)
+	r.POST("/budget/auto-assign", h.AutoAssign)
 	r.GET("/budget/category-average", h.GetCategoryAverage)
+	r.POST("/budget/move", h.MoveMoney)
 	r.PUT("/categories/:id/target", h.SetCategoryTarget)
 	r.DELETE("/categories/:id/target", h.DeleteCategoryTarget)
 	return r
@@ -441,6 +1296,43 @@ func TestBudgetHandler_CategoryAverageMissingParams(t *testing.T) {
 	}
 }
 
+func TestBudgetHandler_MoveMoneyValidation(t *testing.T) {
+	r := setupBudgetRouter(t)
+
+	cases := []struct {
+		body string
+		code int
+	}{
+		{`{"month":"2024-1","to_category_id":1,"amount":100}`, http.StatusBadRequest},
+		{`{"month":"2024-01","to_category_id":1,"amount":-100}`, http.StatusBadRequest},
+		{`{"month":"2024-01","amount":100}`, http.StatusBadRequest},
+		{`{"month":"2024-01","to_category_id":99,"amount":100}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "/budget/move", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d", tc.body, tc.code, w.Code)
+		}
+	}
+}
+
+func TestBudgetHandler_AutoAssignInvalidStrategy(t *testing.T) {
+	r := setupBudgetRouter(t)
+
+	req := httptest.NewRequest("POST", "/budget/auto-assign", strings.NewReader(`{"month":"2024-01","strategy":"everything"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
 // --- Report handler tests ---
 
 func setupReportRouter(t *testing.T) *gin.Engine {
@@ -452,6 +1344,7 @@ func setupReportRouter(t *testing.T) *gin.Engine {
 	r := gin.New()
 	r.GET("/reports/by-category", h.ByCategory)
 	r.GET("/reports/by-account", h.ByAccount)
+	r.GET("/reports/net-worth", h.NetWorth)
 	return r
 }
 
@@ -526,3 +1419,21 @@ func TestReportHandler_ByAccountInvalidType(t *testing.T) {
 		t.Errorf("expected 400, got %d", w.Code)
 	}
 }
+
+func TestReportHandler_NetWorth(t *testing.T) {
+	r := setupReportRouter(t)
+
+	for url, code := range map[string]int{
+		"/reports/net-worth": http.StatusOK,
+		"/reports/net-worth?date_from=2024-01-01&date_to=2024-06-30": http.StatusOK,
+		"/reports/net-worth?date_from=bad":                           http.StatusBadRequest,
+		"/reports/net-worth?date_from=2024-06-01&date_to=2024-01-01": http.StatusBadRequest,
+		"/reports/net-worth?date_from=1800-01-01&date_to=2024-01-01": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != code {
+			t.Errorf("%s: expected %d, got %d", url, code, w.Code)
+		}
+	}
+}
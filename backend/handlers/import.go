@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/services"
+	"bufio"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (h *TransactionHandler) ImportCSV(c *gin.Context) {
+	opts, ok := parseImportOptions(c)
+	if !ok {
+		return
+	}
+	transactions, _, ok := h.readImportFile(c, &opts)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ImportCSV(transactions, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Account is closed")
+			return
+		}
+		respondServerError(c, err, "Failed to import transactions")
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (h *TransactionHandler) PreviewImport(c *gin.Context) {
+	opts, ok := parseImportOptions(c)
+	if !ok {
+		return
+	}
+	transactions, accountID, ok := h.readImportFile(c, &opts)
+	if !ok {
+		return
+	}
+
+	preview, err := h.service.PreviewImport(accountID, transactions, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Account is closed")
+			return
+		}
+		respondServerError(c, err, "Failed to preview import")
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+func (h *TransactionHandler) UpdatePreview(c *gin.Context) {
+	var input struct {
+		Rows []services.ImportPreviewRow `json:"rows" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, r := range input.Rows {
+		if r.Skip {
+			continue
+		}
+		if !validateDate(r.Date) {
+			respondError(c, http.StatusBadRequest, "Invalid date on row "+strconv.Itoa(r.Row)+". Must be YYYY-MM-DD")
+			return
+		}
+		if !validateTxnType(r.Type) {
+			respondError(c, http.StatusBadRequest, "Invalid type on row "+strconv.Itoa(r.Row)+". Must be one of: income, expense")
+			return
+		}
+		if r.Amount <= 0 {
+			respondError(c, http.StatusBadRequest, "Amount on row "+strconv.Itoa(r.Row)+" must be greater than 0")
+			return
+		}
+	}
+
+	preview, err := h.service.UpdatePreview(c.Param("token"), input.Rows)
+	if err != nil {
+		if errors.Is(err, services.ErrPreviewNotFound) {
+			respondError(c, http.StatusNotFound, "Import preview not found or expired")
+			return
+		}
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+func (h *TransactionHandler) ConfirmPreview(c *gin.Context) {
+	result, err := h.service.ConfirmPreview(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrPreviewNotFound) {
+			respondError(c, http.StatusNotFound, "Import preview not found or expired")
+			return
+		}
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Account is closed")
+			return
+		}
+		respondServerError(c, err, "Failed to import transactions")
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// readImportFile parses the uploaded file for the account given in the form.
+// The format comes from the optional format field, else is detected from the
+// file. CSV files are read with the optional profile_id, else the account's
+// default profile. QIF files read dates as date_order (mdy or dmy) and have
+// their categories matched by name; when create_categories is "true",
+// missing ones are created once the import is committed. With mode
+// "lenient", CSV rows that can't be read are left out and recorded in
+// opts.Rejected instead of failing the import; OFX and QIF files are always
+// read strictly. The upload's name is recorded in opts.Filename. It responds
+// with 400 and returns false when any of them is missing or invalid.
+func (h *TransactionHandler) readImportFile(c *gin.Context, opts *services.ImportOptions) ([]models.Transaction, uint, bool) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "File required")
+		return nil, 0, false
+	}
+	defer file.Close()
+	opts.Filename = header.Filename
+
+	accountID := c.PostForm("account_id")
+	if accountID == "" {
+		respondError(c, http.StatusBadRequest, "account_id required")
+		return nil, 0, false
+	}
+
+	id, err := strconv.ParseUint(accountID, 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid account_id")
+		return nil, 0, false
+	}
+	if order := c.PostForm("date_order"); order != "" && order != "mdy" && order != "dmy" {
+		respondError(c, http.StatusBadRequest, "Invalid date_order. Must be one of: mdy, dmy")
+		return nil, 0, false
+	}
+	mode := c.DefaultPostForm("mode", "strict")
+	if !validateImportMode(mode) {
+		respondError(c, http.StatusBadRequest, "Invalid mode. Must be one of: strict, lenient")
+		return nil, 0, false
+	}
+	var profileID *uint
+	if p := c.PostForm("profile_id"); p != "" {
+		pid, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid profile_id")
+			return nil, 0, false
+		}
+		v := uint(pid)
+		profileID = &v
+	}
+	profile, err := h.service.ImportProfile(uint(id), profileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusBadRequest, "Account or import profile not found")
+			return nil, 0, false
+		}
+		respondServerError(c, err, "Failed to load import profile")
+		return nil, 0, false
+	}
+
+	reader := bufio.NewReader(file)
+	format := c.PostForm("format")
+	if format == "" {
+		head, _ := reader.Peek(512)
+		format = services.DetectImportFormat(header.Filename, head)
+	}
+	var transactions []models.Transaction
+	switch format {
+	case "ofx":
+		transactions, err = services.ParseOFX(reader, accountID)
+	case "qif":
+		transactions, err = services.ParseQIF(reader, accountID, c.PostForm("date_order") == "dmy")
+	case "csv":
+		if mode == "lenient" {
+			transactions, opts.Rejected, err = services.ParseCSVLenient(reader, accountID, profile)
+		} else {
+			transactions, err = services.ParseCSVWithProfile(reader, accountID, profile)
+		}
+	default:
+		respondError(c, http.StatusBadRequest, "Invalid format. Must be one of: csv, ofx, qif")
+		return nil, 0, false
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return nil, 0, false
+	}
+	if format == "qif" {
+		if err := h.service.ResolveCategoryNames(transactions, c.PostForm("create_categories") == "true"); err != nil {
+			respondServerError(c, err, "Failed to match categories")
+			return nil, 0, false
+		}
+	}
+	return transactions, uint(id), true
+}
+
+func parseImportOptions(c *gin.Context) (services.ImportOptions, bool) {
+	opts := services.ImportOptions{Duplicates: c.DefaultPostForm("duplicates", "skip")}
+	if !validateDuplicateMode(opts.Duplicates) {
+		respondError(c, http.StatusBadRequest, "Invalid duplicates mode. Must be one of: skip, flag, allow")
+		return opts, false
+	}
+	if w := c.PostForm("duplicate_window_days"); w != "" {
+		days, err := strconv.Atoi(w)
+		if err != nil || days < 0 || days > 31 {
+			respondError(c, http.StatusBadRequest, "duplicate_window_days must be between 0 and 31")
+			return opts, false
+		}
+		opts.DuplicateWindowDays = days
+	}
+	return opts, true
+}
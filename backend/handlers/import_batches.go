@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"budgetting-app/backend/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImportBatchHandler struct {
+	service *services.ImportBatchService
+}
+
+func NewImportBatchHandler(svc *services.ImportBatchService) *ImportBatchHandler {
+	return &ImportBatchHandler{service: svc}
+}
+
+func (h *ImportBatchHandler) List(c *gin.Context) {
+	batches, err := h.service.List()
+	if err != nil {
+		respondServerError(c, err, "Failed to list imports")
+		return
+	}
+	c.JSON(http.StatusOK, batches)
+}
+
+// Rejected downloads the rows a lenient import left out as a CSV, with an
+// import_error column saying why, so they can be fixed and uploaded again.
+func (h *ImportBatchHandler) Rejected(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	batch, err := h.service.Rejected(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Import not found or has no rejected rows")
+			return
+		}
+		respondServerError(c, err, "Failed to load rejected rows")
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="rejected-`+strconv.FormatUint(uint64(batch.ID), 10)+`.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", []byte(batch.RejectedCSV))
+}
+
+func (h *ImportBatchHandler) Rollback(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	deleted, err := h.service.Rollback(id, c.Query("force") == "true")
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Import not found")
+			return
+		}
+		if errors.Is(err, services.ErrTransactionReconciled) {
+			respondError(c, http.StatusConflict, "Some transactions from this import have been reconciled. Unlock them before rolling back")
+			return
+		}
+		if errors.Is(err, services.ErrBatchEdited) {
+			respondError(c, http.StatusConflict, "Some transactions from this import have been edited since. Use force=true to roll back anyway")
+			return
+		}
+		respondServerError(c, err, "Failed to roll back import")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"budgetting-app/backend/services"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImportJobHandler struct {
+	service *services.ImportJobService
+	ctx     context.Context // jobs started by requests stop when this is cancelled
+}
+
+func NewImportJobHandler(svc *services.ImportJobService, ctx context.Context) *ImportJobHandler {
+	return &ImportJobHandler{service: svc, ctx: ctx}
+}
+
+func (h *ImportJobHandler) List(c *gin.Context) {
+	jobs, err := h.service.List()
+	if err != nil {
+		respondServerError(c, err, "Failed to list import jobs")
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+func (h *ImportJobHandler) Get(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	job, err := h.service.Get(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Import job not found")
+			return
+		}
+		respondServerError(c, err, "Failed to get import job")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// Create accepts the same form as ImportCSV but streams the file to disk
+// instead of buffering it, and imports it in the background. It responds
+// 202 with the queued job, whose status can then be polled.
+func (h *ImportJobHandler) Create(c *gin.Context) {
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Multipart form required")
+		return
+	}
+
+	fields := map[string]string{}
+	var path, filename string
+	queued := false
+	defer func() {
+		if path != "" && !queued {
+			h.service.DiscardUpload(path)
+		}
+	}()
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid multipart form")
+			return
+		}
+		if part.FormName() == "file" && path == "" {
+			filename = part.FileName()
+			path, err = h.service.StageUpload(part)
+			if errors.Is(err, services.ErrImportTooLarge) {
+				respondError(c, http.StatusRequestEntityTooLarge, "File too large. Maximum is "+strconv.Itoa(services.MaxImportJobBytes>>20)+" MB")
+				return
+			}
+			if err != nil {
+				respondServerError(c, err, "Failed to save upload")
+				return
+			}
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, 1024))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid multipart form")
+			return
+		}
+		fields[part.FormName()] = string(value)
+	}
+
+	input, ok := parseImportJobFields(c, fields)
+	if !ok {
+		return
+	}
+	if path == "" {
+		respondError(c, http.StatusBadRequest, "File required")
+		return
+	}
+	input.Filename = filename
+
+	job, err := h.service.Create(path, input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusBadRequest, "Account or import profile not found")
+			return
+		}
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Account is closed")
+			return
+		}
+		respondServerError(c, err, "Failed to create import job")
+		return
+	}
+	queued = true
+	h.service.Start(h.ctx, job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// parseImportJobFields validates the form fields of an import job the same
+// way readImportFile and parseImportOptions do for a direct import.
+func parseImportJobFields(c *gin.Context, fields map[string]string) (services.ImportJobInput, bool) {
+	var input services.ImportJobInput
+	accountID, err := strconv.ParseUint(fields["account_id"], 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "account_id required")
+		return input, false
+	}
+	input.AccountID = uint(accountID)
+
+	if p := fields["profile_id"]; p != "" {
+		pid, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid profile_id")
+			return input, false
+		}
+		v := uint(pid)
+		input.ProfileID = &v
+	}
+	input.Format = fields["format"]
+	if input.Format != "" && input.Format != "csv" && input.Format != "ofx" && input.Format != "qif" {
+		respondError(c, http.StatusBadRequest, "Invalid format. Must be one of: csv, ofx, qif")
+		return input, false
+	}
+	input.Duplicates = fields["duplicates"]
+	if input.Duplicates == "" {
+		input.Duplicates = "skip"
+	}
+	if !validateDuplicateMode(input.Duplicates) {
+		respondError(c, http.StatusBadRequest, "Invalid duplicates mode. Must be one of: skip, flag, allow")
+		return input, false
+	}
+	if w := fields["duplicate_window_days"]; w != "" {
+		days, err := strconv.Atoi(w)
+		if err != nil || days < 0 || days > 31 {
+			respondError(c, http.StatusBadRequest, "duplicate_window_days must be between 0 and 31")
+			return input, false
+		}
+		input.DuplicateWindowDays = days
+	}
+	input.DateOrder = fields["date_order"]
+	if input.DateOrder != "" && input.DateOrder != "mdy" && input.DateOrder != "dmy" {
+		respondError(c, http.StatusBadRequest, "Invalid date_order. Must be one of: mdy, dmy")
+		return input, false
+	}
+	input.CreateCategories = fields["create_categories"] == "true"
+	input.Mode = fields["mode"]
+	if input.Mode == "" {
+		input.Mode = "strict"
+	}
+	if !validateImportMode(input.Mode) {
+		respondError(c, http.StatusBadRequest, "Invalid mode. Must be one of: strict, lenient")
+		return input, false
+	}
+	return input, true
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"budgetting-app/backend/services"
+	"errors"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImportProfileHandler struct {
+	service *services.ImportProfileService
+}
+
+func NewImportProfileHandler(svc *services.ImportProfileService) *ImportProfileHandler {
+	return &ImportProfileHandler{service: svc}
+}
+
+func (h *ImportProfileHandler) List(c *gin.Context) {
+	profiles, err := h.service.List()
+	if err != nil {
+		respondServerError(c, err, "Failed to list import profiles")
+		return
+	}
+	c.JSON(http.StatusOK, profiles)
+}
+
+func (h *ImportProfileHandler) Create(c *gin.Context) {
+	var input services.ImportProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateImportProfileInput(c, input) {
+		return
+	}
+
+	profile, err := h.service.Create(input)
+	if err != nil {
+		respondServerError(c, err, "Failed to create import profile")
+		return
+	}
+	c.JSON(http.StatusCreated, profile)
+}
+
+func (h *ImportProfileHandler) Update(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.ImportProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateImportProfileInput(c, input) {
+		return
+	}
+
+	profile, err := h.service.Update(id, input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Import profile not found")
+			return
+		}
+		respondServerError(c, err, "Failed to update import profile")
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ImportProfileHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	err := h.service.Delete(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Import profile not found")
+			return
+		}
+		respondServerError(c, err, "Failed to delete import profile")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Import profile deleted"})
+}
+
+func validateImportProfileInput(c *gin.Context, input services.ImportProfileInput) bool {
+	usesAmount := input.AmountColumn != ""
+	usesPaidIn, usesPaidOut := input.PaidInColumn != "", input.PaidOutColumn != ""
+	if usesAmount == (usesPaidIn || usesPaidOut) || usesPaidIn != usesPaidOut {
+		respondError(c, http.StatusBadRequest, "Set either amount_column or both paid_in_column and paid_out_column")
+		return false
+	}
+	if input.DateFormat != "" && !validateDateFormat(input.DateFormat) {
+		respondError(c, http.StatusBadRequest, "Invalid date_format. Use YYYY, YY, MMM, MM, M, DD and D with separators, e.g. DD/MM/YYYY")
+		return false
+	}
+	if input.Delimiter != "" && (utf8.RuneCountInString(input.Delimiter) != 1 || input.Delimiter == "\"" || input.Delimiter == "\n") {
+		respondError(c, http.StatusBadRequest, "delimiter must be a single character")
+		return false
+	}
+	if input.DecimalSeparator != "" && !validateDecimalSeparator(input.DecimalSeparator) {
+		respondError(c, http.StatusBadRequest, "Invalid decimal_separator. Must be one of: '.', ','")
+		return false
+	}
+	if input.DecimalSeparator != "" && input.DecimalSeparator == input.Delimiter {
+		respondError(c, http.StatusBadRequest, "decimal_separator must differ from delimiter")
+		return false
+	}
+	if input.SignConvention != "" && !validateSignConvention(input.SignConvention) {
+		respondError(c, http.StatusBadRequest, "Invalid sign_convention. Must be one of: negative_expense, positive_expense")
+		return false
+	}
+	if input.SkipRows < 0 || input.SkipRows > 100 {
+		respondError(c, http.StatusBadRequest, "skip_rows must be between 0 and 100")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"budgetting-app/backend/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RecurringHandler struct {
+	service *services.RecurringService
+}
+
+func NewRecurringHandler(svc *services.RecurringService) *RecurringHandler {
+	return &RecurringHandler{service: svc}
+}
+
+func (h *RecurringHandler) List(c *gin.Context) {
+	rules, err := h.service.List()
+	if err != nil {
+		respondServerError(c, err, "Failed to list recurring transactions")
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *RecurringHandler) Create(c *gin.Context) {
+	var input services.RecurringInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateRecurringInput(c, input) {
+		return
+	}
+
+	rule, err := h.service.Create(input)
+	if err != nil {
+		if errors.Is(err, services.ErrRecurringReference) {
+			respondError(c, http.StatusBadRequest, "account_id or category_id does not exist")
+			return
+		}
+		respondServerError(c, err, "Failed to create recurring transaction")
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *RecurringHandler) Update(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.RecurringInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateRecurringInput(c, input) {
+		return
+	}
+
+	rule, err := h.service.Update(id, input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Recurring transaction not found")
+			return
+		}
+		if errors.Is(err, services.ErrRecurringReference) {
+			respondError(c, http.StatusBadRequest, "account_id or category_id does not exist")
+			return
+		}
+		respondServerError(c, err, "Failed to update recurring transaction")
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *RecurringHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	err := h.service.Delete(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Recurring transaction not found")
+			return
+		}
+		respondServerError(c, err, "Failed to delete recurring transaction")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring transaction deleted"})
+}
+
+func validateRecurringInput(c *gin.Context, input services.RecurringInput) bool {
+	if !validateTxnType(input.Type) {
+		respondError(c, http.StatusBadRequest, "Invalid transaction type. Must be one of: income, expense")
+		return false
+	}
+	if !validateFrequency(input.Frequency) {
+		respondError(c, http.StatusBadRequest, "Invalid frequency. Must be one of: monthly, weekly, last_business_day")
+		return false
+	}
+	if input.Amount <= 0 {
+		respondError(c, http.StatusBadRequest, "Amount must be greater than 0")
+		return false
+	}
+	if input.Interval < 0 {
+		respondError(c, http.StatusBadRequest, "interval must be 1 or more")
+		return false
+	}
+	if input.DayOfMonth < 0 || input.DayOfMonth > 31 {
+		respondError(c, http.StatusBadRequest, "day_of_month must be between 1 and 31")
+		return false
+	}
+	if !validateDate(input.StartDate) {
+		respondError(c, http.StatusBadRequest, "Invalid start_date format. Must be YYYY-MM-DD")
+		return false
+	}
+	if input.EndDate != nil && (!validateDate(*input.EndDate) || *input.EndDate < input.StartDate) {
+		respondError(c, http.StatusBadRequest, "end_date must be a YYYY-MM-DD date on or after start_date")
+		return false
+	}
+	return true
+}
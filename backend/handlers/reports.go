@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"budgetting-app/backend/services"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +20,7 @@ func (h *ReportHandler) ByCategory(c *gin.Context) {
 	dateFrom := c.Query("date_from")
 	dateTo := c.Query("date_to")
 	txnType := c.Query("type")
+	groupBy := c.Query("group_by")
 
 	if dateFrom != "" && !validateDate(dateFrom) {
 		respondError(c, http.StatusBadRequest, "Invalid date_from format. Must be YYYY-MM-DD")
@@ -32,11 +34,16 @@ func (h *ReportHandler) ByCategory(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "Invalid type. Must be one of: income, expense")
 		return
 	}
+	if groupBy != "" && !validateReportGroupBy(groupBy) {
+		respondError(c, http.StatusBadRequest, "Invalid group_by. Must be one of: category, group")
+		return
+	}
 
 	params := services.ReportParams{
 		DateFrom: dateFrom,
 		DateTo:   dateTo,
 		Type:     txnType,
+		GroupBy:  groupBy,
 	}
 	results, err := h.service.ByCategory(params)
 	if err != nil {
@@ -76,3 +83,31 @@ func (h *ReportHandler) ByAccount(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, results)
 }
+
+func (h *ReportHandler) NetWorth(c *gin.Context) {
+	params := services.NetWorthParams{DateFrom: c.Query("date_from"), DateTo: c.Query("date_to")}
+
+	if params.DateFrom != "" && !validateDate(params.DateFrom) {
+		respondError(c, http.StatusBadRequest, "Invalid date_from format. Must be YYYY-MM-DD")
+		return
+	}
+	if params.DateTo != "" && !validateDate(params.DateTo) {
+		respondError(c, http.StatusBadRequest, "Invalid date_to format. Must be YYYY-MM-DD")
+		return
+	}
+	if params.DateFrom != "" && params.DateTo != "" && params.DateFrom > params.DateTo {
+		respondError(c, http.StatusBadRequest, "date_from must not be after date_to")
+		return
+	}
+
+	results, err := h.service.NetWorth(params)
+	if err != nil {
+		if errors.Is(err, services.ErrRangeTooLarge) {
+			respondError(c, http.StatusBadRequest, "Too many months. Narrow the date range")
+			return
+		}
+		respondServerError(c, err, "Failed to generate net worth report")
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"budgetting-app/backend/services"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RuleHandler struct {
+	service *services.RuleService
+}
+
+func NewRuleHandler(svc *services.RuleService) *RuleHandler {
+	return &RuleHandler{service: svc}
+}
+
+func (h *RuleHandler) List(c *gin.Context) {
+	rules, err := h.service.List()
+	if err != nil {
+		respondServerError(c, err, "Failed to list rules")
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *RuleHandler) Create(c *gin.Context) {
+	var input services.RuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateRuleInput(c, input) {
+		return
+	}
+
+	rule, err := h.service.Create(input)
+	if err != nil {
+		if errors.Is(err, services.ErrRuleReference) {
+			respondError(c, http.StatusBadRequest, "set_category_id or account_id does not exist")
+			return
+		}
+		respondServerError(c, err, "Failed to create rule")
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *RuleHandler) Update(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var input services.RuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateRuleInput(c, input) {
+		return
+	}
+
+	rule, err := h.service.Update(id, input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Rule not found")
+			return
+		}
+		if errors.Is(err, services.ErrRuleReference) {
+			respondError(c, http.StatusBadRequest, "set_category_id or account_id does not exist")
+			return
+		}
+		respondServerError(c, err, "Failed to update rule")
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *RuleHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	err := h.service.Delete(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Rule not found")
+			return
+		}
+		respondServerError(c, err, "Failed to delete rule")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
+}
+
+func (h *RuleHandler) Reapply(c *gin.Context) {
+	updated, err := h.service.Reapply()
+	if err != nil {
+		respondServerError(c, err, "Failed to apply rules")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+func validateRuleInput(c *gin.Context, input services.RuleInput) bool {
+	if (input.DescriptionOp == nil) != (input.DescriptionValue == nil) {
+		respondError(c, http.StatusBadRequest, "description_op and description_value must be set together")
+		return false
+	}
+	if input.DescriptionOp != nil {
+		if !validateRuleOp(*input.DescriptionOp) {
+			respondError(c, http.StatusBadRequest, "Invalid description_op. Must be one of: contains, regex, equals")
+			return false
+		}
+		if *input.DescriptionValue == "" {
+			respondError(c, http.StatusBadRequest, "description_value must not be empty")
+			return false
+		}
+		if *input.DescriptionOp == "regex" {
+			if _, err := regexp.Compile(*input.DescriptionValue); err != nil {
+				respondError(c, http.StatusBadRequest, "Invalid description_value regex: "+err.Error())
+				return false
+			}
+		}
+	}
+	if input.AmountMin != nil && input.AmountMax != nil && *input.AmountMin > *input.AmountMax {
+		respondError(c, http.StatusBadRequest, "amount_min must not be greater than amount_max")
+		return false
+	}
+	if (input.Type != nil && !validateTxnType(*input.Type)) || (input.SetType != nil && !validateTxnType(*input.SetType)) {
+		respondError(c, http.StatusBadRequest, "Invalid transaction type. Must be one of: income, expense")
+		return false
+	}
+	if input.SetCategoryID == nil && input.SetDescription == nil && input.SetType == nil {
+		respondError(c, http.StatusBadRequest, "a rule must set at least one of: set_category_id, set_description, set_type")
+		return false
+	}
+	return true
+}
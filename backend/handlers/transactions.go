@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"budgetting-app/backend/models"
 	"budgetting-app/backend/services"
+	"bytes"
 	"errors"
 	"net/http"
 	"strconv"
@@ -30,14 +32,34 @@ func (h *TransactionHandler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, transactions)
 }
 
+// Export downloads the transactions matching the list filters as a file.
+// QIF is the only format so far.
+func (h *TransactionHandler) Export(c *gin.Context) {
+	if format := c.DefaultQuery("format", "qif"); format != "qif" {
+		respondError(c, http.StatusBadRequest, "Invalid format. Must be one of: qif")
+		return
+	}
+	params := services.ParseListParams(c.Query)
+
+	var buf bytes.Buffer
+	if err := h.service.ExportQIF(&buf, params); err != nil {
+		respondServerError(c, err, "Failed to export transactions")
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="transactions.qif"`)
+	c.Data(http.StatusOK, "application/qif", buf.Bytes())
+}
+
 func (h *TransactionHandler) Create(c *gin.Context) {
 	var input struct {
-		AccountID   uint   `json:"account_id" binding:"required"`
-		CategoryID  *uint  `json:"category_id"`
-		Amount      int64  `json:"amount"`
-		Description string `json:"description" binding:"required"`
-		Date        string `json:"date" binding:"required"`
-		Type        string `json:"type" binding:"required"`
+		AccountID   uint                      `json:"account_id" binding:"required"`
+		CategoryID  *uint                     `json:"category_id"`
+		Amount      int64                     `json:"amount"`
+		Description string                    `json:"description" binding:"required"`
+		Date        string                    `json:"date" binding:"required"`
+		Type        string                    `json:"type" binding:"required"`
+		Status      string                    `json:"status"`
+		Splits      []models.TransactionSplit `json:"splits"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		respondError(c, http.StatusBadRequest, err.Error())
@@ -55,15 +77,71 @@ func (h *TransactionHandler) Create(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "Amount must be greater than 0")
 		return
 	}
+	if input.Status != "" && !validateTxnStatus(input.Status) {
+		respondError(c, http.StatusBadRequest, "Invalid status. Must be one of: uncleared, cleared")
+		return
+	}
+	if !validateSplitLines(c, input.Splits) {
+		return
+	}
 
 	txn := services.CreateTransactionFromInput(input.AccountID, input.CategoryID, input.Amount, input.Description, input.Date, input.Type)
+	txn.Status = input.Status
+	txn.Splits = input.Splits
 	if err := h.service.Create(&txn); err != nil {
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Account is closed")
+			return
+		}
+		if errors.Is(err, services.ErrSplitSumMismatch) {
+			respondError(c, http.StatusBadRequest, "Split amounts must sum to the transaction amount")
+			return
+		}
+		if errors.Is(err, services.ErrSplitCategory) {
+			respondError(c, http.StatusBadRequest, "Split category not found")
+			return
+		}
 		respondServerError(c, err, "Failed to create transaction")
 		return
 	}
 	c.JSON(http.StatusCreated, txn)
 }
 
+func (h *TransactionHandler) CreateTransfer(c *gin.Context) {
+	var input services.CreateTransferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validateDate(input.Date) {
+		respondError(c, http.StatusBadRequest, "Invalid date format. Must be YYYY-MM-DD")
+		return
+	}
+	if input.Amount <= 0 {
+		respondError(c, http.StatusBadRequest, "Amount must be greater than 0")
+		return
+	}
+
+	legs, err := h.service.CreateTransfer(input)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Account not found")
+			return
+		}
+		if errors.Is(err, services.ErrTransferSameAccount) {
+			respondError(c, http.StatusBadRequest, "Cannot transfer to the same account")
+			return
+		}
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Account is closed")
+			return
+		}
+		respondServerError(c, err, "Failed to create transfer")
+		return
+	}
+	c.JSON(http.StatusCreated, legs)
+}
+
 func (h *TransactionHandler) Update(c *gin.Context) {
 	id, ok := parseID(c)
 	if !ok {
@@ -82,6 +160,13 @@ func (h *TransactionHandler) Update(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "Invalid transaction type. Must be one of: income, expense")
 		return
 	}
+	if input.Status != nil && !validateTxnStatus(*input.Status) {
+		respondError(c, http.StatusBadRequest, "Invalid status. Must be one of: uncleared, cleared")
+		return
+	}
+	if input.Splits != nil && !validateSplitLines(c, *input.Splits) {
+		return
+	}
 
 	txn, err := h.service.Update(id, input)
 	if err != nil {
@@ -89,6 +174,26 @@ func (h *TransactionHandler) Update(c *gin.Context) {
 			respondError(c, http.StatusNotFound, "Transaction not found")
 			return
 		}
+		if errors.Is(err, services.ErrSplitSumMismatch) {
+			respondError(c, http.StatusBadRequest, "Split amounts must sum to the transaction amount")
+			return
+		}
+		if errors.Is(err, services.ErrSplitCategory) {
+			respondError(c, http.StatusBadRequest, "Split category not found")
+			return
+		}
+		if errors.Is(err, services.ErrTransferTypeChange) || errors.Is(err, services.ErrTransferCategory) || errors.Is(err, services.ErrTransferSameAccount) {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrTransactionReconciled) {
+			respondError(c, http.StatusConflict, "Transaction is reconciled. Unlock it to change its amount, date, account, type or status")
+			return
+		}
+		if errors.Is(err, services.ErrAccountClosed) {
+			respondError(c, http.StatusConflict, "Account is closed")
+			return
+		}
 		respondServerError(c, err, "Failed to update transaction")
 		return
 	}
@@ -106,12 +211,34 @@ func (h *TransactionHandler) Delete(c *gin.Context) {
 			respondError(c, http.StatusNotFound, "Transaction not found")
 			return
 		}
+		if errors.Is(err, services.ErrTransactionReconciled) {
+			respondError(c, http.StatusConflict, "Transaction is reconciled. Unlock it before deleting")
+			return
+		}
 		respondServerError(c, err, "Failed to delete transaction")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted"})
 }
 
+// Unlock returns a reconciled transaction to cleared so it can be edited.
+func (h *TransactionHandler) Unlock(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	txn, err := h.service.Unlock(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Transaction not found")
+			return
+		}
+		respondServerError(c, err, "Failed to unlock transaction")
+		return
+	}
+	c.JSON(http.StatusOK, txn)
+}
+
 func (h *TransactionHandler) BulkUpdateCategory(c *gin.Context) {
 	var input struct {
 		TransactionIDs []uint `json:"transaction_ids" binding:"required"`
@@ -138,29 +265,16 @@ func (h *TransactionHandler) BulkUpdateCategory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"updated": affected})
 }
 
-func (h *TransactionHandler) ImportCSV(c *gin.Context) {
-	file, _, err := c.Request.FormFile("file")
-	if err != nil {
-		respondError(c, http.StatusBadRequest, "File required")
-		return
-	}
-	defer file.Close()
-
-	accountID := c.PostForm("account_id")
-	if accountID == "" {
-		respondError(c, http.StatusBadRequest, "account_id required")
-		return
-	}
-
-	transactions, err := services.ParseCSV(file, accountID)
-	if err != nil {
-		respondError(c, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	if err := h.service.ImportCSV(transactions); err != nil {
-		respondServerError(c, err, "Failed to import transactions")
-		return
+func validateSplitLines(c *gin.Context, splits []models.TransactionSplit) bool {
+	for _, sp := range splits {
+		if sp.CategoryID == nil {
+			respondError(c, http.StatusBadRequest, "each split must have a category_id")
+			return false
+		}
+		if sp.Amount <= 0 {
+			respondError(c, http.StatusBadRequest, "split amounts must be greater than 0")
+			return false
+		}
 	}
-	c.JSON(http.StatusCreated, gin.H{"imported": len(transactions)})
+	return true
 }
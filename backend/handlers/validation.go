@@ -7,10 +7,12 @@ import (
 
 var validAccountTypes = map[string]bool{"checking": true, "savings": true, "credit": true, "cash": true}
 var validTxnTypes = map[string]bool{"income": true, "expense": true}
+var validTxnStatuses = map[string]bool{"uncleared": true, "cleared": true} // reconciled is set by reconciling
 var colourRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
 
 func validateAccountType(t string) bool { return validAccountTypes[t] }
 func validateTxnType(t string) bool     { return validTxnTypes[t] }
+func validateTxnStatus(s string) bool   { return validTxnStatuses[s] }
 func validateColour(c string) bool      { return colourRegex.MatchString(c) }
 func validateDate(d string) bool        { _, err := time.Parse("2006-01-02", d); return err == nil }
 
@@ -21,3 +23,56 @@ func validateMonth(m string) bool { return monthRegex.MatchString(m) }
 var validTargetTypes = map[string]bool{"monthly_savings": true, "savings_balance": true, "spending_by_date": true}
 
 func validateTargetType(t string) bool { return validTargetTypes[t] }
+
+var validFrequencies = map[string]bool{"monthly": true, "weekly": true, "last_business_day": true}
+
+func validateFrequency(f string) bool { return validFrequencies[f] }
+
+var validRuleOps = map[string]bool{"contains": true, "regex": true, "equals": true}
+
+func validateRuleOp(op string) bool { return validRuleOps[op] }
+
+var validDuplicateModes = map[string]bool{"skip": true, "flag": true, "allow": true}
+
+func validateDuplicateMode(m string) bool { return validDuplicateModes[m] }
+
+var validBalanceIntervals = map[string]bool{"day": true, "month": true}
+
+func validateBalanceInterval(i string) bool { return validBalanceIntervals[i] }
+
+var validOpeningBalanceBudgets = map[string]bool{"ready_to_assign": true, "excluded": true}
+
+func validateOpeningBalanceBudget(b string) bool { return validOpeningBalanceBudgets[b] }
+
+var validMergeTargets = map[string]bool{"target": true, "source": true}
+
+func validateMergeTargets(k string) bool { return validMergeTargets[k] }
+
+var validAutoAssignStrategies = map[string]bool{"underfunded": true, "last_month": true, "average": true, "reset": true}
+
+func validateAutoAssignStrategy(s string) bool { return validAutoAssignStrategies[s] }
+
+var validReportGroupBys = map[string]bool{"category": true, "group": true}
+
+func validateReportGroupBy(g string) bool { return validReportGroupBys[g] }
+
+var validImportModes = map[string]bool{"strict": true, "lenient": true}
+
+func validateImportMode(m string) bool { return validImportModes[m] }
+
+var validDecimalSeparators = map[string]bool{".": true, ",": true}
+
+func validateDecimalSeparator(s string) bool { return validDecimalSeparators[s] }
+
+var validSignConventions = map[string]bool{"negative_expense": true, "positive_expense": true}
+
+func validateSignConvention(s string) bool { return validSignConventions[s] }
+
+// dateFormatRegex accepts formats built from the tokens the CSV parser
+// understands, separated by -, /, ., spaces or ", ". Alternatives are
+// separated by |.
+var dateFormatRegex = regexp.MustCompile(`^` + dateFormatPattern + `(\|` + dateFormatPattern + `)*$`)
+
+const dateFormatPattern = `(YYYY|YY|MMMM|MMM|MM|M|DD|D)(([-/. ]|, )(YYYY|YY|MMMM|MMM|MM|M|DD|D)){2}`
+
+func validateDateFormat(f string) bool { return dateFormatRegex.MatchString(f) }
@@ -30,16 +30,36 @@ func main() {
 	// Services
 	accountSvc := services.NewAccountService(db)
 	categorySvc := services.NewCategoryService(db)
+	categoryGroupSvc := services.NewCategoryGroupService(db)
 	transactionSvc := services.NewTransactionService(db)
 	budgetSvc := services.NewBudgetService(db)
 	reportSvc := services.NewReportService(db)
+	recurringSvc := services.NewRecurringService(db, transactionSvc)
+	ruleSvc := services.NewRuleService(db)
+	importProfileSvc := services.NewImportProfileService(db)
+	importBatchSvc := services.NewImportBatchService(db)
+	importJobSvc := services.NewImportJobService(db, cfg.ImportDir)
+
+	// Credit accounts created before payment categories existed get theirs
+	if err := accountSvc.EnsurePaymentCategories(); err != nil {
+		log.Fatal("Failed to create credit card payment categories:", err)
+	}
+
+	// Background work (recurring transactions, import jobs) stops on shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
 
 	// Handlers
 	accountH := handlers.NewAccountHandler(accountSvc)
 	categoryH := handlers.NewCategoryHandler(categorySvc)
+	categoryGroupH := handlers.NewCategoryGroupHandler(categoryGroupSvc)
 	transactionH := handlers.NewTransactionHandler(transactionSvc)
 	budgetH := handlers.NewBudgetHandler(budgetSvc)
 	reportH := handlers.NewReportHandler(reportSvc)
+	recurringH := handlers.NewRecurringHandler(recurringSvc)
+	ruleH := handlers.NewRuleHandler(ruleSvc)
+	importProfileH := handlers.NewImportProfileHandler(importProfileSvc)
+	importBatchH := handlers.NewImportBatchHandler(importBatchSvc)
+	importJobH := handlers.NewImportJobHandler(importJobSvc, bgCtx)
 
 	r := gin.Default()
 	r.MaxMultipartMemory = 8 << 20
@@ -81,26 +101,77 @@ func main() {
 		api.POST("/accounts", accountH.Create)
 		api.PUT("/accounts/:id", accountH.Update)
 		api.DELETE("/accounts/:id", accountH.Delete)
+		api.PUT("/accounts/:id/import-profile", accountH.SetDefaultImportProfile)
+		api.GET("/accounts/:id/opening-balance", accountH.GetOpeningBalance)
+		api.PUT("/accounts/:id/opening-balance", accountH.SetOpeningBalance)
+		api.POST("/accounts/:id/close", accountH.Close)
+		api.POST("/accounts/:id/reopen", accountH.Reopen)
+		api.POST("/accounts/:id/merge", accountH.Merge)
+		api.GET("/accounts/:id/balance-history", accountH.BalanceHistory)
+		api.GET("/accounts/:id/checkpoints", accountH.Checkpoints)
+		api.POST("/accounts/:id/reconcile", accountH.Reconcile)
 
 		api.GET("/categories", categoryH.List)
 		api.POST("/categories", categoryH.Create)
 		api.PUT("/categories/:id", categoryH.Update)
 		api.DELETE("/categories/:id", categoryH.Delete)
+		api.POST("/categories/:id/merge", categoryH.Merge)
+
+		api.GET("/category-groups", categoryGroupH.List)
+		api.POST("/category-groups", categoryGroupH.Create)
+		api.PUT("/category-groups/order", categoryGroupH.Reorder)
+		api.PUT("/category-groups/:id", categoryGroupH.Update)
+		api.DELETE("/category-groups/:id", categoryGroupH.Delete)
+		api.PUT("/category-groups/:id/categories", categoryGroupH.SetCategories)
 
 		api.GET("/transactions", transactionH.List)
+		api.GET("/transactions/export", transactionH.Export)
 		api.POST("/transactions", transactionH.Create)
+		api.POST("/transactions/transfer", transactionH.CreateTransfer)
 		api.PUT("/transactions/:id", transactionH.Update)
 		api.DELETE("/transactions/:id", transactionH.Delete)
+		api.POST("/transactions/:id/unlock", transactionH.Unlock)
 		api.PUT("/transactions/bulk-category", transactionH.BulkUpdateCategory)
 		api.POST("/transactions/import", transactionH.ImportCSV)
+		api.POST("/transactions/import/preview", transactionH.PreviewImport)
+		api.PUT("/transactions/import/preview/:token", transactionH.UpdatePreview)
+		api.POST("/transactions/import/preview/:token/confirm", transactionH.ConfirmPreview)
+
+		api.GET("/recurring", recurringH.List)
+		api.POST("/recurring", recurringH.Create)
+		api.PUT("/recurring/:id", recurringH.Update)
+		api.DELETE("/recurring/:id", recurringH.Delete)
+
+		api.GET("/rules", ruleH.List)
+		api.POST("/rules", ruleH.Create)
+		api.PUT("/rules/:id", ruleH.Update)
+		api.DELETE("/rules/:id", ruleH.Delete)
+		api.POST("/rules/reapply", ruleH.Reapply)
+
+		api.GET("/import-profiles", importProfileH.List)
+		api.POST("/import-profiles", importProfileH.Create)
+		api.PUT("/import-profiles/:id", importProfileH.Update)
+		api.DELETE("/import-profiles/:id", importProfileH.Delete)
+
+		api.GET("/import-batches", importBatchH.List)
+		api.GET("/import-batches/:id/rejected", importBatchH.Rejected)
+		api.POST("/import-batches/:id/rollback", importBatchH.Rollback)
+
+		api.GET("/import-jobs", importJobH.List)
+		api.POST("/import-jobs", importJobH.Create)
+		api.GET("/import-jobs/:id", importJobH.Get)
 
 		api.GET("/reports/by-category", reportH.ByCategory)
 		api.GET("/reports/by-account", reportH.ByAccount)
+		api.GET("/reports/net-worth", reportH.NetWorth)
 
 		api.GET("/budget", budgetH.GetBudget)
 		api.PUT("/budget/allocate", budgetH.AllocateBudget)
 		api.PUT("/budget/allocate-bulk", budgetH.AllocateBulk)
+		api.POST("/budget/auto-assign", budgetH.AutoAssign)
 		api.GET("/budget/category-average", budgetH.GetCategoryAverage)
+		api.POST("/budget/move", budgetH.MoveMoney)
+		api.GET("/budget/moves", budgetH.ListMoves)
 		api.PUT("/categories/:id/target", budgetH.SetCategoryTarget)
 		api.DELETE("/categories/:id/target", budgetH.DeleteCategoryTarget)
 	}
@@ -120,6 +191,14 @@ func main() {
 		Handler: r,
 	}
 
+	// Post due recurring transactions in the background
+	go recurringSvc.Run(bgCtx, time.Hour)
+
+	// Pick up import jobs interrupted by the last shutdown
+	if err := importJobSvc.Resume(bgCtx); err != nil {
+		slog.Error("Failed to resume import jobs", "error", err)
+	}
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("listen: %s\n", err)
@@ -130,6 +209,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	slog.Info("Shutting down server...")
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
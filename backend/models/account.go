@@ -3,9 +3,13 @@ package models
 import "time"
 
 type Account struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"not null"`
-	Type      string    `json:"type" gorm:"not null"` // checking, savings, credit, cash
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                     uint      `json:"id" gorm:"primaryKey"`
+	Name                   string    `json:"name" gorm:"not null"`
+	Type                   string    `json:"type" gorm:"not null"` // checking, savings, credit, cash
+	DefaultImportProfileID *uint     `json:"default_import_profile_id"`
+	OffBudget              bool      `json:"off_budget" gorm:"not null;default:false"`                       // a tracking account, left out of the budget
+	OpeningBalanceBudget   string    `json:"opening_balance_budget" gorm:"not null;default:ready_to_assign"` // ready_to_assign | excluded
+	ClosedDate             *string   `json:"closed_date"`                                                    // YYYY-MM-DD; set once the account is closed
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
 }
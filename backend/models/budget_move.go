@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// BudgetMove records money moved between two categories' allocations in a
+// month. A nil side is Ready to Assign. When a category is merged away its
+// moves are kept on the merged-into category, so a move can end up with the
+// same category on both sides.
+type BudgetMove struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Month          string    `json:"month" gorm:"not null;index"` // YYYY-MM
+	FromCategoryID *uint     `json:"from_category_id" gorm:"index"`
+	ToCategoryID   *uint     `json:"to_category_id" gorm:"index"`
+	Amount         int64     `json:"amount" gorm:"not null"` // cents, always positive
+	Note           string    `json:"note"`
+	MergedFrom     string    `json:"merged_from,omitempty"` // a category since merged into one side, by name
+	CreatedAt      time.Time `json:"created_at"`
+}
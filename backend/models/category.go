@@ -3,9 +3,12 @@ package models
 import "time"
 
 type Category struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"not null"`
-	Colour    string    `json:"colour" gorm:"not null"` // hex colour e.g. #FF5733
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Name             string    `json:"name" gorm:"not null"`
+	Colour           string    `json:"colour" gorm:"not null"`                // hex colour e.g. #FF5733
+	PaymentAccountID *uint     `json:"payment_account_id" gorm:"uniqueIndex"` // set on the category that pays off a credit account
+	GroupID          *uint     `json:"group_id" gorm:"index"`                 // nil when the category isn't in a group
+	SortOrder        int       `json:"sort_order" gorm:"not null;default:0"`  // position within its group
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// CategoryGroup gathers categories under a heading in the budget, such as
+// Fixed Bills or Savings Goals.
+type CategoryGroup struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	SortOrder int       `json:"sort_order" gorm:"not null;default:0"` // groups are listed in ascending order
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
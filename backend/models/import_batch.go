@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ImportBatch records one completed import so that the transactions it
+// created, which carry its ID, can be rolled back together.
+type ImportBatch struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	AccountID   uint      `json:"account_id" gorm:"not null;index"`
+	Account     Account   `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+	Filename    string    `json:"filename"`
+	RowCount    int       `json:"row_count" gorm:"not null"`          // transactions created by the import
+	Rejected    int       `json:"rejected" gorm:"not null;default:0"` // rows left out of a lenient import
+	RejectedCSV string    `json:"-" gorm:"type:text"`                 // those rows, for download
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
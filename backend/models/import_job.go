@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ImportJob is an import too large to run within a request. The upload is
+// kept on disk until the job finishes, and rows are committed in chunks with
+// RowsProcessed advanced in the same database transaction, so a job
+// interrupted by a restart can resume where it stopped.
+type ImportJob struct {
+	ID                  uint            `json:"id" gorm:"primaryKey"`
+	AccountID           uint            `json:"account_id" gorm:"not null;index"`
+	Filename            string          `json:"filename"`
+	Format              string          `json:"format" gorm:"not null"` // csv | ofx | qif
+	Path                string          `json:"-" gorm:"not null"`      // the staged upload
+	Profile             string          `json:"-" gorm:"type:text"`     // JSON-encoded CSV import profile, fixed when the job is created
+	Duplicates          string          `json:"duplicates" gorm:"not null"`
+	DuplicateWindowDays int             `json:"duplicate_window_days" gorm:"not null"`
+	DateOrder           string          `json:"date_order"` // QIF only: mdy | dmy
+	CreateCategories    bool            `json:"create_categories"`
+	Mode                string          `json:"mode" gorm:"not null;default:strict"` // CSV only: strict | lenient
+	Status              string          `json:"status" gorm:"not null;index"`        // queued | running | completed | failed
+	RowsProcessed       int             `json:"rows_processed" gorm:"not null"`      // rows read from the file and committed, not counting rejected ones
+	Imported            int             `json:"imported" gorm:"not null"`
+	Skipped             int             `json:"skipped" gorm:"not null"`
+	Flagged             int             `json:"flagged" gorm:"not null"`
+	Rejected            int             `json:"rejected" gorm:"not null;default:0"` // rows left out of a lenient import
+	BatchID             *uint           `json:"batch_id"`
+	BalanceCheck        json.RawMessage `json:"balance_check,omitempty" gorm:"type:text"` // the statement balance check, when the file had a balance column
+	Error               string          `json:"error,omitempty"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+	FinishedAt          *time.Time      `json:"finished_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ImportPreview holds parsed import rows between a dry run and its
+// confirmation, so the confirmed set is exactly what the user reviewed.
+type ImportPreview struct {
+	Token     string    `json:"token" gorm:"primaryKey"`
+	AccountID uint      `json:"account_id" gorm:"not null"`
+	Filename  string    `json:"filename"`
+	Rows      string    `json:"-" gorm:"type:text;not null"` // JSON-encoded preview rows
+	Window    int       `json:"-" gorm:"not null"`           // duplicate window in days, reused when rows are edited
+	Rejected  string    `json:"-" gorm:"type:text"`          // JSON-encoded rows a lenient parse left out, if any
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
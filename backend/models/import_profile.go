@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ImportProfile describes how to read one bank's CSV export. Column names are
+// matched case-insensitively against the header row.
+type ImportProfile struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	Name              string    `json:"name" gorm:"not null"`
+	DateColumn        string    `json:"date_column" gorm:"not null"`
+	DescriptionColumn string    `json:"description_column" gorm:"not null"`
+	AmountColumn      string    `json:"amount_column"`                     // single signed amount; empty when paid in/out columns are used
+	PaidInColumn      string    `json:"paid_in_column"`                    // optional, used with paid_out_column instead of amount_column
+	PaidOutColumn     string    `json:"paid_out_column"`                   // optional
+	TypeColumn        string    `json:"type_column"`                       // optional; income | expense
+	BalanceColumn     string    `json:"balance_column"`                    // optional; the bank's running balance, checked on import
+	DateFormat        string    `json:"date_format" gorm:"not null"`       // e.g. YYYY-MM-DD, DD/MM/YYYY; alternatives separated by |
+	Delimiter         string    `json:"delimiter" gorm:"not null"`         // single character
+	DecimalSeparator  string    `json:"decimal_separator" gorm:"not null"` // . or ,
+	SignConvention    string    `json:"sign_convention" gorm:"not null"`   // negative_expense | positive_expense
+	SkipRows          int       `json:"skip_rows" gorm:"not null"`         // lines before the header row
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
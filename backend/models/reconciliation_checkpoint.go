@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ReconciliationCheckpoint records a balance the bank reported for an account
+// on a date, taken from the running balance column of an imported statement
+// or entered when reconciling, and whether the account's transactions agreed
+// with it.
+type ReconciliationCheckpoint struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	AccountID     uint      `json:"account_id" gorm:"not null;index"`
+	ImportBatchID *uint     `json:"import_batch_id" gorm:"index"` // the import the balance came from; nil for a reconciliation
+	Date          string    `json:"date" gorm:"not null"`
+	Balance       int64     `json:"balance" gorm:"not null"`    // the bank's balance at the end of the statement
+	Difference    int64     `json:"difference" gorm:"not null"` // computed minus the bank's balance; 0 when they agree
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type RecurringTransaction struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	AccountID   uint      `json:"account_id" gorm:"not null;index"`
+	Account     Account   `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+	CategoryID  *uint     `json:"category_id"`
+	Category    *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Amount      int64     `json:"amount" gorm:"not null"` // cents
+	Description string    `json:"description" gorm:"not null"`
+	Type        string    `json:"type" gorm:"not null"`
+	Frequency   string    `json:"frequency" gorm:"not null"`          // monthly | weekly | last_business_day
+	Interval    int       `json:"interval" gorm:"not null;default:1"` // every N months or weeks
+	DayOfMonth  int       `json:"day_of_month"`                       // monthly only, clamped to the month's last day
+	StartDate   string    `json:"start_date" gorm:"not null"`         // YYYY-MM-DD
+	EndDate     *string   `json:"end_date"`                           // YYYY-MM-DD, nullable (null = no end)
+	NextDate    string    `json:"next_date" gorm:"not null;index"`    // next occurrence to post, YYYY-MM-DD
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Rule categorizes or rewrites transactions as they are created or imported.
+// Unset conditions match everything; a rule matches when all set conditions do.
+type Rule struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"not null"`
+	Priority int    `json:"priority" gorm:"not null;index"` // lower runs first
+	Enabled  bool   `json:"enabled" gorm:"not null"`
+
+	DescriptionOp    *string `json:"description_op"` // contains | regex | equals, nullable
+	DescriptionValue *string `json:"description_value"`
+	AmountMin        *int64  `json:"amount_min"` // cents, inclusive, nullable
+	AmountMax        *int64  `json:"amount_max"` // cents, inclusive, nullable
+	AccountID        *uint   `json:"account_id"`
+	Type             *string `json:"type"`
+
+	SetCategoryID  *uint   `json:"set_category_id"`
+	SetDescription *string `json:"set_description"`
+	SetType        *string `json:"set_type"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -3,15 +3,28 @@ package models
 import "time"
 
 type Transaction struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	AccountID   uint      `json:"account_id" gorm:"not null;index"`
-	Account     Account   `json:"account,omitempty" gorm:"foreignKey:AccountID"`
-	CategoryID  *uint     `json:"category_id" gorm:"index"`
-	Category    *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
-	Amount      int64     `json:"amount" gorm:"not null"`
-	Description string    `json:"description" gorm:"not null"`
-	Date        string    `json:"date" gorm:"not null;index;index:idx_type_date"`
-	Type        string    `json:"type" gorm:"not null;index:idx_type_date"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uint               `json:"id" gorm:"primaryKey"`
+	AccountID         uint               `json:"account_id" gorm:"not null;index;index:idx_account_external;index:idx_account_date"`
+	Account           Account            `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+	CategoryID        *uint              `json:"category_id" gorm:"index"`
+	Category          *Category          `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Amount            int64              `json:"amount" gorm:"not null"`
+	Description       string             `json:"description" gorm:"not null"`
+	Date              string             `json:"date" gorm:"not null;index;index:idx_type_date;index:idx_account_date"`
+	Type              string             `json:"type" gorm:"not null;index:idx_type_date"`
+	TransferID        *string            `json:"transfer_id" gorm:"index"` // shared by both legs of a transfer, nullable
+	TransferAccountID *uint              `json:"transfer_account_id"`      // the other leg's account, nullable
+	RecurringID       *uint              `json:"recurring_id" gorm:"uniqueIndex:idx_recurring_occurrence"`
+	RecurringDate     *string            `json:"recurring_date" gorm:"uniqueIndex:idx_recurring_occurrence"` // scheduled occurrence this row was posted for
+	DuplicateOfID     *uint              `json:"duplicate_of_id"`                                            // set when an import flagged this row as a likely duplicate
+	ExternalID        *string            `json:"external_id" gorm:"index:idx_account_external"`              // the bank's own ID (OFX FITID), nullable
+	ImportBatchID     *uint              `json:"import_batch_id" gorm:"index"`                               // the import that created this row, nullable
+	EditedAt          *time.Time         `json:"edited_at"`                                                  // last changed by hand, nullable
+	Status            string             `json:"status" gorm:"not null;default:uncleared;index"`             // uncleared | cleared | reconciled
+	Kind              string             `json:"kind" gorm:"not null;default:regular"`                       // regular | opening_balance
+	Splits            []TransactionSplit `json:"splits,omitempty" gorm:"foreignKey:TransactionID;constraint:OnDelete:CASCADE"`
+	StatementBalance  *int64             `json:"-" gorm:"-"`                         // the bank's running balance after this row, when an import file gives one
+	RunningBalance    *int64             `json:"running_balance,omitempty" gorm:"-"` // the account's balance after this row, when listing one account
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type TransactionSplit struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	TransactionID uint      `json:"transaction_id" gorm:"not null;index"`
+	CategoryID    *uint     `json:"category_id" gorm:"index"`
+	Category      *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Amount        int64     `json:"amount" gorm:"not null"` // cents
+	Memo          string    `json:"memo"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -17,81 +17,660 @@ func NewAccountService(db *gorm.DB) *AccountService {
 
 type AccountResponse struct {
 	models.Account
-	HasTransactions bool `json:"has_transactions"`
+	HasTransactions  bool  `json:"has_transactions"`
+	Balance          int64 `json:"balance"`           // income minus expense across all transactions
+	ClearedBalance   int64 `json:"cleared_balance"`   // cleared and reconciled transactions
+	UnclearedBalance int64 `json:"uncleared_balance"` // transactions not yet seen on a statement
 }
 
 type CreateAccountInput struct {
-	Name            string `json:"name" binding:"required"`
-	Type            string `json:"type" binding:"required"`
-	StartingBalance *int64 `json:"starting_balance"`
+	Name                 string `json:"name" binding:"required"`
+	Type                 string `json:"type" binding:"required"`
+	StartingBalance      *int64 `json:"starting_balance"`
+	OffBudget            bool   `json:"off_budget"`
+	OpeningDate          string `json:"opening_date"`           // YYYY-MM-DD; defaults to today
+	OpeningBalanceBudget string `json:"opening_balance_budget"` // ready_to_assign | excluded; defaults to ready_to_assign
 }
 
-func (s *AccountService) List() ([]AccountResponse, error) {
+// List returns the open accounts with their balances, and the closed ones too
+// when includeClosed is set.
+func (s *AccountService) List(includeClosed bool) ([]AccountResponse, error) {
 	var accounts []models.Account
-	if err := s.db.Order("name").Find(&accounts).Error; err != nil {
+	query := s.db.Order("name")
+	if !includeClosed {
+		query = query.Where("closed_date IS NULL")
+	}
+	if err := query.Find(&accounts).Error; err != nil {
 		return nil, err
 	}
 
-	// Batch query for has_transactions to avoid N+1
+	// Batch query for has_transactions and balances to avoid N+1
 	accountIDs := make([]uint, len(accounts))
 	for i, a := range accounts {
 		accountIDs[i] = a.ID
 	}
-	var idsWithTxns []uint
-	if len(accountIDs) > 0 {
-		s.db.Raw("SELECT DISTINCT account_id FROM transactions WHERE account_id IN ?", accountIDs).Scan(&idsWithTxns)
+	var totals []struct {
+		AccountID uint
+		Cleared   int64
+		Uncleared int64
 	}
-	hasTxnMap := make(map[uint]bool)
-	for _, id := range idsWithTxns {
-		hasTxnMap[id] = true
+	if len(accountIDs) > 0 {
+		if err := s.db.Raw(`SELECT account_id,
+			SUM(CASE WHEN status = 'uncleared' THEN 0 WHEN type = 'income' THEN amount ELSE -amount END) AS cleared,
+			SUM(CASE WHEN status <> 'uncleared' THEN 0 WHEN type = 'income' THEN amount ELSE -amount END) AS uncleared
+			FROM transactions WHERE account_id IN ? GROUP BY account_id`, accountIDs).Scan(&totals).Error; err != nil {
+			return nil, err
+		}
 	}
-
 	results := make([]AccountResponse, len(accounts))
+	byID := make(map[uint]*AccountResponse, len(accounts))
 	for i, a := range accounts {
-		results[i] = AccountResponse{Account: a, HasTransactions: hasTxnMap[a.ID]}
+		results[i] = AccountResponse{Account: a}
+		byID[a.ID] = &results[i]
+	}
+	for _, t := range totals {
+		r := byID[t.AccountID]
+		r.HasTransactions, r.ClearedBalance, r.UnclearedBalance = true, t.Cleared, t.Uncleared
+		r.Balance = t.Cleared + t.Uncleared
 	}
 	return results, nil
 }
 
+// Create adds an account, with an opening balance transaction dated
+// input.OpeningDate when a starting balance is given. Credit accounts get a
+// payment category.
 func (s *AccountService) Create(input CreateAccountInput) (models.Account, error) {
-	account := models.Account{Name: input.Name, Type: input.Type}
+	account := models.Account{Name: input.Name, Type: input.Type, OffBudget: input.OffBudget, OpeningBalanceBudget: input.OpeningBalanceBudget}
+	date := input.OpeningDate
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&account).Error; err != nil {
 			return err
 		}
+		if err := ensurePaymentCategory(tx, account); err != nil {
+			return err
+		}
 		if input.StartingBalance != nil && *input.StartingBalance != 0 {
-			amount := *input.StartingBalance
-			txType := "income"
-			if amount < 0 {
-				amount = -amount
-				txType = "expense"
+			_, err := writeOpeningBalance(tx, account.ID, *input.StartingBalance, date)
+			return err
+		}
+		return nil
+	})
+	return account, err
+}
+
+// Update renames an account and changes its type, and moves it on or off
+// the budget when offBudget is set. A payment category that still has its
+// default name follows the account's. An account that becomes a credit
+// account gets a payment category, and one that stops being one gives it up.
+func (s *AccountService) Update(id uint, name string, accountType string, offBudget *bool) (models.Account, error) {
+	var account models.Account
+	if err := s.db.First(&account, id).Error; err != nil {
+		return account, err
+	}
+	oldName := account.Name
+	updates := map[string]interface{}{"name": name, "type": accountType}
+	if offBudget != nil {
+		updates["off_budget"] = *offBudget
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Category{}).Where("payment_account_id = ? AND name = ?", account.ID, oldName+" Payment").
+			Update("name", name+" Payment").Error; err != nil {
+			return err
+		}
+		if accountType != "credit" {
+			return releasePaymentCategory(tx, account.ID)
+		}
+		account.Name, account.Type = name, accountType
+		return ensurePaymentCategory(tx, account)
+	})
+	return account, err
+}
+
+// EnsurePaymentCategories gives every credit account that lacks one a
+// payment category, for accounts created before they existed.
+func (s *AccountService) EnsurePaymentCategories() error {
+	var accounts []models.Account
+	if err := s.db.Where("type = ?", "credit").Find(&accounts).Error; err != nil {
+		return err
+	}
+	for _, a := range accounts {
+		if err := ensurePaymentCategory(s.db, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetDefaultImportProfile sets or, with a nil profileID, clears the profile
+// used for the account's imports when none is given.
+func (s *AccountService) SetDefaultImportProfile(id uint, profileID *uint) (models.Account, error) {
+	var account models.Account
+	if err := s.db.First(&account, id).Error; err != nil {
+		return account, err
+	}
+	if profileID != nil {
+		var profile models.ImportProfile
+		if err := s.db.First(&profile, *profileID).Error; err != nil {
+			return account, err
+		}
+	}
+	err := s.db.Model(&account).Update("default_import_profile_id", profileID).Error
+	return account, err
+}
+
+type ReconcileInput struct {
+	StatementDate    string `json:"statement_date" binding:"required"`
+	StatementBalance int64  `json:"statement_balance"`
+	// Adjust records the difference as a transaction when the cleared
+	// balance doesn't match, instead of failing with ErrReconcileMismatch.
+	Adjust bool `json:"adjust"`
+}
+
+type ReconcileResult struct {
+	ClearedBalance int64               `json:"cleared_balance"` // through the statement date, before any adjustment
+	Difference     int64               `json:"difference"`      // statement balance minus cleared balance
+	Reconciled     int64               `json:"reconciled"`      // transactions locked
+	Adjustment     *models.Transaction `json:"adjustment,omitempty"`
+	CheckpointID   uint                `json:"checkpoint_id,omitempty"`
+}
+
+// ReconcileAdjustmentDescription names the transaction Reconcile creates for
+// a difference.
+const ReconcileAdjustmentDescription = "Reconciliation balance adjustment"
+
+// Reconcile compares the account's cleared balance through a statement date
+// with the statement's balance. When they match, the cleared transactions up
+// to that date are locked as reconciled and the balance is recorded as a
+// reconciliation checkpoint. When they don't, it fails with
+// ErrReconcileMismatch, returning the difference, unless input.Adjust is set;
+// then an uncategorized adjustment transaction for the difference is
+// recorded, already reconciled, before locking.
+func (s *AccountService) Reconcile(id uint, input ReconcileInput) (*ReconcileResult, error) {
+	var account models.Account
+	if err := s.db.First(&account, id).Error; err != nil {
+		return nil, err
+	}
+	if account.ClosedDate != nil {
+		return nil, ErrAccountClosed
+	}
+
+	result := &ReconcileResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Transaction{}).
+			Select("COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE -amount END), 0)").
+			Where("account_id = ? AND status <> ? AND date <= ?", account.ID, "uncleared", input.StatementDate).
+			Scan(&result.ClearedBalance).Error; err != nil {
+			return err
+		}
+		result.Difference = input.StatementBalance - result.ClearedBalance
+		if result.Difference != 0 {
+			if !input.Adjust {
+				return ErrReconcileMismatch
 			}
-			txn := models.Transaction{
+			adjustment := models.Transaction{
 				AccountID:   account.ID,
-				Amount:      amount,
-				Type:        txType,
-				Description: "Starting balance",
-				Date:        time.Now().Format("2006-01-02"),
+				Amount:      abs(result.Difference),
+				Description: ReconcileAdjustmentDescription,
+				Date:        input.StatementDate,
+				Type:        "income",
+				Status:      "reconciled",
+			}
+			if result.Difference < 0 {
+				adjustment.Type = "expense"
 			}
-			if err := tx.Create(&txn).Error; err != nil {
+			if err := tx.Create(&adjustment).Error; err != nil {
 				return err
 			}
+			result.Adjustment = &adjustment
+		}
+
+		res := tx.Model(&models.Transaction{}).
+			Where("account_id = ? AND status = ? AND date <= ?", account.ID, "cleared", input.StatementDate).
+			Update("status", "reconciled")
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Reconciled = res.RowsAffected
+
+		checkpoint := models.ReconciliationCheckpoint{
+			AccountID:  account.ID,
+			Date:       input.StatementDate,
+			Balance:    input.StatementBalance,
+			Difference: -result.Difference,
+		}
+		if err := tx.Create(&checkpoint).Error; err != nil {
+			return err
 		}
+		result.CheckpointID = checkpoint.ID
 		return nil
 	})
-	return account, err
+	return result, err
+}
+
+// OpeningBalanceDescription names an account's opening balance transaction.
+const OpeningBalanceDescription = "Opening balance"
+
+// OpeningBalance is an account's balance before its first transaction, and
+// whether the budget can assign it.
+type OpeningBalance struct {
+	Amount        int64  `json:"amount"` // negative for a debt
+	Date          string `json:"date"`
+	Budget        string `json:"budget"`         // ready_to_assign | excluded
+	TransactionID *uint  `json:"transaction_id"` // nil when the account has no opening balance
 }
 
-func (s *AccountService) Update(id uint, name string, accountType string) (models.Account, error) {
+type OpeningBalanceInput struct {
+	Amount int64  `json:"amount"`
+	Date   string `json:"date" binding:"required"`
+	Budget string `json:"budget"` // left as it is when empty
+}
+
+func (s *AccountService) GetOpeningBalance(id uint) (*OpeningBalance, error) {
+	var account models.Account
+	if err := s.db.First(&account, id).Error; err != nil {
+		return nil, err
+	}
+	return loadOpeningBalance(s.db, account)
+}
+
+// SetOpeningBalance changes an account's opening balance, its date and how
+// the budget treats it. An amount of zero removes the opening balance
+// transaction. A reconciled opening balance keeps its amount and date,
+// returning ErrTransactionReconciled, until it is unlocked.
+func (s *AccountService) SetOpeningBalance(id uint, input OpeningBalanceInput) (*OpeningBalance, error) {
+	var account models.Account
+	if err := s.db.First(&account, id).Error; err != nil {
+		return nil, err
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if input.Budget != "" {
+			if err := tx.Model(&account).Update("opening_balance_budget", input.Budget).Error; err != nil {
+				return err
+			}
+		}
+		var existing models.Transaction
+		err := tx.Where("account_id = ? AND kind = ?", account.ID, "opening_balance").First(&existing).Error
+		if err == nil && existing.Status == "reconciled" && (signedAmount(existing) != input.Amount || existing.Date != input.Date) {
+			return ErrTransactionReconciled
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		_, err = writeOpeningBalance(tx, account.ID, input.Amount, input.Date)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loadOpeningBalance(s.db, account)
+}
+
+// writeOpeningBalance creates, updates or, for a zero amount, deletes an
+// account's opening balance transaction. Positive amounts are income and
+// negative ones expense. Opening balances are cleared, since they come from
+// the bank's own figure.
+func writeOpeningBalance(tx *gorm.DB, accountID uint, amount int64, date string) (*models.Transaction, error) {
+	var txn models.Transaction
+	err := tx.Where("account_id = ? AND kind = ?", accountID, "opening_balance").First(&txn).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	exists := err == nil
+	if amount == 0 {
+		if exists {
+			return nil, tx.Delete(&txn).Error
+		}
+		return nil, nil
+	}
+
+	txn.Amount, txn.Type, txn.Date = amount, "income", date
+	if amount < 0 {
+		txn.Amount, txn.Type = -amount, "expense"
+	}
+	if exists {
+		err = tx.Model(&txn).Updates(map[string]interface{}{"amount": txn.Amount, "type": txn.Type, "date": txn.Date}).Error
+		return &txn, err
+	}
+	txn.AccountID = accountID
+	txn.Description = OpeningBalanceDescription
+	txn.Kind = "opening_balance"
+	txn.Status = "cleared"
+	return &txn, tx.Create(&txn).Error
+}
+
+func loadOpeningBalance(db *gorm.DB, account models.Account) (*OpeningBalance, error) {
+	if err := db.First(&account, account.ID).Error; err != nil {
+		return nil, err
+	}
+	balance := &OpeningBalance{Budget: account.OpeningBalanceBudget}
+	var txn models.Transaction
+	err := db.Where("account_id = ? AND kind = ?", account.ID, "opening_balance").First(&txn).Error
+	if err == gorm.ErrRecordNotFound {
+		return balance, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	balance.Amount, balance.Date, balance.TransactionID = signedAmount(txn), txn.Date, &txn.ID
+	return balance, nil
+}
+
+// MaxBalanceHistoryPoints caps the length of a balance series.
+const MaxBalanceHistoryPoints = 3660
+
+type BalanceHistoryParams struct {
+	Interval string // day | month
+	From     string // YYYY-MM-DD; defaults to the account's first transaction
+	To       string // YYYY-MM-DD; defaults to today
+}
+
+type BalancePoint struct {
+	Date    string `json:"date"`    // the day, or the month as YYYY-MM
+	Balance int64  `json:"balance"` // at the end of the day or month
+}
+
+// BalanceHistory returns the account's balance at the end of each day or
+// month from params.From to params.To, including periods with no
+// transactions. Totals are summed per period in the database, so the cost
+// grows with the number of periods rather than transactions. A series longer
+// than MaxBalanceHistoryPoints is refused with ErrRangeTooLarge.
+func (s *AccountService) BalanceHistory(id uint, params BalanceHistoryParams) ([]BalancePoint, error) {
+	var account models.Account
+	if err := s.db.First(&account, id).Error; err != nil {
+		return nil, err
+	}
+
+	from, to := params.From, params.To
+	if to == "" {
+		to = time.Now().Format("2006-01-02")
+	}
+	if from == "" {
+		if err := s.db.Model(&models.Transaction{}).Select("COALESCE(MIN(date), '')").
+			Where("account_id = ?", account.ID).Scan(&from).Error; err != nil {
+			return nil, err
+		}
+		if from == "" || from > to {
+			from = to
+		}
+	}
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, err
+	}
+
+	period, step, format := "date", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }, "2006-01-02"
+	if params.Interval == "month" {
+		period, format = "substr(date, 1, 7)", "2006-01"
+		step = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+		start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	points := 0
+	for t := start; !t.After(end); t = step(t) {
+		if points++; points > MaxBalanceHistoryPoints {
+			return nil, ErrRangeTooLarge
+		}
+	}
+
+	signed := "COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE -amount END), 0)"
+	var balance int64
+	if err := s.db.Model(&models.Transaction{}).Select(signed).
+		Where("account_id = ? AND date < ?", account.ID, start.Format("2006-01-02")).
+		Scan(&balance).Error; err != nil {
+		return nil, err
+	}
+	var totals []struct {
+		Period string
+		Total  int64
+	}
+	if err := s.db.Model(&models.Transaction{}).Select(period+" AS period, "+signed+" AS total").
+		Where("account_id = ? AND date >= ? AND date <= ?", account.ID, start.Format("2006-01-02"), to).
+		Group("period").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	byPeriod := make(map[string]int64, len(totals))
+	for _, t := range totals {
+		byPeriod[t.Period] = t.Total
+	}
+
+	series := make([]BalancePoint, 0, points)
+	for t := start; !t.After(end); t = step(t) {
+		key := t.Format(format)
+		balance += byPeriod[key]
+		series = append(series, BalancePoint{Date: key, Balance: balance})
+	}
+	return series, nil
+}
+
+// Checkpoints lists the balances recorded for an account from imported
+// statements and reconciliations, newest first.
+func (s *AccountService) Checkpoints(id uint) ([]models.ReconciliationCheckpoint, error) {
+	var account models.Account
+	if err := s.db.First(&account, id).Error; err != nil {
+		return nil, err
+	}
+	checkpoints := []models.ReconciliationCheckpoint{}
+	err := s.db.Where("account_id = ?", id).Order("date DESC, id DESC").Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+// Close marks an account as closed on date. A closed account is left out of
+// List by default and takes no new transactions, but its history stays in
+// reports and balances.
+func (s *AccountService) Close(id uint, date string) (models.Account, error) {
 	var account models.Account
 	if err := s.db.First(&account, id).Error; err != nil {
 		return account, err
 	}
-	if err := s.db.Model(&account).Updates(models.Account{Name: name, Type: accountType}).Error; err != nil {
+	account.ClosedDate = &date
+	err := s.db.Model(&account).Update("closed_date", date).Error
+	return account, err
+}
+
+// Reopen clears an account's closed date.
+func (s *AccountService) Reopen(id uint) (models.Account, error) {
+	var account models.Account
+	if err := s.db.First(&account, id).Error; err != nil {
 		return account, err
 	}
-	return account, nil
+	account.ClosedDate = nil
+	err := s.db.Model(&account).Update("closed_date", nil).Error
+	return account, err
+}
+
+// checkAccountsOpen returns ErrAccountClosed if any of the accounts is
+// closed. Accounts that don't exist are left for the caller to report.
+func checkAccountsOpen(db *gorm.DB, ids ...uint) error {
+	var count int64
+	if err := db.Model(&models.Account{}).Where("id IN ? AND closed_date IS NOT NULL", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrAccountClosed
+	}
+	return nil
+}
+
+type AccountMergeResult struct {
+	Account          models.Account `json:"account"`           // the target, now holding everything
+	Transactions     int64          `json:"transactions"`      // moved from the source
+	TransfersRemoved int64          `json:"transfers_removed"` // transfers between the two, which cancel out
+}
+
+// Merge moves everything belonging to the source account into the target and
+// deletes the source, all in one database transaction. Transfers between the
+// two accounts would become transfers from the account to itself, so both of
+// their legs are removed; they don't change the merged balance. The source's
+// opening balance and payment category are merged into the target's. The
+// target keeps its name, type and default import profile, taking the source's
+// profile only if it has none. It returns ErrAccountClosed if the target is
+// closed, ErrTransactionReconciled if a transfer between the two or either
+// opening balance is reconciled, and ErrMergeBudgetMismatch if only one of
+// the accounts is a tracking account.
+func (s *AccountService) Merge(sourceID, targetID uint) (*AccountMergeResult, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeIntoSelf
+	}
+	var source, target models.Account
+	if err := s.db.First(&source, sourceID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&target, targetID).Error; err != nil {
+		return nil, err
+	}
+	if target.ClosedDate != nil {
+		return nil, ErrAccountClosed
+	}
+	// Moving a balance across the budget's boundary would change ready to assign
+	if source.OffBudget != target.OffBudget {
+		return nil, ErrMergeBudgetMismatch
+	}
+
+	result := &AccountMergeResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		between := tx.Model(&models.Transaction{}).Select("transfer_id").
+			Where("account_id = ? AND transfer_account_id = ?", source.ID, target.ID)
+		var reconciled int64
+		if err := tx.Model(&models.Transaction{}).Where("transfer_id IN (?) AND status = ?", between, "reconciled").
+			Count(&reconciled).Error; err != nil {
+			return err
+		}
+		if reconciled > 0 {
+			return ErrTransactionReconciled
+		}
+		res := tx.Where("transfer_id IN (?)", between).Delete(&models.Transaction{})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.TransfersRemoved = res.RowsAffected / 2
+
+		if err := foldOpeningBalance(tx, source.ID, target.ID); err != nil {
+			return err
+		}
+		res = tx.Model(&models.Transaction{}).Where("account_id = ?", source.ID).Update("account_id", target.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Transactions = res.RowsAffected
+		if err := tx.Model(&models.Transaction{}).Where("transfer_account_id = ?", source.ID).Update("transfer_account_id", target.ID).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&models.RecurringTransaction{},
+			&models.ImportBatch{},
+			&models.ImportJob{},
+			&models.ImportPreview{},
+			&models.ReconciliationCheckpoint{},
+			&models.Rule{},
+		} {
+			if err := tx.Model(model).Where("account_id = ?", source.ID).Update("account_id", target.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := mergePaymentCategory(tx, source, target); err != nil {
+			return err
+		}
+		if target.DefaultImportProfileID == nil && source.DefaultImportProfileID != nil {
+			if err := tx.Model(&target).Update("default_import_profile_id", source.DefaultImportProfileID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&source).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&result.Account, target.ID).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// foldOpeningBalance adds the source account's opening balance to the
+// target's, dated the earlier of the two, so the merged account still has
+// only one. A source opening balance is simply moved with the other rows
+// when the target has none. It returns ErrTransactionReconciled if either is
+// reconciled.
+func foldOpeningBalance(tx *gorm.DB, sourceID, targetID uint) error {
+	var rows []models.Transaction
+	if err := tx.Where("account_id IN ? AND kind = ?", []uint{sourceID, targetID}, "opening_balance").Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) < 2 {
+		return nil
+	}
+	var amount int64
+	date := rows[0].Date
+	for _, r := range rows {
+		if r.Status == "reconciled" {
+			return ErrTransactionReconciled
+		}
+		amount += signedAmount(r)
+		date = min(date, r.Date)
+	}
+	if err := tx.Where("account_id = ? AND kind = ?", sourceID, "opening_balance").Delete(&models.Transaction{}).Error; err != nil {
+		return err
+	}
+	_, err := writeOpeningBalance(tx, targetID, amount, date)
+	return err
+}
+
+// mergePaymentCategory hands the source account's payment category to the
+// target: merged into the target's own, taken over if the target is a credit
+// account without one, or kept as an ordinary category otherwise.
+func mergePaymentCategory(tx *gorm.DB, source, target models.Account) error {
+	var sourcePayment, targetPayment models.Category
+	err := tx.Where("payment_account_id = ?", source.ID).First(&sourcePayment).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = tx.Where("payment_account_id = ?", target.ID).First(&targetPayment).Error
+	if err == nil {
+		return mergeCategories(tx, sourcePayment, targetPayment, "target", &CategoryMergeResult{})
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	var payFor *uint
+	if target.Type == "credit" {
+		payFor = &target.ID
+	}
+	return tx.Model(&sourcePayment).Update("payment_account_id", payFor).Error
+}
+
+// releasePaymentCategory unlinks an account's payment category, for an
+// account that is deleted or no longer a credit account. The category is
+// deleted unless the budget has used it, in which case it stays as an
+// ordinary category.
+func releasePaymentCategory(tx *gorm.DB, accountID uint) error {
+	var payment models.Category
+	err := tx.Where("payment_account_id = ?", accountID).First(&payment).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&payment).Update("payment_account_id", nil).Error; err != nil {
+		return err
+	}
+	if err := NewCategoryService(tx).Delete(payment.ID); err != nil && err != ErrCategoryHasTransactions {
+		return err
+	}
+	return nil
 }
 
 func (s *AccountService) Delete(id uint) error {
@@ -104,5 +683,23 @@ func (s *AccountService) Delete(id uint) error {
 	if count > 0 {
 		return ErrAccountHasTransactions
 	}
-	return s.db.Delete(&account).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", account.ID).Delete(&models.RecurringTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("account_id = ?", account.ID).Delete(&models.ImportBatch{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("account_id = ?", account.ID).Delete(&models.ReconciliationCheckpoint{}).Error; err != nil {
+			return err
+		}
+		// Rules scoped to this account would otherwise start matching every account
+		if err := tx.Where("account_id = ?", account.ID).Delete(&models.Rule{}).Error; err != nil {
+			return err
+		}
+		if err := releasePaymentCategory(tx, account.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&account).Error
+	})
 }
@@ -4,6 +4,8 @@ import (
 	"budgetting-app/backend/models"
 	"budgetting-app/backend/testutil"
 	"testing"
+
+	"gorm.io/gorm"
 )
 
 func TestAccountService_CreateWithStartingBalance(t *testing.T) {
@@ -81,7 +83,7 @@ func TestAccountService_ListWithHasTransactions(t *testing.T) {
 		Date:        "2024-01-01",
 	})
 
-	results, err := svc.List()
+	results, err := svc.List(false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -138,3 +140,326 @@ func TestAccountService_DeleteSuccess(t *testing.T) {
 		t.Errorf("expected 0 accounts, got %d", count)
 	}
 }
+
+func TestAccountService_BalanceHistory(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewAccountService(db)
+	account := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&account)
+	db.Create(&[]models.Transaction{
+		{AccountID: account.ID, Amount: 10000, Description: "Pay", Date: "2024-01-30", Type: "income"},
+		{AccountID: account.ID, Amount: 2500, Description: "Shop", Date: "2024-02-01", Type: "expense"},
+		{AccountID: account.ID, Amount: 500, Description: "Cafe", Date: "2024-03-15", Type: "expense"},
+	})
+
+	daily, err := svc.BalanceHistory(account.ID, BalanceHistoryParams{Interval: "day", From: "2024-01-31", To: "2024-02-02"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []BalancePoint{{"2024-01-31", 10000}, {"2024-02-01", 7500}, {"2024-02-02", 7500}}
+	if len(daily) != len(want) {
+		t.Fatalf("expected %d points, got %+v", len(want), daily)
+	}
+	for i := range want {
+		if daily[i] != want[i] {
+			t.Errorf("point %d: expected %+v, got %+v", i, want[i], daily[i])
+		}
+	}
+
+	monthly, err := svc.BalanceHistory(account.ID, BalanceHistoryParams{Interval: "month", To: "2024-04-10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []BalancePoint{{"2024-01", 10000}, {"2024-02", 7500}, {"2024-03", 7000}, {"2024-04", 7000}}
+	if len(monthly) != len(want) {
+		t.Fatalf("expected %d points, got %+v", len(want), monthly)
+	}
+	for i := range want {
+		if monthly[i] != want[i] {
+			t.Errorf("point %d: expected %+v, got %+v", i, want[i], monthly[i])
+		}
+	}
+
+	if _, err := svc.BalanceHistory(account.ID, BalanceHistoryParams{Interval: "day", From: "2000-01-01", To: "2024-01-01"}); err != ErrRangeTooLarge {
+		t.Errorf("expected ErrRangeTooLarge, got %v", err)
+	}
+
+	list, _ := svc.List(false)
+	if list[0].Balance != 7000 {
+		t.Errorf("expected balance 7000, got %d", list[0].Balance)
+	}
+}
+
+func TestAccountService_Close(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewAccountService(db)
+	txns := NewTransactionService(db)
+	old := models.Account{Name: "Old bank", Type: "checking"}
+	current := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&old)
+	db.Create(&current)
+	db.Create(&models.Transaction{AccountID: old.ID, Amount: 5000, Description: "Deposit", Date: "2023-06-01", Type: "income"})
+
+	closed, err := svc.Close(old.ID, "2023-12-31")
+	if err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if closed.ClosedDate == nil || *closed.ClosedDate != "2023-12-31" {
+		t.Errorf("expected closed date 2023-12-31, got %v", closed.ClosedDate)
+	}
+
+	list, _ := svc.List(false)
+	if len(list) != 1 || list[0].ID != current.ID {
+		t.Errorf("expected only the open account, got %+v", list)
+	}
+	list, _ = svc.List(true)
+	if len(list) != 2 {
+		t.Errorf("expected both accounts with includeClosed, got %d", len(list))
+	}
+
+	txn := models.Transaction{AccountID: old.ID, Amount: 100, Description: "Late", Date: "2024-01-05", Type: "expense"}
+	if err := txns.Create(&txn); err != ErrAccountClosed {
+		t.Errorf("expected ErrAccountClosed on create, got %v", err)
+	}
+	if _, err := txns.CreateTransfer(CreateTransferInput{FromAccountID: current.ID, ToAccountID: old.ID, Amount: 100, Description: "Move", Date: "2024-01-05"}); err != ErrAccountClosed {
+		t.Errorf("expected ErrAccountClosed on transfer, got %v", err)
+	}
+	if _, err := txns.ImportCSV([]models.Transaction{txn}, ImportOptions{}); err != ErrAccountClosed {
+		t.Errorf("expected ErrAccountClosed on import, got %v", err)
+	}
+	moved := models.Transaction{AccountID: current.ID, Amount: 100, Description: "Coffee", Date: "2024-01-05", Type: "expense"}
+	txns.Create(&moved)
+	if _, err := txns.Update(moved.ID, UpdateTransactionInput{AccountID: &old.ID}); err != ErrAccountClosed {
+		t.Errorf("expected ErrAccountClosed on moving a transaction, got %v", err)
+	}
+
+	// History still counts towards reports
+	months, _ := NewReportService(db).NetWorth(NetWorthParams{DateFrom: "2023-06-01", DateTo: "2023-06-30"})
+	if len(months) != 1 || months[0].Assets != 5000 {
+		t.Errorf("expected the closed account in net worth, got %+v", months)
+	}
+
+	if _, err := svc.Reopen(old.ID); err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if err := txns.Create(&txn); err != nil {
+		t.Errorf("expected create to succeed after reopening, got %v", err)
+	}
+}
+
+func TestAccountService_Merge(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewAccountService(db)
+	txns := NewTransactionService(db)
+	amex := models.Account{Name: "Amex", Type: "credit"}
+	full := models.Account{Name: "American Express", Type: "credit"}
+	checking := models.Account{Name: "Checking", Type: "checking"}
+	db.Create(&amex)
+	db.Create(&full)
+	db.Create(&checking)
+
+	db.Create(&models.Transaction{AccountID: amex.ID, Amount: 4000, Description: "Shoes", Date: "2024-01-05", Type: "expense"})
+	db.Create(&models.Transaction{AccountID: full.ID, Amount: 1000, Description: "Lunch", Date: "2024-01-06", Type: "expense"})
+	if _, err := txns.CreateTransfer(CreateTransferInput{FromAccountID: checking.ID, ToAccountID: amex.ID, Amount: 3000, Description: "Payment", Date: "2024-01-10"}); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if _, err := txns.CreateTransfer(CreateTransferInput{FromAccountID: full.ID, ToAccountID: amex.ID, Amount: 500, Description: "Mistake", Date: "2024-01-11"}); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	db.Create(&models.ReconciliationCheckpoint{AccountID: amex.ID, Date: "2024-01-31", Balance: -1000})
+
+	result, err := svc.Merge(amex.ID, full.ID)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if result.Transactions != 2 || result.TransfersRemoved != 1 || result.Account.ID != full.ID {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	list, _ := svc.List(false)
+	if len(list) != 2 {
+		t.Fatalf("expected the source to be deleted, got %d accounts", len(list))
+	}
+	for _, a := range list {
+		if a.ID == full.ID && a.Balance != -2000 {
+			t.Errorf("expected merged balance -2000, got %d", a.Balance)
+		}
+	}
+	var payment models.Transaction
+	db.Where("account_id = ? AND description = ?", checking.ID, "Payment").First(&payment)
+	if payment.TransferAccountID == nil || *payment.TransferAccountID != full.ID {
+		t.Errorf("expected the other leg to point at the target, got %v", payment.TransferAccountID)
+	}
+	checkpoints, _ := svc.Checkpoints(full.ID)
+	if len(checkpoints) != 1 {
+		t.Errorf("expected the checkpoint to move, got %d", len(checkpoints))
+	}
+
+	if _, err := svc.Merge(full.ID, full.ID); err != ErrMergeIntoSelf {
+		t.Errorf("expected ErrMergeIntoSelf, got %v", err)
+	}
+	svc.Close(checking.ID, "2024-02-01")
+	if _, err := svc.Merge(full.ID, checking.ID); err != ErrAccountClosed {
+		t.Errorf("expected ErrAccountClosed merging into a closed account, got %v", err)
+	}
+}
+
+func TestAccountService_MergeRefusals(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewAccountService(db)
+	txns := NewTransactionService(db)
+	checking := models.Account{Name: "Checking", Type: "checking"}
+	savings := models.Account{Name: "Savings", Type: "savings"}
+	pension := models.Account{Name: "Pension", Type: "savings", OffBudget: true}
+	db.Create(&checking)
+	db.Create(&savings)
+	db.Create(&pension)
+
+	if _, err := svc.Merge(pension.ID, savings.ID); err != ErrMergeBudgetMismatch {
+		t.Errorf("expected ErrMergeBudgetMismatch, got %v", err)
+	}
+
+	legs, err := txns.CreateTransfer(CreateTransferInput{FromAccountID: checking.ID, ToAccountID: savings.ID, Amount: 3000, Description: "Top up", Date: "2024-01-10"})
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	db.Model(&models.Transaction{}).Where("transfer_id = ?", legs[0].TransferID).Update("status", "reconciled")
+	if _, err := svc.Merge(savings.ID, checking.ID); err != ErrTransactionReconciled {
+		t.Errorf("expected ErrTransactionReconciled, got %v", err)
+	}
+	var count int64
+	db.Model(&models.Transaction{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected the reconciled transfer to survive, got %d transactions", count)
+	}
+}
+
+func TestAccountService_MergeOpeningBalances(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewAccountService(db)
+	oldBalance, currentBalance, potBalance := int64(50000), int64(-2000), int64(1000)
+	old, _ := svc.Create(CreateAccountInput{Name: "Old savings", Type: "savings", StartingBalance: &oldBalance, OpeningDate: "2023-06-01"})
+	current, _ := svc.Create(CreateAccountInput{Name: "Savings", Type: "savings", StartingBalance: &currentBalance, OpeningDate: "2024-01-01"})
+
+	if _, err := svc.Merge(old.ID, current.ID); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	var count int64
+	db.Model(&models.Transaction{}).Where("account_id = ? AND kind = ?", current.ID, "opening_balance").Count(&count)
+	if count != 1 {
+		t.Fatalf("expected one opening balance after the merge, got %d", count)
+	}
+	balance, err := svc.GetOpeningBalance(current.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if balance.Amount != 48000 || balance.Date != "2023-06-01" {
+		t.Errorf("expected 48000 from 2023-06-01, got %d from %s", balance.Amount, balance.Date)
+	}
+
+	third, _ := svc.Create(CreateAccountInput{Name: "Pot", Type: "savings", StartingBalance: &potBalance, OpeningDate: "2024-01-01"})
+	db.Model(&models.Transaction{}).Where("account_id = ? AND kind = ?", current.ID, "opening_balance").Update("status", "reconciled")
+	if _, err := svc.Merge(third.ID, current.ID); err != ErrTransactionReconciled {
+		t.Errorf("expected ErrTransactionReconciled, got %v", err)
+	}
+}
+
+func TestAccountService_OpeningBalance(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewAccountService(db)
+
+	balance := int64(-25000)
+	account, err := svc.Create(CreateAccountInput{Name: "Card", Type: "credit", StartingBalance: &balance, OpeningDate: "2023-03-15"})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	opening, err := svc.GetOpeningBalance(account.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opening.Amount != -25000 || opening.Date != "2023-03-15" || opening.Budget != "ready_to_assign" || opening.TransactionID == nil {
+		t.Errorf("unexpected opening balance: %+v", opening)
+	}
+	var txn models.Transaction
+	db.First(&txn, *opening.TransactionID)
+	if txn.Kind != "opening_balance" || txn.Description != OpeningBalanceDescription {
+		t.Errorf("expected an opening balance transaction, got %+v", txn)
+	}
+
+	opening, err = svc.SetOpeningBalance(account.ID, OpeningBalanceInput{Amount: 30000, Date: "2023-01-01", Budget: "excluded"})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if opening.Amount != 30000 || opening.Date != "2023-01-01" || opening.Budget != "excluded" || *opening.TransactionID != txn.ID {
+		t.Errorf("expected the same transaction to be updated, got %+v", opening)
+	}
+
+	db.Model(&txn).Update("status", "reconciled")
+	if _, err := svc.SetOpeningBalance(account.ID, OpeningBalanceInput{Amount: 1, Date: "2023-01-01"}); err != ErrTransactionReconciled {
+		t.Errorf("expected ErrTransactionReconciled, got %v", err)
+	}
+	db.Model(&txn).Update("status", "cleared")
+
+	opening, err = svc.SetOpeningBalance(account.ID, OpeningBalanceInput{Amount: 0, Date: "2023-01-01"})
+	if err != nil {
+		t.Fatalf("removal failed: %v", err)
+	}
+	if opening.TransactionID != nil || opening.Amount != 0 {
+		t.Errorf("expected the opening balance to be removed, got %+v", opening)
+	}
+}
+
+func TestAccountService_PaymentCategory(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewAccountService(db)
+	checking := models.Account{Name: "Checking", Type: "checking"}
+	db.Create(&checking)
+	visa, _ := svc.Create(CreateAccountInput{Name: "Visa", Type: "credit"})
+	amex, _ := svc.Create(CreateAccountInput{Name: "Amex", Type: "credit"})
+
+	if _, err := svc.Update(visa.ID, "Visa Gold", "credit", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var payment models.Category
+	db.Where("payment_account_id = ?", visa.ID).First(&payment)
+	if payment.Name != "Visa Gold Payment" {
+		t.Errorf("expected the payment category to follow the rename, got %q", payment.Name)
+	}
+	if err := NewCategoryService(db).Delete(payment.ID); err != ErrPaymentCategory {
+		t.Errorf("expected ErrPaymentCategory, got %v", err)
+	}
+
+	// Checking becomes a card and gets its own payment category
+	if _, err := svc.Update(checking.ID, "Checking", "credit", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var count int64
+	db.Model(&models.Category{}).Where("payment_account_id = ?", checking.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("expected a payment category for the converted account, got %d", count)
+	}
+
+	// And back again: the unused payment category goes with the type
+	if _, err := svc.Update(checking.ID, "Checking", "checking", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.Model(&models.Category{}).Where("payment_account_id = ? OR name = ?", checking.ID, "Checking Payment").Count(&count)
+	if count != 0 {
+		t.Errorf("expected the payment category to be removed, got %d", count)
+	}
+
+	if _, err := svc.Merge(amex.ID, visa.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.Model(&models.Category{}).Where("payment_account_id IS NOT NULL").Count(&count)
+	if count != 1 {
+		t.Errorf("expected Amex's payment category to merge into Visa's, got %d payment categories", count)
+	}
+
+	if err := svc.Delete(visa.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.First(&models.Category{}, payment.ID).Error; err != gorm.ErrRecordNotFound {
+		t.Errorf("expected the unused payment category to be deleted, got %v", err)
+	}
+}
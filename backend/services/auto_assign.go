@@ -0,0 +1,195 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AutoAssignInput asks for a month's allocations to be set by a strategy:
+// underfunded (assign what each category's target still needs), last_month
+// (repeat the previous month's assigned amounts), average (assign the
+// trailing three-month spending average) or reset (assign zero). Without
+// CategoryIDs every category is included except credit card payment
+// categories, which are only touched when listed.
+type AutoAssignInput struct {
+	Month       string `json:"month" binding:"required"`
+	Strategy    string `json:"strategy" binding:"required"`
+	CategoryIDs []uint `json:"category_ids"`
+	Preview     bool   `json:"preview"` // work out the allocations without saving them
+}
+
+// AutoAssignResult lists the allocations a strategy changes, along with any
+// it wanted to raise but couldn't for lack of money.
+type AutoAssignResult struct {
+	Month         string           `json:"month"`
+	Strategy      string           `json:"strategy"`
+	Preview       bool             `json:"preview"`
+	ReadyToAssign int64            `json:"ready_to_assign"` // before the changes
+	Total         int64            `json:"total"`           // net amount taken from ready to assign
+	Allocations   []AutoAssignItem `json:"allocations"`
+}
+
+type AutoAssignItem struct {
+	CategoryID   uint   `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	Current      int64  `json:"current"`
+	Proposed     int64  `json:"proposed"`
+	Capped       bool   `json:"capped"`           // the strategy wanted more than ready to assign could give
+	Wanted       int64  `json:"wanted"`           // what the strategy proposed before capping
+	Reason       string `json:"reason,omitempty"` // capped | insufficient_funds, when Capped
+}
+
+// AutoAssign applies a strategy to the month's allocations, or only works
+// them out when input.Preview is set. Lowering an allocation frees money for
+// raising others, and the net amount assigned never exceeds Ready to Assign.
+// When it would, categories are funded in priority order: those with the
+// earliest target date first, then the rest as the budget lists them, with
+// the last one funded only in part and those after it reported with nothing
+// added. Listed categories that don't exist give gorm.ErrRecordNotFound.
+// Ready to Assign is read and the allocations written in one transaction, so
+// a concurrent allocation can't push past the cap.
+func (s *BudgetService) AutoAssign(input AutoAssignInput) (*AutoAssignResult, error) {
+	var result *AutoAssignResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = NewBudgetService(tx).autoAssign(input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *BudgetService) autoAssign(input AutoAssignInput) (*AutoAssignResult, error) {
+	budget, err := s.GetBudget(input.Month)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := autoAssignRows(budget.Categories, input.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastMonth map[uint]int64
+	if input.Strategy == "last_month" {
+		t, _ := time.Parse("2006-01", input.Month)
+		var allocations []models.BudgetAllocation
+		if err := s.db.Where("month = ?", t.AddDate(0, -1, 0).Format("2006-01")).Find(&allocations).Error; err != nil {
+			return nil, err
+		}
+		lastMonth = make(map[uint]int64, len(allocations))
+		for _, a := range allocations {
+			lastMonth[a.CategoryID] = a.Amount
+		}
+	}
+
+	var items []AutoAssignItem
+	for _, row := range rows {
+		proposed := row.Assigned
+		switch input.Strategy {
+		case "underfunded":
+			if row.Underfunded != nil && *row.Underfunded > 0 {
+				proposed += *row.Underfunded
+			}
+		case "last_month":
+			proposed = lastMonth[row.CategoryID]
+		case "average":
+			if proposed, err = s.GetCategoryAverage(row.CategoryID, input.Month); err != nil {
+				return nil, err
+			}
+		case "reset":
+			proposed = 0
+		}
+		proposed = max(proposed, 0)
+		if proposed != row.Assigned {
+			items = append(items, AutoAssignItem{CategoryID: row.CategoryID, CategoryName: row.CategoryName, Current: row.Assigned, Proposed: proposed, Wanted: proposed})
+		}
+	}
+
+	// Decreases always go through; increases share what they free plus ready to assign
+	remaining := budget.ReadyToAssign
+	for _, item := range items {
+		if item.Proposed < item.Current {
+			remaining += item.Current - item.Proposed
+		}
+	}
+	result := &AutoAssignResult{Month: input.Month, Strategy: input.Strategy, Preview: input.Preview, ReadyToAssign: budget.ReadyToAssign}
+	for _, item := range items {
+		if increase := item.Proposed - item.Current; increase > 0 {
+			if increase > remaining {
+				item.Proposed, item.Capped, item.Reason = item.Current+max(remaining, 0), true, "capped"
+				increase = item.Proposed - item.Current
+				if increase == 0 {
+					// Kept so the preview shows what went unfunded
+					item.Reason = "insufficient_funds"
+				}
+			}
+			remaining -= increase
+		}
+		result.Total += item.Proposed - item.Current
+		result.Allocations = append(result.Allocations, item)
+	}
+
+	if !input.Preview && len(result.Allocations) > 0 {
+		var bulk []BulkAllocationItem
+		for _, item := range result.Allocations {
+			if item.Proposed != item.Current {
+				bulk = append(bulk, BulkAllocationItem{CategoryID: item.CategoryID, Amount: item.Proposed})
+			}
+		}
+		if err := s.AllocateBulk(input.Month, bulk); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// autoAssignRows picks the budget rows a strategy applies to and puts them in
+// priority order.
+func autoAssignRows(all []BudgetCategoryRow, categoryIDs []uint) ([]BudgetCategoryRow, error) {
+	var rows []BudgetCategoryRow
+	if len(categoryIDs) == 0 {
+		for _, row := range all {
+			if row.PaymentAccountID == nil {
+				rows = append(rows, row)
+			}
+		}
+	} else {
+		byID := make(map[uint]BudgetCategoryRow, len(all))
+		for _, row := range all {
+			byID[row.CategoryID] = row
+		}
+		seen := make(map[uint]bool)
+		for _, id := range categoryIDs {
+			row, ok := byID[id]
+			if !ok {
+				// Every category has a row, so this one doesn't exist
+				return nil, gorm.ErrRecordNotFound
+			}
+			if !seen[id] {
+				seen[id] = true
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	position := make(map[uint]int, len(all))
+	for i, row := range all {
+		position[row.CategoryID] = i
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i].TargetDate, rows[j].TargetDate
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && *a != *b {
+			return *a < *b
+		}
+		return position[rows[i].CategoryID] < position[rows[j].CategoryID]
+	})
+	return rows, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"sort"
+)
+
+// UngroupedName heads the budget group holding categories that aren't in one.
+const UngroupedName = "Ungrouped"
+
+// BudgetGroup is a category group in the budget view, with its categories'
+// rows and their totals.
+type BudgetGroup struct {
+	GroupID     *uint               `json:"group_id"` // nil for the categories that aren't in a group
+	Name        string              `json:"name"`
+	Assigned    int64               `json:"assigned"`
+	Activity    int64               `json:"activity"`
+	Available   int64               `json:"available"`
+	Underfunded int64               `json:"underfunded"`
+	Categories  []BudgetCategoryRow `json:"categories"`
+}
+
+// groupBudgetRows nests rows under groups in the groups' order, each group's
+// rows ordered by their position in it and then by name. Categories outside
+// any group come last, under UngroupedName, if there are any.
+func groupBudgetRows(groups []models.CategoryGroup, categories []models.Category, rows []BudgetCategoryRow) []BudgetGroup {
+	sortOrder := make(map[uint]int, len(categories))
+	for _, cat := range categories {
+		sortOrder[cat.ID] = cat.SortOrder
+	}
+	index := make(map[uint]int, len(groups))
+	result := make([]BudgetGroup, 0, len(groups)+1)
+	for i, g := range groups {
+		id := g.ID
+		index[id] = i
+		result = append(result, BudgetGroup{GroupID: &id, Name: g.Name, Categories: []BudgetCategoryRow{}})
+	}
+	ungrouped := BudgetGroup{Name: UngroupedName, Categories: []BudgetCategoryRow{}}
+
+	for _, row := range rows {
+		group := &ungrouped
+		if row.GroupID != nil {
+			if i, ok := index[*row.GroupID]; ok {
+				group = &result[i]
+			}
+		}
+		group.Assigned += row.Assigned
+		group.Activity += row.Activity
+		group.Available += row.Available
+		if row.Underfunded != nil {
+			group.Underfunded += *row.Underfunded
+		}
+		group.Categories = append(group.Categories, row)
+	}
+
+	if len(ungrouped.Categories) > 0 {
+		result = append(result, ungrouped)
+	}
+	for _, g := range result {
+		// Rows arrive sorted by name, so a stable sort keeps that as the tie-break
+		sort.SliceStable(g.Categories, func(i, j int) bool {
+			return sortOrder[g.Categories[i].CategoryID] < sortOrder[g.Categories[j].CategoryID]
+		})
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// MoveMoneyInput moves Amount from one category's allocation to another's.
+// A nil category is Ready to Assign.
+type MoveMoneyInput struct {
+	Month          string `json:"month" binding:"required"`
+	FromCategoryID *uint  `json:"from_category_id"`
+	ToCategoryID   *uint  `json:"to_category_id"`
+	Amount         int64  `json:"amount" binding:"required"`
+	Note           string `json:"note"`
+}
+
+// MoveMoney lowers the source category's allocation for the month and raises
+// the destination's by the same amount, and records the move, in one
+// database transaction. Moving from Ready to Assign only raises the
+// destination, and moving to it only lowers the source, since Ready to Assign
+// is whatever is left unallocated. It returns ErrMoveToSelf if both sides are
+// the same, ErrInsufficientFunds if the source's allocation (or Ready to
+// Assign) is less than the amount, and gorm.ErrRecordNotFound if a category
+// doesn't exist.
+func (s *BudgetService) MoveMoney(input MoveMoneyInput) (*models.BudgetMove, error) {
+	if sameCategory(input.FromCategoryID, input.ToCategoryID) {
+		return nil, ErrMoveToSelf
+	}
+	move := &models.BudgetMove{
+		Month:          input.Month,
+		FromCategoryID: input.FromCategoryID,
+		ToCategoryID:   input.ToCategoryID,
+		Amount:         input.Amount,
+		Note:           input.Note,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if input.ToCategoryID != nil {
+			if err := addAllocation(tx, input.Month, *input.ToCategoryID, input.Amount); err != nil {
+				return err
+			}
+		}
+		// The source must still have the money once the destination has it
+		if input.FromCategoryID != nil {
+			if err := addAllocation(tx, input.Month, *input.FromCategoryID, -input.Amount); err != nil {
+				return err
+			}
+			var left int64
+			if err := tx.Model(&models.BudgetAllocation{}).Select("amount").
+				Where("month = ? AND category_id = ?", input.Month, *input.FromCategoryID).Scan(&left).Error; err != nil {
+				return err
+			}
+			if left < 0 {
+				return ErrInsufficientFunds
+			}
+		} else {
+			budget, err := NewBudgetService(tx).GetBudget(input.Month)
+			if err != nil {
+				return err
+			}
+			if budget.ReadyToAssign < 0 {
+				return ErrInsufficientFunds
+			}
+		}
+		return tx.Create(move).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return move, nil
+}
+
+// ListMoves returns the moves for a month, a category (on either side), or
+// both, newest first. An empty month or nil category doesn't filter.
+func (s *BudgetService) ListMoves(month string, categoryID *uint) ([]models.BudgetMove, error) {
+	query := s.db.Order("created_at DESC, id DESC")
+	if month != "" {
+		query = query.Where("month = ?", month)
+	}
+	if categoryID != nil {
+		query = query.Where("from_category_id = ? OR to_category_id = ?", *categoryID, *categoryID)
+	}
+	var moves []models.BudgetMove
+	err := query.Find(&moves).Error
+	return moves, err
+}
+
+// addAllocation changes a category's allocation for a month by delta,
+// creating the allocation if the category has none yet.
+func addAllocation(tx *gorm.DB, month string, categoryID uint, delta int64) error {
+	var cat models.Category
+	if err := tx.First(&cat, categoryID).Error; err != nil {
+		return err
+	}
+	res := tx.Model(&models.BudgetAllocation{}).Where("month = ? AND category_id = ?", month, categoryID).
+		Update("amount", gorm.Expr("amount + ?", delta))
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+	return tx.Create(&models.BudgetAllocation{Month: month, CategoryID: categoryID, Amount: delta}).Error
+}
+
+func sameCategory(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -23,16 +23,18 @@ type BulkAllocationItem struct {
 }
 
 type BudgetCategoryRow struct {
-	CategoryID   uint    `json:"category_id"`
-	CategoryName string  `json:"category_name"`
-	Colour       string  `json:"colour"`
-	Assigned     int64   `json:"assigned"`
-	Activity     int64   `json:"activity"`
-	Available    int64   `json:"available"`
-	TargetType   *string `json:"target_type"`
-	TargetAmount *int64  `json:"target_amount"`
-	TargetDate   *string `json:"target_date"`
-	Underfunded  *int64  `json:"underfunded"`
+	CategoryID       uint    `json:"category_id"`
+	CategoryName     string  `json:"category_name"`
+	Colour           string  `json:"colour"`
+	Assigned         int64   `json:"assigned"`
+	Activity         int64   `json:"activity"`
+	Available        int64   `json:"available"`
+	TargetType       *string `json:"target_type"`
+	TargetAmount     *int64  `json:"target_amount"`
+	TargetDate       *string `json:"target_date"`
+	Underfunded      *int64  `json:"underfunded"`
+	PaymentAccountID *uint   `json:"payment_account_id,omitempty"` // set on a credit card's payment category
+	GroupID          *uint   `json:"group_id"`
 }
 
 type BudgetResponse struct {
@@ -42,9 +44,17 @@ type BudgetResponse struct {
 	ReadyToAssign         int64               `json:"ready_to_assign"`
 	TotalUnderfunded      int64               `json:"total_underfunded"`
 	UncategorizedExpenses int64               `json:"uncategorized_expenses"`
-	Categories            []BudgetCategoryRow `json:"categories"`
+	TrackingTransfers     int64               `json:"tracking_transfers"`  // net money moved in from (+) or out to (-) tracking accounts this month
+	CreditOverspending    int64               `json:"credit_overspending"` // card spending this month that no category had money for
+	CreditCards           []CreditCardSummary `json:"credit_cards"`
+	Categories            []BudgetCategoryRow `json:"categories"` // every category, by name
+	Groups                []BudgetGroup       `json:"groups"`     // the same rows nested under their groups
 }
 
+// onBudgetAccountsSQL selects the accounts whose money the budget assigns.
+// Tracking accounts, such as a mortgage or pension, are left out.
+const onBudgetAccountsSQL = "(SELECT id FROM accounts WHERE NOT off_budget)"
+
 func (s *BudgetService) GetBudget(month string) (*BudgetResponse, error) {
 	firstDay, lastDay := monthDateRange(month)
 
@@ -57,7 +67,30 @@ func (s *BudgetService) GetBudget(month string) (*BudgetResponse, error) {
 	if err := s.db.Raw(`SELECT
 		COALESCE(SUM(CASE WHEN date >= ? AND date <= ? THEN amount ELSE 0 END), 0) as month_income,
 		COALESCE(SUM(amount), 0) as cumulative_income
-		FROM transactions WHERE type='income' AND date <= ?`, firstDay, lastDay, lastDay).Scan(&income).Error; err != nil {
+		FROM transactions WHERE type='income' AND kind <> 'opening_balance' AND transfer_id IS NULL AND date <= ?
+		AND account_id IN `+onBudgetAccountsSQL, firstDay, lastDay, lastDay).Scan(&income).Error; err != nil {
+		return nil, err
+	}
+
+	// Transfers across the budget's boundary bring money in or take it out
+	var tracking struct {
+		MonthAmount int64
+		CumAmount   int64
+	}
+	if err := s.db.Raw(`SELECT
+		COALESCE(SUM(CASE WHEN date >= ? AND date <= ? THEN (CASE WHEN type='income' THEN amount ELSE -amount END) ELSE 0 END), 0) as month_amount,
+		COALESCE(SUM(CASE WHEN type='income' THEN amount ELSE -amount END), 0) as cum_amount
+		FROM transactions WHERE transfer_id IS NOT NULL AND date <= ?
+		AND account_id IN `+onBudgetAccountsSQL+` AND transfer_account_id NOT IN `+onBudgetAccountsSQL, firstDay, lastDay, lastDay).Scan(&tracking).Error; err != nil {
+		return nil, err
+	}
+
+	// Opening balances aren't income, but those of accounts that budget them
+	// are money to assign from their date on
+	var openingBalances int64
+	if err := s.db.Raw(`SELECT COALESCE(SUM(CASE WHEN t.type = 'income' THEN t.amount ELSE -t.amount END), 0)
+		FROM transactions t JOIN accounts a ON a.id = t.account_id
+		WHERE t.kind = 'opening_balance' AND a.opening_balance_budget = 'ready_to_assign' AND NOT a.off_budget AND t.date <= ?`, lastDay).Scan(&openingBalances).Error; err != nil {
 		return nil, err
 	}
 
@@ -97,7 +130,8 @@ func (s *BudgetService) GetBudget(month string) (*BudgetResponse, error) {
 	if err := s.db.Raw(`SELECT category_id,
 		COALESCE(SUM(CASE WHEN date >= ? AND date <= ? THEN amount ELSE 0 END), 0) as month_amount,
 		COALESCE(SUM(amount), 0) as cum_amount
-		FROM transactions WHERE type='expense' AND date <= ? AND category_id IS NOT NULL
+		FROM `+categoryLinesSQL+` WHERE type='expense' AND kind <> 'opening_balance' AND transfer_id IS NULL AND date <= ? AND category_id IS NOT NULL
+		AND account_id IN `+onBudgetAccountsSQL+`
 		GROUP BY category_id`, firstDay, lastDay, lastDay).Scan(&expenseRows).Error; err != nil {
 		return nil, err
 	}
@@ -108,9 +142,30 @@ func (s *BudgetService) GetBudget(month string) (*BudgetResponse, error) {
 		cumExpenseMap[e.CategoryID] = e.CumAmount
 	}
 
+	// Funded card spending moves into each card's payment category, and
+	// payments to the card draw it down
+	cards, err := s.loadCardActivity(month, lastDay)
+	if err != nil {
+		return nil, err
+	}
+	var cardAccounts []models.Account
+	if err := s.db.Where("type = ? AND NOT off_budget", "credit").Order("name").Find(&cardAccounts).Error; err != nil {
+		return nil, err
+	}
+	paymentCategories := make(map[uint]uint)
+	for _, cat := range categories {
+		if cat.PaymentAccountID == nil {
+			continue
+		}
+		card := *cat.PaymentAccountID
+		paymentCategories[card] = cat.ID
+		monthExpenseMap[cat.ID] += cards.payments[card][month] - cards.funded[card][month]
+		cumExpenseMap[cat.ID] += sumThrough(cards.payments[card], month) - sumThrough(cards.funded[card], month)
+	}
+
 	// 5. Uncategorized expense count
 	var uncategorizedExpenses int64
-	if err := s.db.Raw("SELECT COUNT(*) FROM transactions WHERE type='expense' AND category_id IS NULL AND date >= ? AND date <= ?", firstDay, lastDay).Scan(&uncategorizedExpenses).Error; err != nil {
+	if err := s.db.Raw("SELECT COUNT(DISTINCT id) FROM "+categoryLinesSQL+" WHERE type='expense' AND kind <> 'opening_balance' AND transfer_id IS NULL AND category_id IS NULL AND date >= ? AND date <= ? AND account_id IN "+onBudgetAccountsSQL, firstDay, lastDay).Scan(&uncategorizedExpenses).Error; err != nil {
 		return nil, err
 	}
 
@@ -140,12 +195,14 @@ func (s *BudgetService) GetBudget(month string) (*BudgetResponse, error) {
 		cumulativeTotalAssigned += cumAssigned
 
 		row := BudgetCategoryRow{
-			CategoryID:   cat.ID,
-			CategoryName: cat.Name,
-			Colour:       cat.Colour,
-			Assigned:     assigned,
-			Activity:     activity,
-			Available:    available,
+			CategoryID:       cat.ID,
+			CategoryName:     cat.Name,
+			Colour:           cat.Colour,
+			Assigned:         assigned,
+			Activity:         activity,
+			Available:        available,
+			PaymentAccountID: cat.PaymentAccountID,
+			GroupID:          cat.GroupID,
 		}
 
 		if target, ok := targetMap[cat.ID]; ok {
@@ -160,7 +217,32 @@ func (s *BudgetService) GetBudget(month string) (*BudgetResponse, error) {
 		rows = append(rows, row)
 	}
 
-	readyToAssign := income.CumulativeIncome - cumulativeTotalAssigned
+	creditCards := make([]CreditCardSummary, 0, len(cardAccounts))
+	var creditOverspending int64
+	for _, a := range cardAccounts {
+		summary := CreditCardSummary{
+			AccountID:         a.ID,
+			AccountName:       a.Name,
+			PaymentCategoryID: paymentCategories[a.ID],
+			Debt:              cards.debt[a.ID],
+			Overspending:      cards.overspent[a.ID][month],
+		}
+		for _, row := range rows {
+			if row.CategoryID == summary.PaymentCategoryID {
+				summary.Available = row.Available
+			}
+		}
+		creditOverspending += summary.Overspending
+		creditCards = append(creditCards, summary)
+	}
+
+	// 8. Nest the rows under their groups
+	var groups []models.CategoryGroup
+	if err := s.db.Order("sort_order, id").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	readyToAssign := income.CumulativeIncome + tracking.CumAmount + openingBalances - cumulativeTotalAssigned
 
 	return &BudgetResponse{
 		Month:                 month,
@@ -169,7 +251,11 @@ func (s *BudgetService) GetBudget(month string) (*BudgetResponse, error) {
 		ReadyToAssign:         readyToAssign,
 		TotalUnderfunded:      totalUnderfunded,
 		UncategorizedExpenses: uncategorizedExpenses,
+		TrackingTransfers:     tracking.MonthAmount,
+		CreditOverspending:    creditOverspending,
+		CreditCards:           creditCards,
 		Categories:            rows,
+		Groups:                groupBudgetRows(groups, categories, rows),
 	}, nil
 }
 
@@ -220,7 +306,7 @@ func (s *BudgetService) GetCategoryAverage(categoryID uint, month string) (int64
 	firstOfMonth := t.Format("2006-01-02")
 
 	var total int64
-	err := s.db.Raw("SELECT COALESCE(SUM(amount),0) FROM transactions WHERE type='expense' AND category_id=? AND date >= ? AND date < ?",
+	err := s.db.Raw("SELECT COALESCE(SUM(amount),0) FROM "+categoryLinesSQL+" WHERE type='expense' AND transfer_id IS NULL AND category_id=? AND date >= ? AND date < ? AND account_id IN "+onBudgetAccountsSQL,
 		categoryID, threeMonthsAgo, firstOfMonth).Scan(&total).Error
 	if err != nil {
 		return 0, err
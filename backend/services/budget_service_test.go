@@ -4,6 +4,8 @@ import (
 	"budgetting-app/backend/models"
 	"budgetting-app/backend/testutil"
 	"testing"
+
+	"gorm.io/gorm"
 )
 
 func setupBudgetTest(t *testing.T) (*BudgetService, *models.Account, *models.Category) {
@@ -111,6 +113,71 @@ func TestBudgetService_Rollover(t *testing.T) {
 	}
 }
 
+func TestBudgetService_TransfersExcluded(t *testing.T) {
+	svc, account, _ := setupBudgetTest(t)
+	savings := models.Account{Name: "Savings", Type: "savings"}
+	svc.db.Create(&savings)
+
+	svc.db.Create(&models.Transaction{
+		AccountID: account.ID, Amount: 300000, Description: "Salary",
+		Date: "2024-01-15", Type: "income",
+	})
+	txnSvc := NewTransactionService(svc.db)
+	if _, err := txnSvc.CreateTransfer(CreateTransferInput{
+		FromAccountID: account.ID, ToAccountID: savings.ID,
+		Amount: 100000, Description: "To savings", Date: "2024-01-20",
+	}); err != nil {
+		t.Fatalf("create transfer failed: %v", err)
+	}
+
+	resp, err := svc.GetBudget("2024-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Income != 300000 {
+		t.Errorf("expected income 300000, got %d", resp.Income)
+	}
+	if resp.ReadyToAssign != 300000 {
+		t.Errorf("expected ready to assign 300000, got %d", resp.ReadyToAssign)
+	}
+	if resp.UncategorizedExpenses != 0 {
+		t.Errorf("expected 0 uncategorized expenses, got %d", resp.UncategorizedExpenses)
+	}
+}
+
+func TestBudgetService_SplitActivity(t *testing.T) {
+	svc, account, category := setupBudgetTest(t)
+	household := models.Category{Name: "Household", Colour: "#00FF00"}
+	svc.db.Create(&household)
+
+	svc.db.Create(&models.Transaction{
+		AccountID: account.ID, Amount: 5000, Description: "Tesco",
+		Date: "2024-01-20", Type: "expense",
+		Splits: []models.TransactionSplit{
+			{CategoryID: &category.ID, Amount: 3000},
+			{CategoryID: &household.ID, Amount: 2000},
+		},
+	})
+
+	resp, err := svc.GetBudget("2024-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	activity := map[uint]int64{}
+	for _, row := range resp.Categories {
+		activity[row.CategoryID] = row.Activity
+	}
+	if activity[category.ID] != 3000 {
+		t.Errorf("expected food activity 3000, got %d", activity[category.ID])
+	}
+	if activity[household.ID] != 2000 {
+		t.Errorf("expected household activity 2000, got %d", activity[household.ID])
+	}
+	if resp.UncategorizedExpenses != 0 {
+		t.Errorf("expected 0 uncategorized expenses, got %d", resp.UncategorizedExpenses)
+	}
+}
+
 func TestBudgetService_UncategorizedExpenses(t *testing.T) {
 	svc, account, _ := setupBudgetTest(t)
 
@@ -490,3 +557,291 @@ func TestBudgetService_AverageRounding(t *testing.T) {
 		t.Errorf("expected average 67, got %d", avg)
 	}
 }
+
+func TestBudgetService_OpeningBalances(t *testing.T) {
+	svc, _, _ := setupBudgetTest(t)
+	accounts := NewAccountService(svc.db)
+
+	balance := int64(150000)
+	if _, err := accounts.Create(CreateAccountInput{Name: "Savings", Type: "savings", StartingBalance: &balance, OpeningDate: "2023-06-01"}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	other := int64(40000)
+	if _, err := accounts.Create(CreateAccountInput{Name: "Old ISA", Type: "savings", StartingBalance: &other, OpeningDate: "2023-06-01", OpeningBalanceBudget: "excluded"}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	resp, err := svc.GetBudget("2023-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Income != 0 {
+		t.Errorf("expected opening balances not to count as income, got %d", resp.Income)
+	}
+	if resp.ReadyToAssign != 150000 {
+		t.Errorf("expected only the budgeted opening balance to be ready to assign, got %d", resp.ReadyToAssign)
+	}
+
+	resp, _ = svc.GetBudget("2023-05")
+	if resp.ReadyToAssign != 0 {
+		t.Errorf("expected nothing to assign before the opening date, got %d", resp.ReadyToAssign)
+	}
+}
+
+func TestBudgetService_TrackingAccounts(t *testing.T) {
+	svc, account, category := setupBudgetTest(t)
+	pension := models.Account{Name: "Pension", Type: "savings", OffBudget: true}
+	svc.db.Create(&pension)
+
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 300000, Description: "Salary", Date: "2024-01-15", Type: "income"})
+	// Activity inside a tracking account never reaches the budget
+	svc.db.Create(&models.Transaction{AccountID: pension.ID, Amount: 50000, Description: "Growth", Date: "2024-01-31", Type: "income"})
+	svc.db.Create(&models.Transaction{AccountID: pension.ID, CategoryID: &category.ID, Amount: 2000, Description: "Fees", Date: "2024-01-31", Type: "expense"})
+	svc.db.Create(&models.Transaction{AccountID: pension.ID, Amount: 1000, Description: "Charge", Date: "2024-01-31", Type: "expense"})
+
+	txnSvc := NewTransactionService(svc.db)
+	txnSvc.CreateTransfer(CreateTransferInput{FromAccountID: account.ID, ToAccountID: pension.ID, Amount: 40000, Description: "Contribution", Date: "2024-01-20"})
+	txnSvc.CreateTransfer(CreateTransferInput{FromAccountID: pension.ID, ToAccountID: account.ID, Amount: 10000, Description: "Withdrawal", Date: "2024-01-25"})
+
+	resp, err := svc.GetBudget("2024-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Income != 300000 {
+		t.Errorf("expected income 300000, got %d", resp.Income)
+	}
+	if resp.TrackingTransfers != -30000 {
+		t.Errorf("expected net tracking transfers -30000, got %d", resp.TrackingTransfers)
+	}
+	if resp.ReadyToAssign != 270000 {
+		t.Errorf("expected ready to assign 270000, got %d", resp.ReadyToAssign)
+	}
+	if resp.UncategorizedExpenses != 0 {
+		t.Errorf("expected 0 uncategorized expenses, got %d", resp.UncategorizedExpenses)
+	}
+	for _, row := range resp.Categories {
+		if row.CategoryID == category.ID && row.Activity != 0 {
+			t.Errorf("expected no activity from the tracking account, got %d", row.Activity)
+		}
+	}
+
+	resp, _ = svc.GetBudget("2024-02")
+	if resp.TrackingTransfers != 0 || resp.ReadyToAssign != 270000 {
+		t.Errorf("expected the transfers to carry into February's ready to assign only, got %+v", resp)
+	}
+}
+
+func TestBudgetService_CreditCards(t *testing.T) {
+	svc, account, category := setupBudgetTest(t)
+	card, err := NewAccountService(svc.db).Create(CreateAccountInput{Name: "Visa", Type: "credit"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var payment models.Category
+	if err := svc.db.Where("payment_account_id = ?", card.ID).First(&payment).Error; err != nil {
+		t.Fatalf("expected the card to get a payment category: %v", err)
+	}
+	if payment.Name != "Visa Payment" {
+		t.Errorf("expected category 'Visa Payment', got %q", payment.Name)
+	}
+
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 100000, Description: "Salary", Date: "2024-01-01", Type: "income"})
+	svc.AllocateBudget("2024-01", category.ID, 10000)
+	// 3000 from checking comes first, leaving 7000 of the 12000 on the card funded
+	svc.db.Create(&models.Transaction{AccountID: account.ID, CategoryID: &category.ID, Amount: 3000, Description: "Market", Date: "2024-01-05", Type: "expense"})
+	svc.db.Create(&models.Transaction{AccountID: card.ID, CategoryID: &category.ID, Amount: 12000, Description: "Shop", Date: "2024-01-10", Type: "expense"})
+	NewTransactionService(svc.db).CreateTransfer(CreateTransferInput{FromAccountID: account.ID, ToAccountID: card.ID, Amount: 4000, Description: "Card payment", Date: "2024-01-20"})
+
+	resp, err := svc.GetBudget("2024-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows := make(map[uint]BudgetCategoryRow)
+	for _, row := range resp.Categories {
+		rows[row.CategoryID] = row
+	}
+	if row := rows[payment.ID]; row.Activity != -3000 || row.Available != 3000 || row.PaymentAccountID == nil || *row.PaymentAccountID != card.ID {
+		t.Errorf("expected the payment category to hold 7000 funded less the 4000 paid, got %+v", row)
+	}
+	if row := rows[category.ID]; row.Activity != 15000 || row.Available != -5000 {
+		t.Errorf("expected Food to show its spending, got %+v", row)
+	}
+	if resp.CreditOverspending != 5000 {
+		t.Errorf("expected 5000 credit overspending, got %d", resp.CreditOverspending)
+	}
+	if len(resp.CreditCards) != 1 {
+		t.Fatalf("expected 1 credit card, got %+v", resp.CreditCards)
+	}
+	summary := resp.CreditCards[0]
+	if summary.AccountID != card.ID || summary.PaymentCategoryID != payment.ID || summary.Debt != 8000 || summary.Available != 3000 || summary.Overspending != 5000 {
+		t.Errorf("unexpected card summary: %+v", summary)
+	}
+}
+
+func TestBudgetService_MoveMoney(t *testing.T) {
+	svc, account, food := setupBudgetTest(t)
+	fun := models.Category{Name: "Fun", Colour: "#00FF00"}
+	svc.db.Create(&fun)
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 50000, Description: "Salary", Date: "2024-01-01", Type: "income"})
+	svc.AllocateBudget("2024-01", food.ID, 20000)
+	svc.AllocateBudget("2024-02", food.ID, 2000)
+
+	if _, err := svc.MoveMoney(MoveMoneyInput{Month: "2024-01", FromCategoryID: &food.ID, ToCategoryID: &fun.ID, Amount: 5000, Note: "Concert"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.MoveMoney(MoveMoneyInput{Month: "2024-01", ToCategoryID: &fun.ID, Amount: 1000}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.MoveMoney(MoveMoneyInput{Month: "2024-02", FromCategoryID: &food.ID, Amount: 2000}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.MoveMoney(MoveMoneyInput{Month: "2024-01", FromCategoryID: &fun.ID, ToCategoryID: &fun.ID, Amount: 1000}); err != ErrMoveToSelf {
+		t.Errorf("expected ErrMoveToSelf, got %v", err)
+	}
+	missing := uint(99)
+	if _, err := svc.MoveMoney(MoveMoneyInput{Month: "2024-01", FromCategoryID: &food.ID, ToCategoryID: &missing, Amount: 1000}); err != gorm.ErrRecordNotFound {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+	// Food has 15000 left, and Ready to Assign 29000
+	if _, err := svc.MoveMoney(MoveMoneyInput{Month: "2024-01", FromCategoryID: &food.ID, ToCategoryID: &fun.ID, Amount: 15001}); err != ErrInsufficientFunds {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if _, err := svc.MoveMoney(MoveMoneyInput{Month: "2024-01", ToCategoryID: &fun.ID, Amount: 29001}); err != ErrInsufficientFunds {
+		t.Errorf("expected ErrInsufficientFunds from Ready to Assign, got %v", err)
+	}
+
+	resp, _ := svc.GetBudget("2024-01")
+	assigned := make(map[uint]int64)
+	for _, row := range resp.Categories {
+		assigned[row.CategoryID] = row.Assigned
+	}
+	// The failed moves must not have taken money from Food
+	if assigned[food.ID] != 15000 || assigned[fun.ID] != 6000 {
+		t.Errorf("expected Food 15000 and Fun 6000 assigned, got %v", assigned)
+	}
+	if resp.ReadyToAssign != 29000 {
+		t.Errorf("expected ready to assign 29000, got %d", resp.ReadyToAssign)
+	}
+
+	moves, err := svc.ListMoves("2024-01", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(moves) != 2 || moves[1].Note != "Concert" || moves[1].Amount != 5000 || moves[0].FromCategoryID != nil {
+		t.Errorf("unexpected January moves: %+v", moves)
+	}
+	moves, _ = svc.ListMoves("", &food.ID)
+	if len(moves) != 2 || moves[0].Month != "2024-02" || moves[0].ToCategoryID != nil {
+		t.Errorf("unexpected Food moves: %+v", moves)
+	}
+}
+
+func TestBudgetService_AutoAssign(t *testing.T) {
+	svc, account, food := setupBudgetTest(t)
+	rent := models.Category{Name: "Rent", Colour: "#0000FF"}
+	fun := models.Category{Name: "Fun", Colour: "#00FF00"}
+	svc.db.Create(&rent)
+	svc.db.Create(&fun)
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 20000, Description: "Salary", Date: "2024-01-01", Type: "income"})
+	svc.SetCategoryTarget(food.ID, "2024-01", "monthly_savings", 10000, nil)
+	due := "2024-03"
+	svc.SetCategoryTarget(rent.ID, "2024-01", "savings_balance", 30000, &due)
+
+	// Rent's dated target comes first and takes 15000; Food gets what is left
+	result, err := svc.AutoAssign(AutoAssignInput{Month: "2024-01", Strategy: "underfunded", Preview: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Total != 20000 || len(result.Allocations) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if a := result.Allocations[0]; a.CategoryID != rent.ID || a.Proposed != 15000 || a.Capped {
+		t.Errorf("expected Rent funded in full first, got %+v", a)
+	}
+	if a := result.Allocations[1]; a.CategoryID != food.ID || a.Proposed != 5000 || a.Wanted != 10000 || a.Reason != "capped" {
+		t.Errorf("expected Food capped at 5000, got %+v", a)
+	}
+	resp, _ := svc.GetBudget("2024-01")
+	if resp.TotalAssigned != 0 {
+		t.Errorf("expected a preview to assign nothing, got %d", resp.TotalAssigned)
+	}
+
+	// Only the listed category is funded
+	result, _ = svc.AutoAssign(AutoAssignInput{Month: "2024-01", Strategy: "underfunded", CategoryIDs: []uint{food.ID}})
+	if len(result.Allocations) != 1 || result.Allocations[0].Proposed != 10000 {
+		t.Errorf("expected only Food funded, got %+v", result.Allocations)
+	}
+
+	svc.AllocateBudget("2024-01", fun.ID, 2000)
+	// Food takes the 8000 left and Fun gets nothing, but the preview still reports it
+	result, _ = svc.AutoAssign(AutoAssignInput{Month: "2024-02", Strategy: "last_month", Preview: true})
+	if last := result.Allocations[len(result.Allocations)-1]; last.CategoryID != fun.ID || last.Proposed != 0 || last.Wanted != 2000 || last.Reason != "insufficient_funds" {
+		t.Errorf("expected Fun reported as unfunded, got %+v", last)
+	}
+	result, _ = svc.AutoAssign(AutoAssignInput{Month: "2024-02", Strategy: "last_month"})
+	if result.Total != 8000 || !result.Allocations[len(result.Allocations)-1].Capped {
+		t.Errorf("expected January's 12000 capped at the 8000 left, got %+v", result)
+	}
+	resp, _ = svc.GetBudget("2024-02")
+	if resp.TotalAssigned != 8000 {
+		t.Errorf("expected 8000 assigned in February, got %d assigned", resp.TotalAssigned)
+	}
+
+	if _, err := svc.AutoAssign(AutoAssignInput{Month: "2024-01", Strategy: "reset"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, _ = svc.GetBudget("2024-01")
+	if resp.TotalAssigned != 0 {
+		t.Errorf("expected reset to clear January, got %d assigned", resp.TotalAssigned)
+	}
+
+	if _, err := svc.AutoAssign(AutoAssignInput{Month: "2024-01", Strategy: "reset", CategoryIDs: []uint{99}}); err != gorm.ErrRecordNotFound {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestBudgetService_Groups(t *testing.T) {
+	svc, account, food := setupBudgetTest(t)
+	groups := NewCategoryGroupService(svc.db)
+	bills, _ := groups.Create("Fixed Bills")
+	everyday, _ := groups.Create("Everyday")
+	groups.Create("Savings Goals")
+	rent := models.Category{Name: "Rent", Colour: "#0000FF"}
+	power := models.Category{Name: "Power", Colour: "#00FF00"}
+	salary := models.Category{Name: "Salary", Colour: "#FFFF00"}
+	svc.db.Create(&rent)
+	svc.db.Create(&power)
+	svc.db.Create(&salary)
+	groups.SetCategories(bills.ID, []uint{rent.ID, power.ID})
+	groups.SetCategories(everyday.ID, []uint{food.ID})
+
+	svc.AllocateBudget("2024-01", rent.ID, 80000)
+	svc.AllocateBudget("2024-01", power.ID, 6000)
+	svc.SetCategoryTarget(power.ID, "2024-01", "monthly_savings", 10000, nil)
+	svc.db.Create(&models.Transaction{AccountID: account.ID, CategoryID: &rent.ID, Amount: 80000, Description: "Rent", Date: "2024-01-01", Type: "expense"})
+	svc.db.Create(&models.Transaction{AccountID: account.ID, CategoryID: &power.ID, Amount: 5000, Description: "Power", Date: "2024-01-10", Type: "expense"})
+
+	resp, err := svc.GetBudget("2024-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Groups) != 4 {
+		t.Fatalf("expected 3 groups and the ungrouped categories, got %+v", resp.Groups)
+	}
+	g := resp.Groups[0]
+	if g.Name != "Fixed Bills" || g.Assigned != 86000 || g.Activity != 85000 || g.Available != 1000 || g.Underfunded != 4000 {
+		t.Errorf("unexpected Fixed Bills subtotals: %+v", g)
+	}
+	if len(g.Categories) != 2 || g.Categories[0].CategoryID != rent.ID || g.Categories[1].CategoryID != power.ID {
+		t.Errorf("expected Rent before Power, got %+v", g.Categories)
+	}
+	if resp.Groups[2].Name != "Savings Goals" || len(resp.Groups[2].Categories) != 0 {
+		t.Errorf("expected an empty Savings Goals group, got %+v", resp.Groups[2])
+	}
+	if u := resp.Groups[3]; u.GroupID != nil || u.Name != UngroupedName || len(u.Categories) != 1 || u.Categories[0].CategoryID != salary.ID {
+		t.Errorf("expected Salary to be ungrouped, got %+v", u)
+	}
+	if len(resp.Categories) != 4 {
+		t.Errorf("expected the flat list to keep every category, got %d", len(resp.Categories))
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+type CategoryGroupService struct {
+	db *gorm.DB
+}
+
+func NewCategoryGroupService(db *gorm.DB) *CategoryGroupService {
+	return &CategoryGroupService{db: db}
+}
+
+// List returns the groups in their display order.
+func (s *CategoryGroupService) List() ([]models.CategoryGroup, error) {
+	var groups []models.CategoryGroup
+	err := s.db.Order("sort_order, id").Find(&groups).Error
+	return groups, err
+}
+
+// Create adds a group after the existing ones.
+func (s *CategoryGroupService) Create(name string) (models.CategoryGroup, error) {
+	var last struct{ SortOrder *int }
+	if err := s.db.Model(&models.CategoryGroup{}).Select("MAX(sort_order) AS sort_order").Scan(&last).Error; err != nil {
+		return models.CategoryGroup{}, err
+	}
+	group := models.CategoryGroup{Name: name}
+	if last.SortOrder != nil {
+		group.SortOrder = *last.SortOrder + 1
+	}
+	err := s.db.Create(&group).Error
+	return group, err
+}
+
+func (s *CategoryGroupService) Update(id uint, name string) (models.CategoryGroup, error) {
+	var group models.CategoryGroup
+	if err := s.db.First(&group, id).Error; err != nil {
+		return group, err
+	}
+	err := s.db.Model(&group).Update("name", name).Error
+	return group, err
+}
+
+// Delete removes a group. Its categories are kept, ungrouped.
+func (s *CategoryGroupService) Delete(id uint) error {
+	var group models.CategoryGroup
+	if err := s.db.First(&group, id).Error; err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Category{}).Where("group_id = ?", id).Update("group_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&group).Error
+	})
+}
+
+// Reorder puts the groups in the order given. Groups left out keep their
+// position after the listed ones. It returns gorm.ErrRecordNotFound if a
+// listed group doesn't exist.
+func (s *CategoryGroupService) Reorder(groupIDs []uint) ([]models.CategoryGroup, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var groups []models.CategoryGroup
+		if err := tx.Order("sort_order, id").Find(&groups).Error; err != nil {
+			return err
+		}
+		position := make(map[uint]int, len(groups))
+		for i, id := range groupIDs {
+			position[id] = i
+		}
+		for _, id := range groupIDs {
+			if !containsGroup(groups, id) {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		next := len(groupIDs)
+		for _, g := range groups {
+			order, listed := position[g.ID]
+			if !listed {
+				order = next
+				next++
+			}
+			if err := tx.Model(&g).Update("sort_order", order).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.List()
+}
+
+// SetCategories makes categoryIDs the group's categories, in that order.
+// Listed categories leave any other group, and the group's categories that
+// aren't listed become ungrouped. It returns gorm.ErrRecordNotFound if the
+// group or a listed category doesn't exist.
+func (s *CategoryGroupService) SetCategories(id uint, categoryIDs []uint) ([]models.Category, error) {
+	var group models.CategoryGroup
+	if err := s.db.First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Category{}).Where("id IN ?", categoryIDs).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(uniqueIDs(categoryIDs)) {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.Category{}).Where("group_id = ?", id).Update("group_id", nil).Error; err != nil {
+			return err
+		}
+		for i, categoryID := range categoryIDs {
+			if err := tx.Model(&models.Category{}).Where("id = ?", categoryID).
+				Updates(map[string]interface{}{"group_id": id, "sort_order": i}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var categories []models.Category
+	err = s.db.Where("group_id = ?", id).Order("sort_order, name").Find(&categories).Error
+	return categories, err
+}
+
+func containsGroup(groups []models.CategoryGroup, id uint) bool {
+	for _, g := range groups {
+		if g.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueIDs(ids []uint) map[uint]bool {
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	return seen
+}
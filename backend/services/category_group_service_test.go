@@ -0,0 +1,75 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/testutil"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCategoryGroupService_Order(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewCategoryGroupService(db)
+	bills, _ := svc.Create("Fixed Bills")
+	everyday, _ := svc.Create("Everyday")
+	goals, _ := svc.Create("Savings Goals")
+	if bills.SortOrder != 0 || goals.SortOrder != 2 {
+		t.Errorf("expected new groups to go last, got %d and %d", bills.SortOrder, goals.SortOrder)
+	}
+
+	groups, err := svc.Reorder([]uint{goals.ID, bills.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 3 || groups[0].ID != goals.ID || groups[1].ID != bills.ID || groups[2].ID != everyday.ID {
+		t.Errorf("expected Savings Goals, Fixed Bills, Everyday, got %+v", groups)
+	}
+	if _, err := svc.Reorder([]uint{99}); err != gorm.ErrRecordNotFound {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestCategoryGroupService_SetCategories(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewCategoryGroupService(db)
+	bills, _ := svc.Create("Fixed Bills")
+	everyday, _ := svc.Create("Everyday")
+	rent := models.Category{Name: "Rent", Colour: "#000000"}
+	power := models.Category{Name: "Power", Colour: "#000000"}
+	food := models.Category{Name: "Food", Colour: "#000000"}
+	db.Create(&rent)
+	db.Create(&power)
+	db.Create(&food)
+
+	svc.SetCategories(everyday.ID, []uint{food.ID, power.ID})
+	categories, err := svc.SetCategories(bills.ID, []uint{rent.ID, power.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(categories) != 2 || categories[0].ID != rent.ID || categories[1].ID != power.ID {
+		t.Errorf("expected Rent then Power, got %+v", categories)
+	}
+	db.First(&food, food.ID)
+	if food.GroupID == nil || *food.GroupID != everyday.ID {
+		t.Errorf("expected Food to stay in Everyday, got %v", food.GroupID)
+	}
+
+	// Power is left out and drops out of the group
+	svc.SetCategories(bills.ID, []uint{rent.ID})
+	db.First(&power, power.ID)
+	if power.GroupID != nil {
+		t.Errorf("expected Power to be ungrouped, got %v", *power.GroupID)
+	}
+	if _, err := svc.SetCategories(bills.ID, []uint{rent.ID, 99}); err != gorm.ErrRecordNotFound {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	if err := svc.Delete(bills.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.First(&rent, rent.ID)
+	if rent.GroupID != nil {
+		t.Errorf("expected Rent to be kept ungrouped, got %v", *rent.GroupID)
+	}
+}
@@ -20,7 +20,14 @@ func (s *CategoryService) List() ([]models.Category, error) {
 	return categories, err
 }
 
+// Create adds a category. It returns gorm.ErrRecordNotFound if the category
+// is given a group that doesn't exist.
 func (s *CategoryService) Create(category *models.Category) error {
+	if category.GroupID != nil {
+		if err := s.db.First(&models.CategoryGroup{}, *category.GroupID).Error; err != nil {
+			return err
+		}
+	}
 	return s.db.Create(category).Error
 }
 
@@ -40,11 +47,18 @@ func (s *CategoryService) Delete(id uint) error {
 	if err := s.db.First(&category, id).Error; err != nil {
 		return err
 	}
+	if category.PaymentAccountID != nil {
+		return ErrPaymentCategory
+	}
 	var count int64
 	s.db.Model(&models.Transaction{}).Where("category_id = ?", id).Count(&count)
 	if count > 0 {
 		return ErrCategoryHasTransactions
 	}
+	s.db.Model(&models.TransactionSplit{}).Where("category_id = ?", id).Count(&count)
+	if count > 0 {
+		return ErrCategoryHasTransactions
+	}
 	s.db.Model(&models.BudgetAllocation{}).Where("category_id = ?", id).Count(&count)
 	if count > 0 {
 		return ErrCategoryHasTransactions
@@ -53,5 +67,132 @@ func (s *CategoryService) Delete(id uint) error {
 	if count > 0 {
 		return ErrCategoryHasTransactions
 	}
-	return s.db.Delete(&category).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Recurring rules fall back to posting uncategorized
+		if err := tx.Model(&models.RecurringTransaction{}).Where("category_id = ?", id).Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Rule{}).Where("set_category_id = ?", id).Update("set_category_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+}
+
+type CategoryMergeResult struct {
+	Category     models.Category `json:"category"`     // the target, now holding everything
+	Transactions int64           `json:"transactions"` // transactions and split lines moved from the source
+	Allocations  int64           `json:"allocations"`  // months of budget moved or added to the target's
+	Moves        int64           `json:"moves"`        // budget moves relabelled from the source to the target
+	Targets      string          `json:"targets"`      // whose targets were kept: source | target
+}
+
+// Merge moves everything that references the source category to the target
+// and deletes the source, all in one database transaction. Budget allocations
+// are unique per month, so a month budgeted in both categories is summed into
+// the target's allocation. A category's targets form one timeline, which
+// can't be interleaved with another, so when both categories have targets
+// only one side's are kept: the target's unless keepTargets is "source".
+func (s *CategoryService) Merge(sourceID, targetID uint, keepTargets string) (*CategoryMergeResult, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeIntoSelf
+	}
+	var source, target models.Category
+	if err := s.db.First(&source, sourceID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&target, targetID).Error; err != nil {
+		return nil, err
+	}
+
+	if source.PaymentAccountID != nil {
+		return nil, ErrPaymentCategory
+	}
+
+	result := &CategoryMergeResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return mergeCategories(tx, source, target, keepTargets, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Category = target
+	return result, nil
+}
+
+// mergeCategories moves everything referencing source to target and deletes
+// source, inside the caller's database transaction.
+func mergeCategories(tx *gorm.DB, source, target models.Category, keepTargets string, result *CategoryMergeResult) error {
+	for _, model := range []interface{}{&models.Transaction{}, &models.TransactionSplit{}} {
+		res := tx.Model(model).Where("category_id = ?", source.ID).Update("category_id", target.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Transactions += res.RowsAffected
+	}
+
+	var allocations []models.BudgetAllocation
+	if err := tx.Where("category_id = ?", source.ID).Find(&allocations).Error; err != nil {
+		return err
+	}
+	var err error
+	for _, a := range allocations {
+		res := tx.Model(&models.BudgetAllocation{}).Where("category_id = ? AND month = ?", target.ID, a.Month).
+			Update("amount", gorm.Expr("amount + ?", a.Amount))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			err = tx.Model(&a).Update("category_id", target.ID).Error
+		} else {
+			err = tx.Delete(&a).Error
+		}
+		if err != nil {
+			return err
+		}
+		result.Allocations++
+	}
+
+	if err := mergeTargets(tx, source.ID, target.ID, keepTargets, result); err != nil {
+		return err
+	}
+	if err := tx.Model(&models.RecurringTransaction{}).Where("category_id = ?", source.ID).Update("category_id", target.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Rule{}).Where("set_category_id = ?", source.ID).Update("set_category_id", target.ID).Error; err != nil {
+		return err
+	}
+	// Moves keep their history; those between the two become moves within the target
+	for _, column := range []string{"from_category_id", "to_category_id"} {
+		res := tx.Model(&models.BudgetMove{}).Where(column+" = ?", source.ID).
+			Updates(map[string]interface{}{column: target.ID, "merged_from": source.Name})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Moves += res.RowsAffected
+	}
+	return tx.Delete(&source).Error
+}
+
+// mergeTargets gives the target category one side's targets. A side with no
+// targets never wins over one that has them.
+func mergeTargets(tx *gorm.DB, sourceID, targetID uint, keep string, result *CategoryMergeResult) error {
+	var sourceCount, targetCount int64
+	if err := tx.Model(&models.CategoryTarget{}).Where("category_id = ?", sourceID).Count(&sourceCount).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.CategoryTarget{}).Where("category_id = ?", targetID).Count(&targetCount).Error; err != nil {
+		return err
+	}
+	result.Targets = "target"
+	if sourceCount > 0 && (targetCount == 0 || keep == "source") {
+		result.Targets = "source"
+	}
+	if result.Targets == "target" {
+		return tx.Where("category_id = ?", sourceID).Delete(&models.CategoryTarget{}).Error
+	}
+	if err := tx.Where("category_id = ?", targetID).Delete(&models.CategoryTarget{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.CategoryTarget{}).Where("category_id = ?", sourceID).Update("category_id", targetID).Error
 }
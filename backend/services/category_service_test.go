@@ -158,3 +158,78 @@ func TestCategoryService_ListOrdering(t *testing.T) {
 		t.Errorf("expected alphabetical order, got %s, %s, %s", cats[0].Name, cats[1].Name, cats[2].Name)
 	}
 }
+
+func TestCategoryService_Merge(t *testing.T) {
+	svc, db := setupCategoryTest(t)
+	account := models.Account{Name: "Checking", Type: "checking"}
+	db.Create(&account)
+	source := &models.Category{Name: "Eating Out", Colour: "#FF0000"}
+	target := &models.Category{Name: "Restaurants", Colour: "#00FF00"}
+	svc.Create(source)
+	svc.Create(target)
+
+	db.Create(&models.Transaction{AccountID: account.ID, CategoryID: &source.ID, Amount: 2500, Description: "Pizza", Date: "2024-01-10", Type: "expense"})
+	db.Create(&models.BudgetAllocation{Month: "2024-01", CategoryID: source.ID, Amount: 10000})
+	db.Create(&models.BudgetAllocation{Month: "2024-01", CategoryID: target.ID, Amount: 5000})
+	db.Create(&models.BudgetAllocation{Month: "2024-02", CategoryID: source.ID, Amount: 8000})
+	db.Create(&models.CategoryTarget{CategoryID: source.ID, TargetType: "monthly_savings", TargetAmount: 10000, EffectiveFrom: "2024-01"})
+	db.Create(&models.CategoryTarget{CategoryID: target.ID, TargetType: "monthly_savings", TargetAmount: 20000, EffectiveFrom: "2024-01"})
+	db.Create(&models.Rule{Name: "Pizza", SetCategoryID: &source.ID})
+	db.Create(&models.BudgetMove{Month: "2024-01", FromCategoryID: &target.ID, ToCategoryID: &source.ID, Amount: 1000})
+
+	result, err := svc.Merge(source.ID, target.ID, "target")
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if result.Transactions != 1 || result.Allocations != 2 || result.Moves != 1 || result.Targets != "target" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	var allocations []models.BudgetAllocation
+	db.Where("category_id = ?", target.ID).Order("month").Find(&allocations)
+	if len(allocations) != 2 || allocations[0].Amount != 15000 || allocations[1].Amount != 8000 {
+		t.Errorf("expected allocations summed per month, got %+v", allocations)
+	}
+	var targets []models.CategoryTarget
+	db.Find(&targets)
+	if len(targets) != 1 || targets[0].CategoryID != target.ID || targets[0].TargetAmount != 20000 {
+		t.Errorf("expected only the target's target to remain, got %+v", targets)
+	}
+	var rule models.Rule
+	db.First(&rule)
+	if rule.SetCategoryID == nil || *rule.SetCategoryID != target.ID {
+		t.Errorf("expected the rule to follow the merge, got %v", rule.SetCategoryID)
+	}
+	var move models.BudgetMove
+	db.First(&move)
+	if move.ToCategoryID == nil || *move.ToCategoryID != target.ID || move.MergedFrom != "Eating Out" {
+		t.Errorf("expected the move to be kept and marked, got %+v", move)
+	}
+	if err := db.First(&models.Category{}, source.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected the source to be deleted, got %v", err)
+	}
+}
+
+func TestCategoryService_MergeKeepsSourceTargets(t *testing.T) {
+	svc, db := setupCategoryTest(t)
+	source := &models.Category{Name: "Eating Out", Colour: "#FF0000"}
+	target := &models.Category{Name: "Restaurants", Colour: "#00FF00"}
+	svc.Create(source)
+	svc.Create(target)
+	db.Create(&models.CategoryTarget{CategoryID: source.ID, TargetType: "monthly_savings", TargetAmount: 10000, EffectiveFrom: "2024-01"})
+	db.Create(&models.CategoryTarget{CategoryID: target.ID, TargetType: "monthly_savings", TargetAmount: 20000, EffectiveFrom: "2024-01"})
+
+	result, err := svc.Merge(source.ID, target.ID, "source")
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	var targets []models.CategoryTarget
+	db.Find(&targets)
+	if result.Targets != "source" || len(targets) != 1 || targets[0].CategoryID != target.ID || targets[0].TargetAmount != 10000 {
+		t.Errorf("expected the source's target to move, got %+v", targets)
+	}
+
+	if _, err := svc.Merge(target.ID, target.ID, "target"); !errors.Is(err, ErrMergeIntoSelf) {
+		t.Errorf("expected ErrMergeIntoSelf, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// PaymentCategoryColour is the colour given to a credit card's payment
+// category when it is created.
+const PaymentCategoryColour = "#64748B"
+
+// CreditCardSummary is the position of an on-budget credit card at the end of
+// a budget month.
+type CreditCardSummary struct {
+	AccountID         uint   `json:"account_id"`
+	AccountName       string `json:"account_name"`
+	PaymentCategoryID uint   `json:"payment_category_id"`
+	Debt              int64  `json:"debt"`         // owed on the card, negative when it is in credit
+	Available         int64  `json:"available"`    // set aside in the payment category to pay it
+	Overspending      int64  `json:"overspending"` // card spending this month that no category had money for
+}
+
+// cardActivity is how on-budget credit cards moved money between categories,
+// per card account and month (YYYY-MM).
+type cardActivity struct {
+	funded    map[uint]map[string]int64 // card spending covered by its category's available money
+	overspent map[uint]map[string]int64 // card spending that wasn't
+	payments  map[uint]map[string]int64 // transfers from on-budget accounts to the card
+	debt      map[uint]int64            // owed on the card at the end of the month
+}
+
+// sumThrough adds up a card's amounts for the months up to and including
+// month.
+func sumThrough(byMonth map[string]int64, month string) int64 {
+	var total int64
+	for m, amount := range byMonth {
+		if m <= month {
+			total += amount
+		}
+	}
+	return total
+}
+
+// loadCardActivity works out, month by month up to month, how much of each
+// category's spending on credit cards was covered by money available in the
+// category. Within a month, spending from other accounts is taken to come
+// first. The covered part moves to the card's payment category; the rest is
+// overspending that adds to the card's debt without money to pay it.
+func (s *BudgetService) loadCardActivity(month, lastDay string) (*cardActivity, error) {
+	activity := &cardActivity{
+		funded:    make(map[uint]map[string]int64),
+		overspent: make(map[uint]map[string]int64),
+		payments:  make(map[uint]map[string]int64),
+		debt:      make(map[uint]int64),
+	}
+	add := func(m map[uint]map[string]int64, card uint, month string, amount int64) {
+		if m[card] == nil {
+			m[card] = make(map[string]int64)
+		}
+		m[card][month] += amount
+	}
+
+	type monthAmount struct {
+		CategoryID uint
+		Month      string
+		CardID     uint // 0 for spending from other accounts
+		Amount     int64
+	}
+	var allocations, spending []monthAmount
+	if err := s.db.Raw(`SELECT category_id, month, amount FROM budget_allocations WHERE month <= ?`, month).
+		Scan(&allocations).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Raw(`SELECT lines.category_id, substr(lines.date, 1, 7) AS month,
+		CASE WHEN a.type = 'credit' THEN a.id ELSE 0 END AS card_id, SUM(lines.amount) AS amount
+		FROM `+categoryLinesSQL+` JOIN accounts a ON a.id = lines.account_id
+		WHERE lines.type = 'expense' AND lines.kind <> 'opening_balance' AND lines.transfer_id IS NULL
+		AND lines.category_id IS NOT NULL AND lines.date <= ? AND NOT a.off_budget
+		GROUP BY lines.category_id, month, card_id`, lastDay).Scan(&spending).Error; err != nil {
+		return nil, err
+	}
+
+	type categoryMonth struct {
+		assigned int64
+		cash     int64
+		cards    []monthAmount
+	}
+	byCategory := make(map[uint]map[string]*categoryMonth)
+	at := func(categoryID uint, month string) *categoryMonth {
+		if byCategory[categoryID] == nil {
+			byCategory[categoryID] = make(map[string]*categoryMonth)
+		}
+		if byCategory[categoryID][month] == nil {
+			byCategory[categoryID][month] = &categoryMonth{}
+		}
+		return byCategory[categoryID][month]
+	}
+	for _, a := range allocations {
+		at(a.CategoryID, a.Month).assigned += a.Amount
+	}
+	for _, sp := range spending {
+		cm := at(sp.CategoryID, sp.Month)
+		if sp.CardID == 0 {
+			cm.cash += sp.Amount
+		} else {
+			cm.cards = append(cm.cards, sp)
+		}
+	}
+
+	for _, months := range byCategory {
+		keys := make([]string, 0, len(months))
+		for m := range months {
+			keys = append(keys, m)
+		}
+		sort.Strings(keys)
+		var available int64
+		for _, m := range keys {
+			cm := months[m]
+			available += cm.assigned - cm.cash
+			sort.Slice(cm.cards, func(i, j int) bool { return cm.cards[i].CardID < cm.cards[j].CardID })
+			for _, card := range cm.cards {
+				funded := min(card.Amount, max(available, 0))
+				available -= card.Amount
+				add(activity.funded, card.CardID, m, funded)
+				add(activity.overspent, card.CardID, m, card.Amount-funded)
+			}
+		}
+	}
+
+	var payments []monthAmount
+	if err := s.db.Raw(`SELECT t.account_id AS card_id, substr(t.date, 1, 7) AS month,
+		SUM(CASE WHEN t.type = 'income' THEN t.amount ELSE -t.amount END) AS amount
+		FROM transactions t JOIN accounts a ON a.id = t.account_id
+		WHERE t.transfer_id IS NOT NULL AND t.date <= ? AND a.type = 'credit' AND NOT a.off_budget
+		AND t.transfer_account_id IN `+onBudgetAccountsSQL+`
+		GROUP BY t.account_id, month`, lastDay).Scan(&payments).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		add(activity.payments, p.CardID, p.Month, p.Amount)
+	}
+
+	var balances []struct {
+		AccountID uint
+		Balance   int64
+	}
+	if err := s.db.Raw(`SELECT t.account_id, SUM(CASE WHEN t.type = 'income' THEN t.amount ELSE -t.amount END) AS balance
+		FROM transactions t JOIN accounts a ON a.id = t.account_id
+		WHERE t.date <= ? AND a.type = 'credit' AND NOT a.off_budget
+		GROUP BY t.account_id`, lastDay).Scan(&balances).Error; err != nil {
+		return nil, err
+	}
+	for _, b := range balances {
+		activity.debt[b.AccountID] = -b.Balance
+	}
+	return activity, nil
+}
+
+// ensurePaymentCategory gives a credit account its payment category if it
+// doesn't have one yet.
+func ensurePaymentCategory(tx *gorm.DB, account models.Account) error {
+	if account.Type != "credit" {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Category{}).Where("payment_account_id = ?", account.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	accountID := account.ID
+	return tx.Create(&models.Category{Name: account.Name + " Payment", Colour: PaymentCategoryColour, PaymentAccountID: &accountID}).Error
+}
@@ -2,113 +2,376 @@ package services
 
 import (
 	"budgetting-app/backend/models"
+	"bufio"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
-	"math"
 	"strconv"
 	"strings"
-	"time"
+	"unicode/utf8"
 )
 
 const MaxCSVRows = 10000
 
+// DefaultImportProfile reads the app's own CSV layout: date, description,
+// amount and optional type and balance columns, with ISO dates and dot
+// decimals.
+func DefaultImportProfile() models.ImportProfile {
+	return models.ImportProfile{
+		Name:              "Default",
+		DateColumn:        "date",
+		DescriptionColumn: "description",
+		AmountColumn:      "amount",
+		TypeColumn:        "type",
+		BalanceColumn:     "balance",
+		DateFormat:        "YYYY-MM-DD",
+		Delimiter:         ",",
+		DecimalSeparator:  ".",
+		SignConvention:    "negative_expense",
+	}
+}
+
 // ParseCSV parses a CSV file and returns transactions.
 // Expected columns: date, description, amount, type
 // amount can be a decimal like "12.50" — it will be converted to cents (1250).
 // type should be "income" or "expense". If omitted, it's inferred from the sign of the amount.
 func ParseCSV(reader io.Reader, accountID string) ([]models.Transaction, error) {
+	return ParseCSVWithProfile(reader, accountID, DefaultImportProfile())
+}
+
+// ParseCSVWithProfile parses a CSV file using the column mapping and formats
+// of an import profile. When the profile has paid in/out columns instead of an
+// amount column, whichever is filled in decides the type. Otherwise the type
+// column wins if present, and the amount's sign is read through the profile's
+// sign convention.
+func ParseCSVWithProfile(reader io.Reader, accountID string, profile models.ImportProfile) ([]models.Transaction, error) {
+	rows, err := newCSVRowReader(reader, accountID, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []models.Transaction
+	for {
+		txn, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(transactions) >= MaxCSVRows {
+			return nil, fmt.Errorf("CSV exceeds maximum of %d rows", MaxCSVRows)
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, nil
+}
+
+// ParseCSVLenient is ParseCSVWithProfile for a partial import: rows with a
+// bad amount, date or type are left out and returned as rejected rather than
+// failing the file. Problems with the file as a whole, such as a missing
+// column, are still returned as an error.
+func ParseCSVLenient(reader io.Reader, accountID string, profile models.ImportProfile) ([]models.Transaction, *RejectedRows, error) {
+	rows, err := newCSVRowReader(reader, accountID, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rejected := rows.rejectedRows()
+	var transactions []models.Transaction
+	for {
+		txn, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *ImportRowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return nil, nil, err
+		}
+		if len(transactions)+len(rejected.Errors) >= MaxCSVRows {
+			return nil, nil, fmt.Errorf("CSV exceeds maximum of %d rows", MaxCSVRows)
+		}
+		if rowErr != nil {
+			rejected.Errors = append(rejected.Errors, *rowErr)
+			continue
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, rejected, nil
+}
+
+// ImportRowError describes a CSV row that could not be read as a transaction.
+type ImportRowError struct {
+	Line   int      `json:"line"`            // line number in the file, counting from 1
+	Field  string   `json:"field,omitempty"` // the column at fault, if any
+	Value  string   `json:"value"`           // its raw value
+	Reason string   `json:"reason"`
+	Record []string `json:"record,omitempty"` // the row's raw fields
+}
+
+func (e *ImportRowError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s on line %d", e.Reason, e.Line)
+	}
+	return fmt.Sprintf("%s on line %d: %s", e.Reason, e.Line, e.Value)
+}
+
+// RejectedRows collects the rows a lenient import left out, with enough of
+// the original layout to write them back out as a CSV for fixing.
+type RejectedRows struct {
+	AccountID uint             `json:"account_id"`
+	SkipRows  int              `json:"skip_rows"`
+	Delimiter rune             `json:"delimiter"`
+	Header    []string         `json:"header"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// CSV writes the rejected rows in the layout they came in, so the fixed file
+// can be uploaded again with the same profile. The leading rows the profile
+// skips are written as blank lines, and an import_error column explains each
+// row. Rows too malformed to split into fields are left out.
+func (r *RejectedRows) CSV() (string, error) {
+	var b strings.Builder
+	b.WriteString(strings.Repeat("\n", r.SkipRows))
+	w := csv.NewWriter(&b)
+	if r.Delimiter != 0 {
+		w.Comma = r.Delimiter
+	}
+	if err := w.Write(append(append([]string{}, r.Header...), "import_error")); err != nil {
+		return "", err
+	}
+	for _, e := range r.Errors {
+		if e.Record == nil {
+			continue
+		}
+		row := make([]string, len(r.Header))
+		copy(row, e.Record)
+		if err := w.Write(append(row, e.Reason)); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// csvRowReader reads a CSV file one transaction at a time, so that files too
+// large to hold in memory can be imported by a background job.
+type csvRowReader struct {
+	r         *csv.Reader
+	profile   models.ImportProfile
+	accountID uint
+	layouts   []string
+	header    []string
+
+	dateIdx, descIdx, amountIdx, paidInIdx, paidOutIdx, typeIdx, balanceIdx int
+	amountOK, typeOK, balanceOK                                             bool
+}
+
+// rejectedRows starts an empty list of rejected rows in the file's layout.
+func (cr *csvRowReader) rejectedRows() *RejectedRows {
+	return &RejectedRows{AccountID: cr.accountID, SkipRows: cr.profile.SkipRows, Delimiter: cr.r.Comma, Header: cr.header}
+}
+
+// newCSVRowReader skips the profile's leading rows and reads the header,
+// failing if a mapped column is missing.
+func newCSVRowReader(reader io.Reader, accountID string, profile models.ImportProfile) (*csvRowReader, error) {
 	accID, err := strconv.ParseUint(accountID, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid account_id: %s", accountID)
 	}
 
-	r := csv.NewReader(reader)
+	br := bufio.NewReader(reader)
+	for i := 0; i < profile.SkipRows; i++ {
+		if _, err := br.ReadString('\n'); err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: file has fewer than %d leading rows", profile.SkipRows)
+		}
+	}
+
+	r := csv.NewReader(br)
 	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	if profile.Delimiter != "" {
+		r.Comma, _ = utf8.DecodeRuneInString(profile.Delimiter)
+	}
 
 	header, err := r.Read()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CSV header: %w", err)
 	}
+	r.ReuseRecord = true
 
 	colMap := map[string]int{}
 	for i, col := range header {
+		if i == 0 {
+			col = strings.TrimPrefix(col, "\ufeff")
+		}
 		colMap[strings.ToLower(strings.TrimSpace(col))] = i
 	}
+	column := func(name string) (int, bool) {
+		if name == "" {
+			return -1, false
+		}
+		idx, ok := colMap[strings.ToLower(name)]
+		if !ok {
+			return -1, false
+		}
+		return idx, true
+	}
 
-	dateIdx, ok := colMap["date"]
-	if !ok {
-		return nil, fmt.Errorf("CSV missing required column: date")
+	cr := &csvRowReader{r: r, profile: profile, accountID: uint(accID), layouts: dateLayouts(profile.DateFormat), header: header}
+	var ok bool
+	if cr.dateIdx, ok = column(profile.DateColumn); !ok {
+		return nil, fmt.Errorf("CSV missing required column: %s", profile.DateColumn)
 	}
-	descIdx, ok := colMap["description"]
-	if !ok {
-		return nil, fmt.Errorf("CSV missing required column: description")
+	if cr.descIdx, ok = column(profile.DescriptionColumn); !ok {
+		return nil, fmt.Errorf("CSV missing required column: %s", profile.DescriptionColumn)
 	}
-	amountIdx, ok := colMap["amount"]
-	if !ok {
-		return nil, fmt.Errorf("CSV missing required column: amount")
+	cr.amountIdx, cr.amountOK = column(profile.AmountColumn)
+	var paidInOK, paidOutOK bool
+	cr.paidInIdx, paidInOK = column(profile.PaidInColumn)
+	cr.paidOutIdx, paidOutOK = column(profile.PaidOutColumn)
+	if !cr.amountOK && !paidInOK && !paidOutOK {
+		if profile.AmountColumn != "" {
+			return nil, fmt.Errorf("CSV missing required column: %s", profile.AmountColumn)
+		}
+		return nil, fmt.Errorf("CSV missing required columns: %s, %s", profile.PaidInColumn, profile.PaidOutColumn)
 	}
-	typeIdx, typeOK := colMap["type"]
+	cr.typeIdx, cr.typeOK = column(profile.TypeColumn)
+	cr.balanceIdx, cr.balanceOK = column(profile.BalanceColumn)
+	return cr, nil
+}
 
-	var transactions []models.Transaction
-	lineNum := 1
-	for {
-		lineNum++
-		if lineNum > MaxCSVRows+1 {
-			return nil, fmt.Errorf("CSV exceeds maximum of %d rows", MaxCSVRows)
-		}
-		record, err := r.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("error reading CSV line %d: %w", lineNum, err)
+// Next returns the next row's transaction, or io.EOF after the last row. A
+// row that can't be read is reported as an *ImportRowError, after which
+// reading can continue with the following row.
+func (cr *csvRowReader) Next() (models.Transaction, error) {
+	profile := cr.profile
+	record, err := cr.r.Read()
+	if err == io.EOF {
+		return models.Transaction{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return models.Transaction{}, &ImportRowError{Line: parseErr.StartLine + profile.SkipRows, Reason: "unreadable row: " + parseErr.Err.Error()}
+	}
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("error reading CSV: %w", err)
+	}
+	line, _ := cr.r.FieldPos(0)
+	line += profile.SkipRows
+	rowError := func(col int, reason string) error {
+		return &ImportRowError{
+			Line:   line,
+			Field:  cr.header[col],
+			Value:  field(record, col),
+			Reason: reason,
+			Record: append([]string(nil), record...),
 		}
+	}
 
-		amountStr := strings.TrimSpace(record[amountIdx])
-		amountFloat, err := strconv.ParseFloat(amountStr, 64)
+	var amountCents int64
+	var signedType string
+	if cr.amountOK {
+		amountCents, err = parseAmount(field(record, cr.amountIdx), profile.DecimalSeparator)
 		if err != nil {
-			return nil, fmt.Errorf("invalid amount on line %d: %s", lineNum, amountStr)
+			return models.Transaction{}, rowError(cr.amountIdx, "invalid amount")
 		}
-		amountCents := int64(math.Round(amountFloat * 100))
-
-		// Validate date format
-		dateStr := strings.TrimSpace(record[dateIdx])
-		if _, err := time.Parse("2006-01-02", dateStr); err != nil {
-			return nil, fmt.Errorf("invalid date on line %d: %s (expected YYYY-MM-DD)", lineNum, dateStr)
+		isIncome := amountCents > 0
+		if profile.SignConvention == "positive_expense" {
+			isIncome = amountCents < 0
 		}
-
-		// Validate and cap description
-		desc := strings.TrimSpace(record[descIdx])
-		if len(desc) > 500 {
-			desc = desc[:500]
+		if marker := creditDebitMarker(field(record, cr.amountIdx)); marker != "" {
+			isIncome = marker == "CR"
 		}
-
-		// Determine type
-		txnType := "expense"
-		if typeOK && typeIdx < len(record) {
-			t := strings.ToLower(strings.TrimSpace(record[typeIdx]))
-			if t != "income" && t != "expense" {
-				return nil, fmt.Errorf("invalid type on line %d: %s (must be income or expense)", lineNum, t)
+		signedType = "expense"
+		if isIncome {
+			signedType = "income"
+		}
+	} else {
+		switch {
+		case field(record, cr.paidInIdx) != "":
+			amountCents, err = parseAmount(field(record, cr.paidInIdx), profile.DecimalSeparator)
+			signedType = "income"
+			if err != nil {
+				return models.Transaction{}, rowError(cr.paidInIdx, "invalid amount")
+			}
+		case field(record, cr.paidOutIdx) != "":
+			amountCents, err = parseAmount(field(record, cr.paidOutIdx), profile.DecimalSeparator)
+			signedType = "expense"
+			if err != nil {
+				return models.Transaction{}, rowError(cr.paidOutIdx, "invalid amount")
 			}
-			txnType = t
-		} else if amountCents > 0 {
-			txnType = "income"
+		default:
+			// A profile may name only one of the two, or one the file lacks
+			col := cr.paidInIdx
+			if col < 0 {
+				col = cr.paidOutIdx
+			}
+			return models.Transaction{}, rowError(col, fmt.Sprintf("invalid amount: both %s and %s are empty", profile.PaidInColumn, profile.PaidOutColumn))
 		}
+	}
+
+	// Validate date format
+	date, err := parseDate(field(record, cr.dateIdx), cr.layouts)
+	if err != nil {
+		return models.Transaction{}, rowError(cr.dateIdx, fmt.Sprintf("invalid date (expected %s)", profile.DateFormat))
+	}
 
-		if amountCents < 0 {
-			amountCents = -amountCents
+	// Validate and cap description
+	desc := field(record, cr.descIdx)
+	if len(desc) > 500 {
+		desc = desc[:500]
+	}
+
+	// Determine type
+	txnType := signedType
+	if cr.typeOK && cr.typeIdx < len(record) {
+		t := strings.ToLower(field(record, cr.typeIdx))
+		if t != "income" && t != "expense" {
+			return models.Transaction{}, rowError(cr.typeIdx, "invalid type (must be income or expense)")
 		}
+		txnType = t
+	}
 
-		txn := models.Transaction{
-			AccountID:   uint(accID),
-			Amount:      amountCents,
-			Description: desc,
-			Date:        dateStr,
-			Type:        txnType,
+	// The bank's running balance, when given, is read with the same sign
+	// convention as amounts
+	var balance *int64
+	if raw := field(record, cr.balanceIdx); cr.balanceOK && raw != "" {
+		b, err := parseAmount(raw, profile.DecimalSeparator)
+		if err != nil {
+			return models.Transaction{}, rowError(cr.balanceIdx, "invalid balance")
 		}
-		transactions = append(transactions, txn)
+		if profile.SignConvention == "positive_expense" {
+			b = -b
+		}
+		switch creditDebitMarker(raw) {
+		case "CR":
+			b = abs(b)
+		case "DR":
+			b = -abs(b)
+		}
+		balance = &b
 	}
 
-	return transactions, nil
+	if amountCents < 0 {
+		amountCents = -amountCents
+	}
+
+	return models.Transaction{
+		AccountID:        cr.accountID,
+		Amount:           amountCents,
+		Description:      desc,
+		Date:             date.Format("2006-01-02"),
+		Type:             txnType,
+		StatementBalance: balance,
+	}, nil
+}
+
+// field returns the trimmed value at idx, or "" if the row is too short.
+func field(record []string, idx int) string {
+	if idx < 0 || idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
 }
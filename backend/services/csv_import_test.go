@@ -1,6 +1,7 @@
 package services
 
 import (
+	"budgetting-app/backend/models"
 	"strings"
 	"testing"
 )
@@ -122,3 +123,133 @@ func TestParseCSV_DescriptionCap(t *testing.T) {
 		t.Errorf("expected description capped at 500 chars, got %d", len(txns[0].Description))
 	}
 }
+
+func TestParseCSVWithProfile_Formats(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile models.ImportProfile
+		csv     string
+		amount  int64
+		typ     string
+		date    string
+	}{
+		{
+			name:    "UK dates",
+			profile: models.ImportProfile{DateColumn: "Date", DescriptionColumn: "Details", AmountColumn: "Amount", DateFormat: "DD/MM/YYYY", Delimiter: ",", DecimalSeparator: ".", SignConvention: "negative_expense"},
+			csv:     "Date,Details,Amount\n15/01/2024,Tesco,-12.34\n",
+			amount:  1234, typ: "expense", date: "2024-01-15",
+		},
+		{
+			name:    "paid in and out columns",
+			profile: models.ImportProfile{DateColumn: "Date", DescriptionColumn: "Memo", PaidInColumn: "Paid in", PaidOutColumn: "Paid out", DateFormat: "YYYY-MM-DD", Delimiter: ",", DecimalSeparator: ".", SignConvention: "negative_expense"},
+			csv:     "Date,Memo,Paid out,Paid in\n2024-01-16,Salary,,2500.00\n",
+			amount:  250000, typ: "income", date: "2024-01-16",
+		},
+		{
+			name:    "paid out column only",
+			profile: models.ImportProfile{DateColumn: "date", DescriptionColumn: "description", PaidInColumn: "Paid In", PaidOutColumn: "Paid Out", DateFormat: "YYYY-MM-DD", Delimiter: ",", DecimalSeparator: ".", SignConvention: "negative_expense"},
+			csv:     "amount_x,date,description,Paid Out\n99,2024-01-05,Coffee,3.50\n",
+			amount:  350, typ: "expense", date: "2024-01-05",
+		},
+		{
+			name:    "paid out column first",
+			profile: models.ImportProfile{DateColumn: "date", DescriptionColumn: "description", PaidOutColumn: "Paid Out", DateFormat: "YYYY-MM-DD", Delimiter: ",", DecimalSeparator: ".", SignConvention: "negative_expense"},
+			csv:     "Paid Out,date,description\n3.50,2024-01-05,Coffee\n",
+			amount:  350, typ: "expense", date: "2024-01-05",
+		},
+		{
+			name:    "semicolons and decimal comma",
+			profile: models.ImportProfile{DateColumn: "Datum", DescriptionColumn: "Omschrijving", AmountColumn: "Bedrag", DateFormat: "DD-MM-YYYY", Delimiter: ";", DecimalSeparator: ",", SignConvention: "negative_expense"},
+			csv:     "Datum;Omschrijving;Bedrag\n17-01-2024;Albert Heijn;-1.234,56\n",
+			amount:  123456, typ: "expense", date: "2024-01-17",
+		},
+		{
+			name:    "leading rows skipped",
+			profile: models.ImportProfile{DateColumn: "date", DescriptionColumn: "description", AmountColumn: "amount", DateFormat: "YYYY-MM-DD", Delimiter: ",", DecimalSeparator: ".", SignConvention: "negative_expense", SkipRows: 2},
+			csv:     "Account: 12345678\nExported 2024-02-01\ndate,description,amount\n2024-01-18,Refund,5.00\n",
+			amount:  500, typ: "income", date: "2024-01-18",
+		},
+		{
+			name:    "credit card positive expenses",
+			profile: models.ImportProfile{DateColumn: "date", DescriptionColumn: "description", AmountColumn: "amount", DateFormat: "MM/DD/YYYY", Delimiter: ",", DecimalSeparator: ".", SignConvention: "positive_expense"},
+			csv:     "date,description,amount\n01/19/2024,Amazon,42.00\n",
+			amount:  4200, typ: "expense", date: "2024-01-19",
+		},
+		{
+			name:    "credit card CR marker",
+			profile: models.ImportProfile{DateColumn: "date", DescriptionColumn: "description", AmountColumn: "amount", DateFormat: "D MMM YYYY", Delimiter: ",", DecimalSeparator: ".", SignConvention: "positive_expense"},
+			csv:     "date,description,amount\n20 Jan 2024,Payment received,\"£1,500.00 CR\"\n",
+			amount:  150000, typ: "income", date: "2024-01-20",
+		},
+		{
+			name:    "accounting parentheses",
+			profile: models.ImportProfile{DateColumn: "date", DescriptionColumn: "description", AmountColumn: "amount", DateFormat: "DD.MM.YYYY", Delimiter: ";", DecimalSeparator: ",", SignConvention: "negative_expense"},
+			csv:     "date;description;amount\n21.01.2024;Miete;(1.200,00 €)\n",
+			amount:  120000, typ: "expense", date: "2024-01-21",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txns, err := ParseCSVWithProfile(strings.NewReader(tt.csv), "1", tt.profile)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(txns) != 1 {
+				t.Fatalf("expected 1 transaction, got %d", len(txns))
+			}
+			if txns[0].Amount != tt.amount || txns[0].Type != tt.typ || txns[0].Date != tt.date {
+				t.Errorf("expected %d %s on %s, got %d %s on %s", tt.amount, tt.typ, tt.date, txns[0].Amount, txns[0].Type, txns[0].Date)
+			}
+		})
+	}
+}
+
+func TestParseCSVWithProfile_MissingMappedColumn(t *testing.T) {
+	profile := DefaultImportProfile()
+	profile.DateColumn = "Posted"
+
+	_, err := ParseCSVWithProfile(strings.NewReader("date,description,amount\n2024-01-15,Test,1.00\n"), "1", profile)
+	if err == nil || !strings.Contains(err.Error(), "Posted") {
+		t.Errorf("expected missing column error naming Posted, got %v", err)
+	}
+}
+
+func TestParseCSVLenient_RejectsBadRows(t *testing.T) {
+	profile := DefaultImportProfile()
+	profile.SkipRows = 1
+	csv := "Exported 2024-02-01\ndate,description,amount\n2024-01-15,Coffee,-3.50\n2024-01-16,Typo,abc\n2024-13-01,Bad date,-1.00\n2024-01-17,Salary,3000.00\n"
+
+	txns, rejected, err := ParseCSVLenient(strings.NewReader(csv), "1", profile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(txns) != 2 {
+		t.Fatalf("expected 2 good rows, got %d", len(txns))
+	}
+	if len(rejected.Errors) != 2 {
+		t.Fatalf("expected 2 rejected rows, got %+v", rejected.Errors)
+	}
+	first := rejected.Errors[0]
+	if first.Line != 4 || first.Field != "amount" || first.Value != "abc" || first.Reason != "invalid amount" {
+		t.Errorf("unexpected error for bad amount: %+v", first)
+	}
+	if second := rejected.Errors[1]; second.Line != 5 || second.Field != "date" || second.Value != "2024-13-01" {
+		t.Errorf("unexpected error for bad date: %+v", second)
+	}
+
+	out, err := rejected.CSV()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "\ndate,description,amount,import_error\n2024-01-16,Typo,abc,invalid amount\n2024-13-01,Bad date,-1.00,invalid date (expected YYYY-MM-DD)\n"
+	if out != want {
+		t.Errorf("unexpected rejected CSV:\n%q\nwant:\n%q", out, want)
+	}
+}
+
+func TestParseCSVLenient_MissingColumnFails(t *testing.T) {
+	_, _, err := ParseCSVLenient(strings.NewReader("date,amount\n2024-01-15,1.00\n"), "1", DefaultImportProfile())
+	if err == nil {
+		t.Error("expected a missing column to fail the whole file")
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// fingerprint identifies a transaction for duplicate detection, ignoring the
+// date, which is matched separately within a window.
+type fingerprint struct {
+	AccountID   uint
+	Amount      int64
+	Type        string
+	Description string
+}
+
+func fingerprintOf(txn models.Transaction) fingerprint {
+	return fingerprint{
+		AccountID:   txn.AccountID,
+		Amount:      txn.Amount,
+		Type:        txn.Type,
+		Description: normalizeDescription(txn.Description),
+	}
+}
+
+// normalizeDescription lowercases a description and reduces it to words
+// separated by single spaces, so punctuation and spacing differences between
+// bank exports don't defeat matching.
+func normalizeDescription(desc string) string {
+	fields := strings.FieldsFunc(strings.ToLower(desc), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+type existingRow struct {
+	id          uint
+	date        string
+	hasExternal bool
+}
+
+// findExternalDuplicates returns, for each incoming transaction that carries
+// a bank ID, the ID of an existing transaction in the same account with the
+// same bank ID, or nil. Unlike findDuplicates this match is exact.
+func findExternalDuplicates(db *gorm.DB, txns []models.Transaction) ([]*uint, error) {
+	result := make([]*uint, len(txns))
+	byAccount := map[uint][]string{}
+	for _, t := range txns {
+		if t.ExternalID != nil {
+			byAccount[t.AccountID] = append(byAccount[t.AccountID], *t.ExternalID)
+		}
+	}
+	type key struct {
+		accountID  uint
+		externalID string
+	}
+	existing := map[key]uint{}
+	for accountID, externalIDs := range byAccount {
+		var rows []models.Transaction
+		if err := db.Select("id", "external_id").
+			Where("account_id = ? AND external_id IN ?", accountID, externalIDs).
+			Order("id").Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			k := key{accountID, *r.ExternalID}
+			if _, ok := existing[k]; !ok {
+				existing[k] = r.ID
+			}
+		}
+	}
+	for i, t := range txns {
+		if t.ExternalID == nil {
+			continue
+		}
+		if id, ok := existing[key{t.AccountID, *t.ExternalID}]; ok {
+			result[i] = &id
+		}
+	}
+	return result, nil
+}
+
+// findDuplicates returns, for each incoming transaction, the ID of an existing
+// transaction it likely duplicates, or nil. Existing rows match when their
+// fingerprint is equal and their date is within windowDays either side. Each
+// existing row is matched at most once, so genuinely repeated purchases in a
+// file are only skipped as far as they were already recorded. Two rows that
+// both carry a bank ID are never matched here: if the IDs were equal
+// findExternalDuplicates would have matched them, so they are distinct.
+func findDuplicates(db *gorm.DB, txns []models.Transaction, windowDays int) ([]*uint, error) {
+	result := make([]*uint, len(txns))
+	if len(txns) == 0 {
+		return result, nil
+	}
+
+	accountIDs := map[uint]bool{}
+	minDate, maxDate := txns[0].Date, txns[0].Date
+	for _, t := range txns {
+		accountIDs[t.AccountID] = true
+		if t.Date < minDate {
+			minDate = t.Date
+		}
+		if t.Date > maxDate {
+			maxDate = t.Date
+		}
+	}
+	ids := make([]uint, 0, len(accountIDs))
+	for id := range accountIDs {
+		ids = append(ids, id)
+	}
+
+	var existing []models.Transaction
+	if err := db.Select("id", "account_id", "amount", "type", "description", "date", "external_id").
+		Where("account_id IN ? AND date >= ? AND date <= ?", ids, addDays(minDate, -windowDays), addDays(maxDate, windowDays)).
+		Order("date, id").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	candidates := map[fingerprint][]existingRow{}
+	for _, e := range existing {
+		fp := fingerprintOf(e)
+		candidates[fp] = append(candidates[fp], existingRow{id: e.ID, date: e.Date, hasExternal: e.ExternalID != nil})
+	}
+
+	for i, t := range txns {
+		fp := fingerprintOf(t)
+		rows := candidates[fp]
+		best := -1
+		bestGap := windowDays + 1
+		for j, row := range rows {
+			if t.ExternalID != nil && row.hasExternal {
+				continue
+			}
+			if gap := daysApart(t.Date, row.date); gap < bestGap {
+				best, bestGap = j, gap
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		id := rows[best].id
+		result[i] = &id
+		candidates[fp] = append(rows[:best:best], rows[best+1:]...)
+	}
+	return result, nil
+}
+
+func daysApart(a, b string) int {
+	ta, _ := time.Parse("2006-01-02", a)
+	tb, _ := time.Parse("2006-01-02", b)
+	d := int(ta.Sub(tb).Hours() / 24)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -4,3 +4,22 @@ import "errors"
 
 var ErrAccountHasTransactions = errors.New("cannot delete account with transactions")
 var ErrCategoryHasTransactions = errors.New("cannot delete category that is referenced by transactions, budget allocations, or targets")
+var ErrTransferSameAccount = errors.New("cannot transfer to the same account")
+var ErrTransferTypeChange = errors.New("cannot change the type of a transfer")
+var ErrTransferCategory = errors.New("transfers cannot be categorized")
+var ErrSplitSumMismatch = errors.New("split amounts must sum to the transaction amount")
+var ErrPreviewNotFound = errors.New("import preview not found or expired")
+var ErrBatchEdited = errors.New("import batch has edited transactions")
+var ErrImportTooLarge = errors.New("import file is too large")
+var ErrTransactionReconciled = errors.New("reconciled transactions must be unlocked before they can be changed")
+var ErrReconcileMismatch = errors.New("cleared balance does not match the statement balance")
+var ErrRangeTooLarge = errors.New("date range is too large")
+var ErrAccountClosed = errors.New("account is closed")
+var ErrMergeIntoSelf = errors.New("cannot merge into itself")
+var ErrPaymentCategory = errors.New("credit card payment categories belong to their account")
+var ErrMoveToSelf = errors.New("cannot move money to the same category")
+var ErrRuleReference = errors.New("rule refers to a category or account that does not exist")
+var ErrMergeBudgetMismatch = errors.New("cannot merge a tracking account with an on-budget account")
+var ErrInsufficientFunds = errors.New("not enough money to move from the source")
+var ErrSplitCategory = errors.New("split refers to a category that does not exist")
+var ErrRecurringReference = errors.New("recurring transaction refers to an account or category that does not exist")
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+type ImportBatchService struct {
+	db *gorm.DB
+}
+
+func NewImportBatchService(db *gorm.DB) *ImportBatchService {
+	return &ImportBatchService{db: db}
+}
+
+func (s *ImportBatchService) List() ([]models.ImportBatch, error) {
+	var batches []models.ImportBatch
+	err := s.db.Preload("Account").Order("created_at DESC, id DESC").Find(&batches).Error
+	return batches, err
+}
+
+// Rejected returns a batch with the rows its lenient import left out, as CSV
+// in RejectedCSV. It returns gorm.ErrRecordNotFound if the batch doesn't
+// exist or rejected no rows.
+func (s *ImportBatchService) Rejected(id uint) (models.ImportBatch, error) {
+	var batch models.ImportBatch
+	err := s.db.Where("rejected > 0").First(&batch, id).Error
+	return batch, err
+}
+
+// Rollback deletes every transaction an import created, the batch itself and
+// any reconciliation checkpoint taken from it, in one database transaction.
+// Rows edited by hand since the import would lose those edits, so unless
+// force is set the rollback is refused with ErrBatchEdited when any exist. Reconciled
+// rows are never deleted; the rollback fails with ErrTransactionReconciled
+// until they are unlocked. It returns the number of transactions deleted,
+// which can be lower than the batch's row count if some were already deleted
+// by hand.
+func (s *ImportBatchService) Rollback(id uint, force bool) (int64, error) {
+	var batch models.ImportBatch
+	if err := s.db.First(&batch, id).Error; err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var reconciled int64
+		if err := tx.Model(&models.Transaction{}).
+			Where("import_batch_id = ? AND status = ?", batch.ID, "reconciled").
+			Count(&reconciled).Error; err != nil {
+			return err
+		}
+		if reconciled > 0 {
+			return ErrTransactionReconciled
+		}
+		if !force {
+			var edited int64
+			if err := tx.Model(&models.Transaction{}).
+				Where("import_batch_id = ? AND edited_at IS NOT NULL", batch.ID).
+				Count(&edited).Error; err != nil {
+				return err
+			}
+			if edited > 0 {
+				return ErrBatchEdited
+			}
+		}
+
+		batchRows := tx.Model(&models.Transaction{}).Select("id").Where("import_batch_id = ?", batch.ID)
+		// Rows flagged against this batch by a later import are no longer duplicates
+		if err := tx.Model(&models.Transaction{}).Where("duplicate_of_id IN (?)", batchRows).
+			UpdateColumn("duplicate_of_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("transaction_id IN (?)", batchRows).Delete(&models.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("import_batch_id = ?", batch.ID).Delete(&models.Transaction{})
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = res.RowsAffected
+		if err := tx.Where("import_batch_id = ?", batch.ID).Delete(&models.ReconciliationCheckpoint{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&batch).Error
+	})
+	return deleted, err
+}
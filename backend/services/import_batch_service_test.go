@@ -0,0 +1,149 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/testutil"
+	"errors"
+	"testing"
+)
+
+func setupImportBatchTest(t *testing.T) (*ImportBatchService, *TransactionService, *models.Account) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	return NewImportBatchService(db), NewTransactionService(db), &account
+}
+
+func importRows(t *testing.T, svc *TransactionService, accountID uint, opts ImportOptions) *ImportResult {
+	t.Helper()
+	result, err := svc.ImportCSV([]models.Transaction{
+		{AccountID: accountID, Amount: 350, Description: "Coffee", Date: "2024-01-03", Type: "expense"},
+		{AccountID: accountID, Amount: 1200, Description: "Cinema", Date: "2024-01-04", Type: "expense"},
+	}, opts)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	return result
+}
+
+func TestImportBatchService_RecordsBatch(t *testing.T) {
+	batches, txns, account := setupImportBatchTest(t)
+
+	result := importRows(t, txns, account.ID, ImportOptions{Duplicates: "skip", Filename: "january.csv"})
+	if result.BatchID == nil {
+		t.Fatal("expected import to record a batch")
+	}
+
+	list, err := batches.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].Filename != "january.csv" || list[0].RowCount != 2 || list[0].AccountID != account.ID {
+		t.Errorf("unexpected batches: %+v", list)
+	}
+
+	// A re-import that skips everything creates no batch
+	result = importRows(t, txns, account.ID, ImportOptions{Duplicates: "skip"})
+	if result.BatchID != nil {
+		t.Errorf("expected no batch for an empty import, got %d", *result.BatchID)
+	}
+}
+
+func TestImportBatchService_Rollback(t *testing.T) {
+	batches, txns, account := setupImportBatchTest(t)
+
+	first := importRows(t, txns, account.ID, ImportOptions{Duplicates: "skip"})
+	// A second import flags its rows against the first batch
+	second := importRows(t, txns, account.ID, ImportOptions{Duplicates: "flag"})
+
+	deleted, err := batches.Rollback(*first.BatchID, false)
+	if err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 deleted, got %d", deleted)
+	}
+
+	var remaining []models.Transaction
+	txns.db.Find(&remaining)
+	if len(remaining) != 2 || remaining[0].ImportBatchID == nil || *remaining[0].ImportBatchID != *second.BatchID {
+		t.Fatalf("expected only the second batch to remain, got %+v", remaining)
+	}
+	if remaining[0].DuplicateOfID != nil {
+		t.Error("expected duplicate_of_id pointing at rolled back rows to be cleared")
+	}
+	if _, err := batches.Rollback(*first.BatchID, false); err == nil {
+		t.Error("expected rolled back batch to be gone")
+	}
+}
+
+func TestImportBatchService_RollbackAfterEarlierBatch(t *testing.T) {
+	batches, txns, account := setupImportBatchTest(t)
+
+	first := importRows(t, txns, account.ID, ImportOptions{Duplicates: "skip"})
+	second := importRows(t, txns, account.ID, ImportOptions{Duplicates: "flag"})
+
+	if _, err := batches.Rollback(*first.BatchID, false); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	// Clearing the duplicate flags on the second batch is not an edit
+	deleted, err := batches.Rollback(*second.BatchID, false)
+	if err != nil || deleted != 2 {
+		t.Errorf("expected the second batch to roll back, got %d (err %v)", deleted, err)
+	}
+}
+
+func TestImportBatchService_RollbackEdited(t *testing.T) {
+	batches, txns, account := setupImportBatchTest(t)
+
+	result := importRows(t, txns, account.ID, ImportOptions{Duplicates: "skip"})
+	desc := "Coffee with Sam"
+	if _, err := txns.Update(*result.Rows[0].TransactionID, UpdateTransactionInput{Description: &desc}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	if _, err := batches.Rollback(*result.BatchID, false); !errors.Is(err, ErrBatchEdited) {
+		t.Fatalf("expected ErrBatchEdited, got %v", err)
+	}
+	deleted, err := batches.Rollback(*result.BatchID, true)
+	if err != nil || deleted != 2 {
+		t.Errorf("expected forced rollback to delete 2, got %d (err %v)", deleted, err)
+	}
+}
+
+func TestImportBatchService_RecordsRejectedRows(t *testing.T) {
+	batches, txns, account := setupImportBatchTest(t)
+
+	rejected := &RejectedRows{
+		AccountID: account.ID,
+		Header:    []string{"date", "description", "amount"},
+		Errors: []ImportRowError{
+			{Line: 3, Field: "amount", Value: "abc", Reason: "invalid amount", Record: []string{"2024-01-05", "Typo", "abc"}},
+		},
+	}
+	result := importRows(t, txns, account.ID, ImportOptions{Duplicates: "skip", Rejected: rejected})
+	if result.Imported != 2 || result.Rejected != 1 || len(result.Errors) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	batch, err := batches.Rejected(*result.BatchID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if batch.Rejected != 1 || batch.RejectedCSV != "date,description,amount,import_error\n2024-01-05,Typo,abc,invalid amount\n" {
+		t.Errorf("unexpected rejected rows: %d %q", batch.Rejected, batch.RejectedCSV)
+	}
+
+	// An import where every row is rejected still records a batch to download
+	result, err = txns.ImportCSV(nil, ImportOptions{Duplicates: "skip", Rejected: rejected})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.BatchID == nil {
+		t.Fatal("expected a batch for the rejected rows")
+	}
+	if _, err := batches.Rejected(*result.BatchID); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
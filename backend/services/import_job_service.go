@@ -0,0 +1,425 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxImportJobBytes caps the size of a file uploaded for a background import.
+const MaxImportJobBytes = 512 << 20
+
+// ImportJobChunkSize is how many rows a background import commits at a time.
+const ImportJobChunkSize = 500
+
+type ImportJobService struct {
+	db        *gorm.DB
+	dir       string
+	chunkSize int
+}
+
+func NewImportJobService(db *gorm.DB, dir string) *ImportJobService {
+	return &ImportJobService{db: db, dir: dir, chunkSize: ImportJobChunkSize}
+}
+
+type ImportJobInput struct {
+	AccountID           uint
+	ProfileID           *uint
+	Filename            string
+	Format              string // csv | ofx | qif; detected from the file when empty
+	Duplicates          string
+	DuplicateWindowDays int
+	DateOrder           string
+	CreateCategories    bool
+	Mode                string // strict | lenient; OFX and QIF files are always read strictly
+}
+
+func (s *ImportJobService) List() ([]models.ImportJob, error) {
+	var jobs []models.ImportJob
+	err := s.db.Order("created_at DESC, id DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+func (s *ImportJobService) Get(id uint) (models.ImportJob, error) {
+	var job models.ImportJob
+	err := s.db.First(&job, id).Error
+	return job, err
+}
+
+// StageUpload streams an uploaded file to the import directory and returns
+// its path, without holding it in memory. Files over MaxImportJobBytes are
+// removed again and rejected with ErrImportTooLarge.
+func (s *ImportJobService) StageUpload(r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return "", err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.dir, token+".upload")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, MaxImportJobBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > MaxImportJobBytes {
+		err = ErrImportTooLarge
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// DiscardUpload removes a staged upload that won't become a job.
+func (s *ImportJobService) DiscardUpload(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("Failed to remove import upload", "path", path, "error", err)
+	}
+}
+
+// Create queues a job for a staged upload. The CSV profile is resolved now,
+// so later edits to it don't change a job that is already running. It
+// returns gorm.ErrRecordNotFound if the account or profile doesn't exist, and
+// ErrAccountClosed if the account is closed.
+func (s *ImportJobService) Create(path string, input ImportJobInput) (models.ImportJob, error) {
+	job := models.ImportJob{
+		AccountID:           input.AccountID,
+		Filename:            input.Filename,
+		Format:              input.Format,
+		Path:                path,
+		Duplicates:          input.Duplicates,
+		DuplicateWindowDays: input.DuplicateWindowDays,
+		DateOrder:           input.DateOrder,
+		CreateCategories:    input.CreateCategories,
+		Mode:                input.Mode,
+		Status:              "queued",
+	}
+	if job.Duplicates == "" {
+		job.Duplicates = "skip"
+	}
+	if job.Mode == "" {
+		job.Mode = "strict"
+	}
+	if job.Format == "" {
+		format, err := detectFileFormat(path, input.Filename)
+		if err != nil {
+			return job, err
+		}
+		job.Format = format
+	}
+
+	profile, err := resolveImportProfile(s.db, input.AccountID, input.ProfileID)
+	if err != nil {
+		return job, err
+	}
+	if err := checkAccountsOpen(s.db, input.AccountID); err != nil {
+		return job, err
+	}
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return job, err
+	}
+	job.Profile = string(data)
+
+	err = s.db.Create(&job).Error
+	return job, err
+}
+
+// Start processes a job in the background until it finishes or ctx is
+// cancelled. A cancelled job stays running and is picked up by Resume.
+func (s *ImportJobService) Start(ctx context.Context, id uint) {
+	go func() {
+		if err := s.run(ctx, id); err != nil {
+			slog.Error("Import job failed", "job", id, "error", err)
+		}
+	}()
+}
+
+// Resume restarts every job left queued or running by a previous process.
+// Jobs whose upload has gone missing are failed instead.
+func (s *ImportJobService) Resume(ctx context.Context) error {
+	var jobs []models.ImportJob
+	if err := s.db.Where("status IN ?", []string{"queued", "running"}).Order("id").Find(&jobs).Error; err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if _, err := os.Stat(job.Path); err != nil {
+			if err := s.fail(&job, "the uploaded file was lost before the import finished"); err != nil {
+				return err
+			}
+			continue
+		}
+		slog.Info("Resuming import job", "job", job.ID, "rows_processed", job.RowsProcessed)
+		s.Start(ctx, job.ID)
+	}
+	return nil
+}
+
+// run imports a job's rows in chunks, skipping any already committed. Each
+// chunk is imported, and the job's progress advanced, in one database
+// transaction. A file that can't be parsed fails the job; the chunks before
+// it stay imported and can be undone by rolling back the job's batch. In
+// lenient mode, CSV rows that can't be read are left out and recorded on the
+// batch instead. The statement balance check needs the whole file, so it runs
+// once the last chunk is in, over every row read.
+func (s *ImportJobService) run(ctx context.Context, id uint) error {
+	var job models.ImportJob
+	if err := s.db.First(&job, id).Error; err != nil {
+		return err
+	}
+	if err := s.db.Model(&job).Update("status", "running").Error; err != nil {
+		return err
+	}
+
+	f, err := os.Open(job.Path)
+	if err != nil {
+		return s.fail(&job, "the uploaded file could not be opened")
+	}
+	defer f.Close()
+
+	source, err := s.openSource(job, f)
+	if err != nil {
+		return s.fail(&job, err.Error())
+	}
+	var rejected *RejectedRows
+	var statement *statementSource
+	if reader, ok := source.(*csvRowReader); ok {
+		if job.Mode == "lenient" {
+			rejected = reader.rejectedRows()
+			source = &lenientSource{rowSource: source, rejected: rejected}
+		}
+		if reader.balanceOK {
+			statement = &statementSource{rowSource: source}
+			source = statement
+		}
+	}
+	for i := 0; i < job.RowsProcessed; i++ {
+		if _, err := source.Next(); err != nil {
+			return s.fail(&job, fmt.Sprintf("the file has changed since row %d was imported", i+1))
+		}
+	}
+
+	opts := ImportOptions{Duplicates: job.Duplicates, DuplicateWindowDays: job.DuplicateWindowDays, Filename: job.Filename, BatchID: job.BatchID, Chunked: true}
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		chunk, readErr := readChunk(source, s.chunkSize)
+		if len(chunk) > 0 {
+			if err := s.commitChunk(&job, &opts, chunk); err != nil {
+				return s.fail(&job, "failed to save rows: "+err.Error())
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return s.fail(&job, readErr.Error())
+		}
+	}
+
+	if err := s.finish(&job, rejected, statement); err != nil {
+		return s.fail(&job, "failed to finish the import: "+err.Error())
+	}
+	s.DiscardUpload(job.Path)
+	return nil
+}
+
+// finish records the rows a lenient job left out on its batch, starting one
+// if no row was imported, runs the statement balance check and marks the job
+// completed, in one database transaction.
+func (s *ImportJobService) finish(job *models.ImportJob, rejected *RejectedRows, statement *statementSource) error {
+	now := time.Now()
+	updates := map[string]interface{}{"status": "completed", "finished_at": &now}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if rejected != nil && len(rejected.Errors) > 0 {
+			if job.BatchID == nil {
+				batchID, err := recordImportBatch(tx, nil, job.Filename, rejected, nil)
+				if err != nil {
+					return err
+				}
+				job.BatchID = batchID
+				updates["batch_id"] = batchID
+			} else {
+				data, err := rejected.CSV()
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&models.ImportBatch{}).Where("id = ?", *job.BatchID).
+					Updates(map[string]interface{}{"rejected": len(rejected.Errors), "rejected_csv": data}).Error; err != nil {
+					return err
+				}
+			}
+			updates["rejected"] = len(rejected.Errors)
+		}
+		if statement != nil && len(statement.rows) > 0 {
+			check, err := checkStatementBalances(tx, job.AccountID, job.BatchID, statement.rows)
+			if err != nil {
+				return err
+			}
+			if check != nil {
+				data, err := json.Marshal(check)
+				if err != nil {
+					return err
+				}
+				updates["balance_check"] = data
+			}
+		}
+		return tx.Model(job).Updates(updates).Error
+	})
+}
+
+func (s *ImportJobService) commitChunk(job *models.ImportJob, opts *ImportOptions, chunk []models.Transaction) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txns := NewTransactionService(tx)
+		if job.Format == "qif" {
+			if err := txns.ResolveCategoryNames(chunk, job.CreateCategories); err != nil {
+				return err
+			}
+		}
+		result, err := txns.ImportCSV(chunk, *opts)
+		if err != nil {
+			return err
+		}
+		if opts.BatchID == nil {
+			opts.BatchID = result.BatchID
+		}
+		job.RowsProcessed += len(chunk)
+		job.Imported += result.Imported
+		job.Skipped += result.Skipped
+		job.Flagged += result.Flagged
+		job.BatchID = opts.BatchID
+		return tx.Model(job).Updates(map[string]interface{}{
+			"rows_processed": job.RowsProcessed,
+			"imported":       job.Imported,
+			"skipped":        job.Skipped,
+			"flagged":        job.Flagged,
+			"batch_id":       job.BatchID,
+		}).Error
+	})
+}
+
+func (s *ImportJobService) fail(job *models.ImportJob, reason string) error {
+	now := time.Now()
+	job.Status, job.Error, job.FinishedAt = "failed", reason, &now
+	if err := s.db.Model(job).Updates(map[string]interface{}{"status": job.Status, "error": job.Error, "finished_at": job.FinishedAt}).Error; err != nil {
+		return err
+	}
+	s.DiscardUpload(job.Path)
+	return nil
+}
+
+// rowSource yields parsed transactions one at a time, returning io.EOF after
+// the last.
+type rowSource interface {
+	Next() (models.Transaction, error)
+}
+
+// sliceSource serves rows from a file that was parsed in one go.
+type sliceSource struct {
+	rows []models.Transaction
+}
+
+func (s *sliceSource) Next() (models.Transaction, error) {
+	if len(s.rows) == 0 {
+		return models.Transaction{}, io.EOF
+	}
+	txn := s.rows[0]
+	s.rows = s.rows[1:]
+	return txn, nil
+}
+
+// lenientSource passes over rows that can't be read, collecting them in
+// rejected.
+type lenientSource struct {
+	rowSource
+	rejected *RejectedRows
+}
+
+func (s *lenientSource) Next() (models.Transaction, error) {
+	for {
+		txn, err := s.rowSource.Next()
+		var rowErr *ImportRowError
+		if !errors.As(err, &rowErr) {
+			return txn, err
+		}
+		s.rejected.Errors = append(s.rejected.Errors, *rowErr)
+	}
+}
+
+// statementSource keeps each row read as the statement balance check sees
+// it, so the check can run over the whole file once it has been imported.
+type statementSource struct {
+	rowSource
+	rows []statementRow
+}
+
+func (s *statementSource) Next() (models.Transaction, error) {
+	txn, err := s.rowSource.Next()
+	if err == nil {
+		s.rows = append(s.rows, statementRow{row: len(s.rows) + 1, date: txn.Date, signed: signedAmount(txn), balance: txn.StatementBalance})
+	}
+	return txn, err
+}
+
+// openSource streams CSV files row by row. OFX and QIF files are parsed
+// whole, without the row cap that applies to requests.
+func (s *ImportJobService) openSource(job models.ImportJob, f io.Reader) (rowSource, error) {
+	accountID := strconv.FormatUint(uint64(job.AccountID), 10)
+	switch job.Format {
+	case "ofx":
+		rows, err := parseOFX(f, accountID, 0)
+		return &sliceSource{rows: rows}, err
+	case "qif":
+		rows, err := parseQIF(f, accountID, job.DateOrder == "dmy", 0)
+		return &sliceSource{rows: rows}, err
+	default:
+		var profile models.ImportProfile
+		if err := json.Unmarshal([]byte(job.Profile), &profile); err != nil {
+			return nil, err
+		}
+		return newCSVRowReader(f, accountID, profile)
+	}
+}
+
+func readChunk(source rowSource, size int) ([]models.Transaction, error) {
+	chunk := make([]models.Transaction, 0, size)
+	for len(chunk) < size {
+		txn, err := source.Next()
+		if err != nil {
+			return chunk, err
+		}
+		chunk = append(chunk, txn)
+	}
+	return chunk, nil
+}
+
+func detectFileFormat(path, filename string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head, err := bufio.NewReader(f).Peek(512)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return DetectImportFormat(filename, head), nil
+}
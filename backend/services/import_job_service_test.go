@@ -0,0 +1,168 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/testutil"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func setupImportJobTest(t *testing.T) (*ImportJobService, *models.Account) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	svc := NewImportJobService(db, t.TempDir())
+	svc.chunkSize = 2
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	return svc, &account
+}
+
+func createJob(t *testing.T, svc *ImportJobService, accountID uint, content string) models.ImportJob {
+	t.Helper()
+	path, err := svc.StageUpload(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to stage upload: %v", err)
+	}
+	job, err := svc.Create(path, ImportJobInput{AccountID: accountID, Filename: "big.csv"})
+	if err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	return job
+}
+
+const jobCSV = `date,description,amount
+2024-01-01,Coffee,-3.50
+2024-01-02,Coffee,-3.50
+2024-01-02,Coffee,-3.50
+2024-01-03,Lunch,-8.00
+2024-01-04,Salary,2000.00
+`
+
+func TestImportJobService_RunsInChunks(t *testing.T) {
+	svc, account := setupImportJobTest(t)
+	job := createJob(t, svc, account.ID, jobCSV)
+	if job.Format != "csv" || job.Status != "queued" {
+		t.Fatalf("unexpected new job: %+v", job)
+	}
+
+	if err := svc.run(context.Background(), job.ID); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	job, _ = svc.Get(job.ID)
+	if job.Status != "completed" || job.RowsProcessed != 5 || job.FinishedAt == nil {
+		t.Fatalf("unexpected finished job: %+v", job)
+	}
+	// The repeated coffee straddles a chunk boundary but is not a duplicate
+	// of the row committed with the previous chunk
+	if job.Imported != 5 || job.Skipped != 0 {
+		t.Errorf("expected all 5 rows imported, got %d imported and %d skipped", job.Imported, job.Skipped)
+	}
+
+	var batch models.ImportBatch
+	if job.BatchID == nil || svc.db.First(&batch, *job.BatchID).Error != nil || batch.RowCount != 5 {
+		t.Errorf("expected one batch holding 5 rows, got %+v", batch)
+	}
+	if _, err := os.Stat(job.Path); !os.IsNotExist(err) {
+		t.Error("expected the upload to be removed once the job finished")
+	}
+}
+
+func TestImportJobService_ResumesAfterProcessedRows(t *testing.T) {
+	svc, account := setupImportJobTest(t)
+	job := createJob(t, svc, account.ID, jobCSV)
+	// As if the first three rows were committed before a restart
+	svc.db.Model(&job).Updates(map[string]interface{}{"status": "running", "rows_processed": 3})
+
+	if err := svc.run(context.Background(), job.ID); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	job, _ = svc.Get(job.ID)
+	if job.Status != "completed" || job.RowsProcessed != 5 || job.Imported != 2 {
+		t.Errorf("expected the remaining 2 rows to be imported, got %+v", job)
+	}
+}
+
+func TestImportJobService_ResumeFailsMissingUpload(t *testing.T) {
+	svc, account := setupImportJobTest(t)
+	job := createJob(t, svc, account.ID, jobCSV)
+	os.Remove(job.Path)
+
+	if err := svc.Resume(context.Background()); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	job, _ = svc.Get(job.ID)
+	if job.Status != "failed" || job.Error == "" {
+		t.Errorf("expected job to be failed, got %+v", job)
+	}
+}
+
+func TestImportJobService_FailsOnBadRow(t *testing.T) {
+	svc, account := setupImportJobTest(t)
+	job := createJob(t, svc, account.ID, "date,description,amount\n2024-01-01,A,-1.00\n2024-01-02,B,-2.00\n2024-01-03,C,-3.00\n2024-01-04,D,oops\n")
+
+	if err := svc.run(context.Background(), job.ID); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	job, _ = svc.Get(job.ID)
+	if job.Status != "failed" || !strings.Contains(job.Error, "line 5") {
+		t.Errorf("expected failure naming line 5, got %+v", job)
+	}
+	if job.Imported != 3 || job.BatchID == nil {
+		t.Errorf("expected rows before the error to stay imported in the batch, got %+v", job)
+	}
+}
+
+func TestImportJobService_Lenient(t *testing.T) {
+	svc, account := setupImportJobTest(t)
+	path, _ := svc.StageUpload(strings.NewReader("date,description,amount\n2024-01-01,A,-1.00\n2024-01-02,B,oops\n2024-01-03,C,-3.00\n2024-01-04,D,-4.00\n"))
+	job, err := svc.Create(path, ImportJobInput{AccountID: account.ID, Filename: "big.csv", Mode: "lenient"})
+	if err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	if err := svc.run(context.Background(), job.ID); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	job, _ = svc.Get(job.ID)
+	if job.Status != "completed" || job.Imported != 3 || job.Rejected != 1 || job.BatchID == nil {
+		t.Fatalf("expected 3 imported and 1 rejected, got %+v", job)
+	}
+	batch, err := NewImportBatchService(svc.db).Rejected(*job.BatchID)
+	if err != nil {
+		t.Fatalf("expected the rejected row on the batch: %v", err)
+	}
+	if batch.RowCount != 3 || !strings.Contains(batch.RejectedCSV, "2024-01-02,B,oops") {
+		t.Errorf("unexpected batch: %d rows, rejected %q", batch.RowCount, batch.RejectedCSV)
+	}
+}
+
+func TestImportJobService_BalanceCheck(t *testing.T) {
+	svc, account := setupImportJobTest(t)
+	// The chunks of two rows split January 2nd; the last balance is off by 1.00
+	job := createJob(t, svc, account.ID, `date,description,amount,balance
+2024-01-01,Coffee,-3.50,96.50
+2024-01-02,Coffee,-3.50,93.00
+2024-01-02,Coffee,-3.50,89.50
+2024-01-03,Lunch,-8.00,81.50
+2024-01-04,Salary,2000.00,2082.50
+`)
+
+	if err := svc.run(context.Background(), job.ID); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	job, _ = svc.Get(job.ID)
+	var check BalanceCheck
+	if job.Status != "completed" || json.Unmarshal(job.BalanceCheck, &check) != nil {
+		t.Fatalf("expected a completed job with a balance check, got %+v", job)
+	}
+	if check.Checked != 5 || check.OpeningBalance != 10000 || check.ClosingBalance != 208250 {
+		t.Errorf("unexpected balance check: %+v", check)
+	}
+	if check.FirstDivergence == nil || check.FirstDivergence.Row != 5 || check.FirstDivergence.Computed != 208150 {
+		t.Errorf("expected the last row to diverge, got %+v", check.FirstDivergence)
+	}
+}
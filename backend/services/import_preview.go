@@ -0,0 +1,405 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PreviewTTL is how long an import preview can be edited and confirmed.
+const PreviewTTL = time.Hour
+
+type ImportPreviewRow struct {
+	Row                 int                       `json:"row"`
+	Date                string                    `json:"date"`
+	Description         string                    `json:"description"`
+	Amount              int64                     `json:"amount"`
+	Type                string                    `json:"type"`
+	CategoryID          *uint                     `json:"category_id"`
+	CategoryName        string                    `json:"category_name,omitempty"` // a category from the file to create on confirm, when category_id is null
+	SuggestedCategoryID *uint                     `json:"suggested_category_id"`
+	DuplicateOfID       *uint                     `json:"duplicate_of_id"`
+	ExternalID          *string                   `json:"external_id"`
+	Balance             *int64                    `json:"balance,omitempty"` // the bank's running balance after the row, if the file has one
+	Splits              []models.TransactionSplit `json:"splits,omitempty"`  // from the file; kept unless a category is chosen
+	Skip                bool                      `json:"skip"`
+	Warnings            []string                  `json:"warnings"`
+}
+
+type ImportPreviewResponse struct {
+	Token     string             `json:"token"`
+	AccountID uint               `json:"account_id"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	Rows      []ImportPreviewRow `json:"rows"`
+	Errors    []ImportRowError   `json:"errors,omitempty"` // rows a lenient parse left out
+}
+
+// PreviewImport runs the same categorization and duplicate checks as
+// ImportCSV without writing any transactions. The rows are stored under a
+// token so they can be edited and then committed unchanged by ConfirmPreview.
+// With the default "skip" duplicates mode, likely duplicates start out skipped.
+func (s *TransactionService) PreviewImport(accountID uint, transactions []models.Transaction, opts ImportOptions) (*ImportPreviewResponse, error) {
+	if err := checkAccountsOpen(s.db, accountID); err != nil {
+		return nil, err
+	}
+	rules, err := loadRules(s.db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range transactions {
+		applyRules(rules, &transactions[i])
+	}
+
+	rows := make([]ImportPreviewRow, len(transactions))
+	for i, t := range transactions {
+		rows[i] = ImportPreviewRow{
+			Row:                 i + 1,
+			Date:                t.Date,
+			Description:         t.Description,
+			Amount:              t.Amount,
+			Type:                t.Type,
+			CategoryID:          t.CategoryID,
+			CategoryName:        pendingCategoryName(t.Category),
+			SuggestedCategoryID: t.CategoryID,
+			ExternalID:          t.ExternalID,
+			Balance:             t.StatementBalance,
+			Splits:              t.Splits,
+		}
+	}
+	if err := s.checkPreviewRows(rows, accountID, opts); err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	preview := models.ImportPreview{Token: token, AccountID: accountID, Filename: opts.Filename, Window: opts.DuplicateWindowDays, ExpiresAt: time.Now().Add(PreviewTTL)}
+	if err := encodePreviewRows(&preview, rows); err != nil {
+		return nil, err
+	}
+	var rowErrors []ImportRowError
+	if opts.Rejected != nil && len(opts.Rejected.Errors) > 0 {
+		rowErrors = opts.Rejected.Errors
+		data, err := json.Marshal(opts.Rejected)
+		if err != nil {
+			return nil, err
+		}
+		preview.Rejected = string(data)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Opportunistically clear out abandoned previews
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.ImportPreview{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&preview).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ImportPreviewResponse{Token: token, AccountID: accountID, ExpiresAt: preview.ExpiresAt, Rows: rows, Errors: rowErrors}, nil
+}
+
+// UpdatePreview replaces the rows of a pending preview with the user's edits
+// and re-runs the warnings. Row numbers and suggestions are kept from the
+// original parse; rows are matched by their row number. An edit naming a
+// category that doesn't exist is rejected.
+func (s *TransactionService) UpdatePreview(token string, edits []ImportPreviewRow) (*ImportPreviewResponse, error) {
+	preview, rows, err := s.loadPreview(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPreviewCategories(s.db, edits); err != nil {
+		return nil, err
+	}
+
+	byRow := make(map[int]int, len(rows))
+	for i, r := range rows {
+		byRow[r.Row] = i
+	}
+	for _, e := range edits {
+		i, ok := byRow[e.Row]
+		if !ok {
+			return nil, fmt.Errorf("preview has no row %d", e.Row)
+		}
+		r := &rows[i]
+		r.Date, r.Description, r.Amount, r.Type = e.Date, e.Description, e.Amount, e.Type
+		r.CategoryID, r.CategoryName = e.CategoryID, ""
+		if e.CategoryID == nil {
+			r.CategoryName = e.CategoryName
+		}
+		r.Skip = e.Skip
+		if len(r.Splits) > 0 {
+			// Choosing a single category collapses the file's split lines
+			if r.CategoryID != nil {
+				r.Splits = nil
+			} else if err := validateSplits(r.Amount, r.Splits); err != nil {
+				return nil, fmt.Errorf("row %d: %w", r.Row, err)
+			}
+		}
+	}
+	// Keep the user's skip choices rather than re-deriving them
+	if err := s.checkPreviewRows(rows, preview.AccountID, ImportOptions{Duplicates: "allow", DuplicateWindowDays: preview.Window}); err != nil {
+		return nil, err
+	}
+
+	if err := encodePreviewRows(&preview, rows); err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&preview).Update("rows", preview.Rows).Error; err != nil {
+		return nil, err
+	}
+	response := &ImportPreviewResponse{Token: token, AccountID: preview.AccountID, ExpiresAt: preview.ExpiresAt, Rows: rows}
+	rejected, err := previewRejected(preview)
+	if err != nil {
+		return nil, err
+	}
+	if rejected != nil {
+		response.Errors = rejected.Errors
+	}
+	return response, nil
+}
+
+// ConfirmPreview commits the stored, non-skipped rows of a preview and
+// discards it. Rows still marked as likely duplicates are imported flagged,
+// while rows that only match transactions added since the preview are
+// skipped. Running balances from the file are checked as in ImportCSV.
+func (s *TransactionService) ConfirmPreview(token string) (*ImportResult, error) {
+	preview, rows, err := s.loadPreview(token)
+	if err != nil {
+		return nil, err
+	}
+	rejected, err := previewRejected(preview)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Rows: make([]ImportRowResult, len(rows))}
+	if rejected != nil {
+		result.Rejected, result.Errors = len(rejected.Errors), rejected.Errors
+	}
+	transactions := make([]models.Transaction, len(rows))
+	var pending []int // rows to import
+	for i, r := range rows {
+		result.Rows[i] = ImportRowResult{Row: r.Row, Status: "imported", DuplicateOfID: r.DuplicateOfID}
+		if r.Skip {
+			result.Rows[i].Status = "skipped"
+			result.Skipped++
+			continue
+		}
+		transactions[i] = models.Transaction{
+			AccountID:     preview.AccountID,
+			CategoryID:    r.CategoryID,
+			Amount:        r.Amount,
+			Description:   r.Description,
+			Date:          r.Date,
+			Type:          r.Type,
+			DuplicateOfID: r.DuplicateOfID,
+			ExternalID:    r.ExternalID,
+			Splits:        r.Splits,
+			Status:        "cleared",
+		}
+		if r.CategoryID == nil && r.CategoryName != "" {
+			transactions[i].Category = &models.Category{Name: r.CategoryName}
+		}
+		pending = append(pending, i)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Deleting first makes a second confirm of the same token a no-op
+		res := tx.Where("token = ?", token).Delete(&models.ImportPreview{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrPreviewNotFound
+		}
+
+		// Rows that match a transaction added since the preview was taken,
+		// such as from an overlapping file, are skipped rather than imported
+		// twice. Matches the user already saw and kept are imported flagged.
+		candidates := make([]models.Transaction, len(pending))
+		for j, i := range pending {
+			candidates[j] = transactions[i]
+		}
+		exact, err := findExternalDuplicates(tx, candidates)
+		if err != nil {
+			return err
+		}
+		duplicates, err := findDuplicates(tx, candidates, preview.Window)
+		if err != nil {
+			return err
+		}
+		var toInsert []*models.Transaction
+		for j, i := range pending {
+			row := &result.Rows[i]
+			if match := exact[j]; match != nil || (duplicates[j] != nil && rows[i].DuplicateOfID == nil) {
+				if match == nil {
+					match = duplicates[j]
+				}
+				row.Status, row.DuplicateOfID = "skipped", match
+				result.Skipped++
+				continue
+			}
+			if rows[i].DuplicateOfID != nil {
+				row.Status = "flagged"
+				result.Flagged++
+			}
+			toInsert = append(toInsert, &transactions[i])
+		}
+
+		if err := createImportedCategories(tx, toInsert); err != nil {
+			return err
+		}
+		if result.BatchID, err = recordImportBatch(tx, nil, preview.Filename, rejected, toInsert); err != nil {
+			return err
+		}
+		if err := createInBatches(tx, toInsert); err != nil {
+			return err
+		}
+		statement := make([]statementRow, len(rows))
+		for i, r := range rows {
+			statement[i] = statementRow{row: r.Row, date: r.Date, signed: signedAmount(models.Transaction{Type: r.Type, Amount: r.Amount}), balance: r.Balance}
+		}
+		if len(statement) > 0 {
+			result.BalanceCheck, err = checkStatementBalances(tx, preview.AccountID, result.BatchID, statement)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Rows {
+		if result.Rows[i].Status == "skipped" {
+			continue
+		}
+		result.Imported++
+		id := transactions[i].ID
+		result.Rows[i].TransactionID = &id
+	}
+	return result, nil
+}
+
+// checkPreviewCategories makes sure every category the edits choose exists.
+func checkPreviewCategories(db *gorm.DB, edits []ImportPreviewRow) error {
+	var ids []uint
+	for _, e := range edits {
+		if e.CategoryID != nil {
+			ids = append(ids, *e.CategoryID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var found []uint
+	if err := db.Model(&models.Category{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+		return err
+	}
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	for _, e := range edits {
+		if e.CategoryID != nil && !exists[*e.CategoryID] {
+			return fmt.Errorf("row %d: category %d does not exist", e.Row, *e.CategoryID)
+		}
+	}
+	return nil
+}
+
+// previewRejected decodes the rows a lenient parse left out of a preview, or
+// returns nil if there were none.
+func previewRejected(preview models.ImportPreview) (*RejectedRows, error) {
+	if preview.Rejected == "" {
+		return nil, nil
+	}
+	var rejected RejectedRows
+	if err := json.Unmarshal([]byte(preview.Rejected), &rejected); err != nil {
+		return nil, err
+	}
+	return &rejected, nil
+}
+
+func (s *TransactionService) loadPreview(token string) (models.ImportPreview, []ImportPreviewRow, error) {
+	var preview models.ImportPreview
+	err := s.db.Where("token = ? AND expires_at > ?", token, time.Now()).First(&preview).Error
+	if err == gorm.ErrRecordNotFound {
+		return preview, nil, ErrPreviewNotFound
+	}
+	if err != nil {
+		return preview, nil, err
+	}
+	var rows []ImportPreviewRow
+	if err := json.Unmarshal([]byte(preview.Rows), &rows); err != nil {
+		return preview, nil, err
+	}
+	return preview, rows, nil
+}
+
+// checkPreviewRows refreshes each row's duplicate match and warnings. Rows
+// whose bank ID is already recorded are always skipped.
+func (s *TransactionService) checkPreviewRows(rows []ImportPreviewRow, accountID uint, opts ImportOptions) error {
+	transactions := make([]models.Transaction, len(rows))
+	for i, r := range rows {
+		transactions[i] = models.Transaction{AccountID: accountID, Amount: r.Amount, Description: r.Description, Date: r.Date, Type: r.Type, ExternalID: r.ExternalID}
+	}
+	exact, err := findExternalDuplicates(s.db, transactions)
+	if err != nil {
+		return err
+	}
+	duplicates, err := findDuplicates(s.db, transactions, opts.DuplicateWindowDays)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for i := range rows {
+		r := &rows[i]
+		r.DuplicateOfID = duplicates[i]
+		r.Warnings = []string{}
+		if exact[i] != nil {
+			// Already imported; this can't be overridden by editing the row
+			r.DuplicateOfID = exact[i]
+			r.Skip = true
+			r.Warnings = append(r.Warnings, fmt.Sprintf("already imported as transaction %d", *exact[i]))
+		} else if r.DuplicateOfID != nil {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("likely duplicate of transaction %d", *r.DuplicateOfID))
+			if opts.Duplicates == "skip" || opts.Duplicates == "" {
+				r.Skip = true
+			}
+		}
+		if r.CategoryID == nil && r.CategoryName == "" && len(r.Splits) == 0 && r.Type == "expense" {
+			r.Warnings = append(r.Warnings, "no category")
+		}
+		if r.Date > today {
+			r.Warnings = append(r.Warnings, "date is in the future")
+		}
+		if r.Amount == 0 {
+			r.Warnings = append(r.Warnings, "amount is zero")
+		}
+	}
+	return nil
+}
+
+// pendingCategoryName returns the name of a category an import will create,
+// or "" if there is none.
+func pendingCategoryName(category *models.Category) string {
+	if category == nil {
+		return ""
+	}
+	return category.Name
+}
+
+func encodePreviewRows(preview *models.ImportPreview, rows []ImportPreviewRow) error {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	preview.Rows = string(data)
+	return nil
+}
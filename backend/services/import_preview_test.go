@@ -0,0 +1,114 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"testing"
+)
+
+func TestImportPreview_DoesNotWrite(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+
+	cat := models.Category{Name: "Food", Colour: "#FF0000"}
+	svc.db.Create(&cat)
+	NewRuleService(svc.db).Create(RuleInput{Name: "Tesco", DescriptionOp: strPtr("contains"), DescriptionValue: strPtr("tesco"), SetCategoryID: &cat.ID})
+	svc.Create(&models.Transaction{AccountID: account.ID, Amount: 350, Description: "Coffee", Date: "2024-01-02", Type: "expense"})
+
+	preview, err := svc.PreviewImport(account.ID, []models.Transaction{
+		{AccountID: account.ID, Amount: 4500, Description: "TESCO STORES", Date: "2024-01-03", Type: "expense"},
+		{AccountID: account.ID, Amount: 350, Description: "Coffee", Date: "2024-01-02", Type: "expense"},
+	}, ImportOptions{})
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if preview.Token == "" {
+		t.Fatal("expected a preview token")
+	}
+	if preview.Rows[0].SuggestedCategoryID == nil || *preview.Rows[0].SuggestedCategoryID != cat.ID {
+		t.Errorf("expected suggested category %d, got %v", cat.ID, preview.Rows[0].SuggestedCategoryID)
+	}
+	if !preview.Rows[1].Skip || preview.Rows[1].DuplicateOfID == nil || len(preview.Rows[1].Warnings) == 0 {
+		t.Errorf("expected row 2 to be skipped as a duplicate with a warning, got %+v", preview.Rows[1])
+	}
+
+	_, total, _ := svc.List(TransactionListParams{})
+	if total != 1 {
+		t.Errorf("expected preview not to write transactions, got %d", total)
+	}
+}
+
+func TestImportPreview_EditAndConfirm(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+
+	preview, err := svc.PreviewImport(account.ID, []models.Transaction{
+		{AccountID: account.ID, Amount: 4500, Description: "TESCO STORES", Date: "2024-01-03", Type: "expense"},
+		{AccountID: account.ID, Amount: 999, Description: "Unwanted", Date: "2024-01-04", Type: "expense"},
+	}, ImportOptions{})
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+
+	edited := preview.Rows
+	edited[0].Description = "Tesco"
+	edited[1].Skip = true
+	if _, err := svc.UpdatePreview(preview.Token, edited); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	result, err := svc.ConfirmPreview(preview.Token)
+	if err != nil {
+		t.Fatalf("confirm failed: %v", err)
+	}
+	if result.Imported != 1 || result.Skipped != 1 {
+		t.Errorf("expected 1 imported and 1 skipped, got %d and %d", result.Imported, result.Skipped)
+	}
+	txns, _, _ := svc.List(TransactionListParams{})
+	if len(txns) != 1 || txns[0].Description != "Tesco" {
+		t.Errorf("expected the edited row to be committed, got %+v", txns)
+	}
+
+	// A token can only be confirmed once
+	if _, err := svc.ConfirmPreview(preview.Token); err != ErrPreviewNotFound {
+		t.Errorf("expected ErrPreviewNotFound on second confirm, got %v", err)
+	}
+}
+
+func TestImportPreview_EditUnknownCategory(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+	preview, _ := svc.PreviewImport(account.ID, []models.Transaction{
+		{AccountID: account.ID, Amount: 4500, Description: "Tesco", Date: "2024-01-03", Type: "expense"},
+	}, ImportOptions{})
+
+	missing := uint(99)
+	edited := preview.Rows
+	edited[0].CategoryID = &missing
+	if _, err := svc.UpdatePreview(preview.Token, edited); err == nil {
+		t.Fatal("expected an error for a category that does not exist")
+	}
+}
+
+func TestImportPreview_OverlappingPreviews(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+	rows := func() []models.Transaction {
+		return []models.Transaction{
+			{AccountID: account.ID, Amount: 4500, Description: "Tesco", Date: "2024-01-03", Type: "expense"},
+			{AccountID: account.ID, Amount: 350, Description: "Coffee", Date: "2024-01-04", Type: "expense"},
+		}
+	}
+	first, _ := svc.PreviewImport(account.ID, rows(), ImportOptions{})
+	second, _ := svc.PreviewImport(account.ID, rows()[1:], ImportOptions{})
+
+	if _, err := svc.ConfirmPreview(first.Token); err != nil {
+		t.Fatalf("confirm failed: %v", err)
+	}
+	result, err := svc.ConfirmPreview(second.Token)
+	if err != nil {
+		t.Fatalf("confirm failed: %v", err)
+	}
+	if result.Imported != 0 || result.Skipped != 1 || result.Rows[0].DuplicateOfID == nil {
+		t.Errorf("expected the overlapping row to be skipped, got %+v", result)
+	}
+	_, total, _ := svc.List(TransactionListParams{})
+	if total != 2 {
+		t.Errorf("expected 2 transactions, got %d", total)
+	}
+}
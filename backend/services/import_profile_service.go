@@ -0,0 +1,113 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+type ImportProfileService struct {
+	db *gorm.DB
+}
+
+func NewImportProfileService(db *gorm.DB) *ImportProfileService {
+	return &ImportProfileService{db: db}
+}
+
+type ImportProfileInput struct {
+	Name              string `json:"name" binding:"required"`
+	DateColumn        string `json:"date_column" binding:"required"`
+	DescriptionColumn string `json:"description_column" binding:"required"`
+	AmountColumn      string `json:"amount_column"`
+	PaidInColumn      string `json:"paid_in_column"`
+	PaidOutColumn     string `json:"paid_out_column"`
+	TypeColumn        string `json:"type_column"`
+	BalanceColumn     string `json:"balance_column"`
+	DateFormat        string `json:"date_format"`
+	Delimiter         string `json:"delimiter"`
+	DecimalSeparator  string `json:"decimal_separator"`
+	SignConvention    string `json:"sign_convention"`
+	SkipRows          int    `json:"skip_rows"`
+}
+
+func (s *ImportProfileService) List() ([]models.ImportProfile, error) {
+	var profiles []models.ImportProfile
+	err := s.db.Order("name").Find(&profiles).Error
+	return profiles, err
+}
+
+func (s *ImportProfileService) Create(input ImportProfileInput) (models.ImportProfile, error) {
+	profile := models.ImportProfile{}
+	applyImportProfileInput(&profile, input)
+	err := s.db.Create(&profile).Error
+	return profile, err
+}
+
+func (s *ImportProfileService) Update(id uint, input ImportProfileInput) (models.ImportProfile, error) {
+	var profile models.ImportProfile
+	if err := s.db.First(&profile, id).Error; err != nil {
+		return profile, err
+	}
+	applyImportProfileInput(&profile, input)
+	err := s.db.Save(&profile).Error
+	return profile, err
+}
+
+// Delete removes a profile and unsets it as any account's default.
+func (s *ImportProfileService) Delete(id uint) error {
+	var profile models.ImportProfile
+	if err := s.db.First(&profile, id).Error; err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Account{}).Where("default_import_profile_id = ?", id).
+			Update("default_import_profile_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&profile).Error
+	})
+}
+
+// applyImportProfileInput copies input onto profile. Formatting fields left
+// blank take the values of the default profile.
+func applyImportProfileInput(profile *models.ImportProfile, input ImportProfileInput) {
+	def := DefaultImportProfile()
+	profile.Name = input.Name
+	profile.DateColumn = input.DateColumn
+	profile.DescriptionColumn = input.DescriptionColumn
+	profile.AmountColumn = input.AmountColumn
+	profile.PaidInColumn = input.PaidInColumn
+	profile.PaidOutColumn = input.PaidOutColumn
+	profile.TypeColumn = input.TypeColumn
+	profile.BalanceColumn = input.BalanceColumn
+	profile.DateFormat = orDefault(input.DateFormat, def.DateFormat)
+	profile.Delimiter = orDefault(input.Delimiter, def.Delimiter)
+	profile.DecimalSeparator = orDefault(input.DecimalSeparator, def.DecimalSeparator)
+	profile.SignConvention = orDefault(input.SignConvention, def.SignConvention)
+	profile.SkipRows = input.SkipRows
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// resolveImportProfile picks the profile for an import: the one requested,
+// else the account's default, else the built-in default layout.
+func resolveImportProfile(db *gorm.DB, accountID uint, profileID *uint) (models.ImportProfile, error) {
+	var profile models.ImportProfile
+	if profileID == nil {
+		var account models.Account
+		if err := db.First(&account, accountID).Error; err != nil {
+			return profile, err
+		}
+		if account.DefaultImportProfileID == nil {
+			return DefaultImportProfile(), nil
+		}
+		profileID = account.DefaultImportProfileID
+	}
+	err := db.First(&profile, *profileID).Error
+	return profile, err
+}
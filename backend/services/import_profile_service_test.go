@@ -0,0 +1,69 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/testutil"
+	"testing"
+)
+
+func TestImportProfileService_CreateFillsDefaults(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewImportProfileService(db)
+
+	profile, err := svc.Create(ImportProfileInput{Name: "Bank", DateColumn: "Date", DescriptionColumn: "Memo", AmountColumn: "Amount"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.DateFormat != "YYYY-MM-DD" || profile.Delimiter != "," || profile.DecimalSeparator != "." || profile.SignConvention != "negative_expense" {
+		t.Errorf("expected default formatting, got %+v", profile)
+	}
+}
+
+func TestImportProfileService_Resolve(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewImportProfileService(db)
+	accounts := NewAccountService(db)
+	txns := NewTransactionService(db)
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+
+	profile, err := txns.ImportProfile(account.ID, nil)
+	if err != nil || profile.ID != 0 || profile.AmountColumn != "amount" {
+		t.Fatalf("expected built-in default profile, got %+v (err %v)", profile, err)
+	}
+
+	uk, _ := svc.Create(ImportProfileInput{Name: "UK", DateColumn: "Date", DescriptionColumn: "Memo", AmountColumn: "Amount", DateFormat: "DD/MM/YYYY"})
+	other, _ := svc.Create(ImportProfileInput{Name: "Other", DateColumn: "Date", DescriptionColumn: "Memo", AmountColumn: "Value"})
+	if _, err := accounts.SetDefaultImportProfile(account.ID, &uk.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	profile, _ = txns.ImportProfile(account.ID, nil)
+	if profile.ID != uk.ID {
+		t.Errorf("expected account default %d, got %d", uk.ID, profile.ID)
+	}
+	profile, _ = txns.ImportProfile(account.ID, &other.ID)
+	if profile.ID != other.ID {
+		t.Errorf("expected requested profile %d, got %d", other.ID, profile.ID)
+	}
+}
+
+func TestImportProfileService_DeleteClearsAccountDefault(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewImportProfileService(db)
+	accounts := NewAccountService(db)
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	profile, _ := svc.Create(ImportProfileInput{Name: "UK", DateColumn: "Date", DescriptionColumn: "Memo", AmountColumn: "Amount"})
+	accounts.SetDefaultImportProfile(account.ID, &profile.ID)
+
+	if err := svc.Delete(profile.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.First(&account, account.ID)
+	if account.DefaultImportProfileID != nil {
+		t.Errorf("expected account default to be cleared, got %d", *account.DefaultImportProfileID)
+	}
+}
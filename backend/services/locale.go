@@ -0,0 +1,193 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxAmountDigits bounds the whole part of an amount so that it fits in
+// int64 cents with room to spare.
+const maxAmountDigits = 15
+
+// parseAmount converts a bank's formatted amount to signed cents exactly,
+// without going through floating point. decimalSep is "." or ","; the other
+// of the two, spaces and apostrophes are read as thousands separators, which
+// must group digits in threes. Currency symbols and three-letter codes (£, €,
+// EUR or eur) are ignored. A negative amount can be written as -12.50, 12.50-,
+// (12.50) or 12.50 DR; a CR suffix marks a credit and leaves the sign alone.
+// More than two decimal places are only accepted when the extra digits are
+// zeros.
+func parseAmount(s string, decimalSep string) (int64, error) {
+	invalid := fmt.Errorf("invalid amount: %q", s)
+	thousandsSep := ','
+	if decimalSep == "," {
+		thousandsSep = '.'
+	}
+
+	value := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' ' // no-break and narrow spaces are common thousands separators
+		}
+		return r
+	}, s))
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+	// Currency codes and CR/DR markers are matched in upper case
+	value = strings.ToUpper(value)
+	switch {
+	case strings.HasSuffix(value, "CR"):
+		value = strings.TrimSpace(value[:len(value)-2])
+	case strings.HasSuffix(value, "DR"):
+		negative = !negative
+		value = strings.TrimSpace(value[:len(value)-2])
+	}
+
+	var whole, frac strings.Builder
+	signs := 0
+	started, ended, inFrac := false, false, false
+	group, grouped := 0, false // digits since the last thousands separator
+	letters := 0               // length of the current run of currency code letters
+	runes := []rune(value)
+	for i, r := range runes {
+		if r < 'A' || r > 'Z' {
+			if letters != 0 && letters != 3 {
+				return 0, invalid
+			}
+			letters = 0
+		}
+		switch {
+		case r >= '0' && r <= '9':
+			if ended {
+				return 0, invalid
+			}
+			started = true
+			if inFrac {
+				frac.WriteRune(r)
+			} else {
+				whole.WriteRune(r)
+				group++
+			}
+		case string(r) == decimalSep:
+			if ended || inFrac || (grouped && group != 3) {
+				return 0, invalid
+			}
+			started, inFrac = true, true
+		case r == thousandsSep || r == '\'' || r == ' ':
+			if r == ' ' && (!started || ended || i+1 == len(runes) || runes[i+1] < '0' || runes[i+1] > '9') {
+				// A space around the number rather than within it
+				if started {
+					ended = true
+				}
+				continue
+			}
+			if !started || ended || inFrac || group == 0 || group > 3 || (grouped && group != 3) {
+				return 0, invalid
+			}
+			grouped, group = true, 0
+		case r == '-' || r == '+':
+			if r == '-' {
+				signs++
+			}
+			if started {
+				ended = true
+			}
+		case r >= 'A' && r <= 'Z':
+			letters++
+			if started {
+				ended = true
+			}
+		case unicode.Is(unicode.Sc, r):
+			if started {
+				ended = true
+			}
+		default:
+			return 0, invalid
+		}
+	}
+	if (letters != 0 && letters != 3) || signs > 1 || (signs == 1 && negative) {
+		return 0, invalid
+	}
+	if grouped && !inFrac && group != 3 {
+		return 0, invalid
+	}
+	if signs == 1 {
+		negative = true
+	}
+
+	wholeDigits, fracDigits := whole.String(), frac.String()
+	if wholeDigits == "" && fracDigits == "" {
+		return 0, invalid
+	}
+	if len(fracDigits) > 2 {
+		if strings.Trim(fracDigits[2:], "0") != "" {
+			return 0, invalid
+		}
+		fracDigits = fracDigits[:2]
+	}
+	fracDigits += strings.Repeat("0", 2-len(fracDigits))
+	wholeDigits = strings.TrimLeft(wholeDigits, "0")
+	if len(wholeDigits) > maxAmountDigits {
+		return 0, invalid
+	}
+	cents, err := strconv.ParseInt(wholeDigits+fracDigits, 10, 64)
+	if err != nil {
+		return 0, invalid
+	}
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// creditDebitMarker returns "CR" or "DR" when an amount is marked as a credit
+// or debit, which says which way the money went whatever the sign convention
+// of the rest of the file.
+func creditDebitMarker(s string) string {
+	upper := strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), ")")))
+	for _, marker := range []string{"CR", "DR"} {
+		if strings.HasSuffix(upper, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// dateLayouts converts a date format such as DD/MM/YYYY into Go time layouts.
+// Supported tokens are YYYY, YY, MMMM (January), MMM (Jan), MM, M, DD and D,
+// and several formats can be given separated by "|" for banks that are not
+// consistent. Days and months are read with or without a leading zero, and
+// month names in any case.
+func dateLayouts(format string) []string {
+	r := strings.NewReplacer("YYYY", "2006", "YY", "06", "MMMM", "January", "MMM", "Jan", "MM", "1", "M", "1", "DD", "2", "D", "2")
+	var layouts []string
+	for _, f := range strings.Split(format, "|") {
+		layouts = append(layouts, r.Replace(strings.TrimSpace(f)))
+	}
+	return layouts
+}
+
+// parseDate parses value with the first of layouts that fits.
+func parseDate(value string, layouts []string) (time.Time, error) {
+	value = strings.Join(strings.Fields(value), " ")
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,89 @@
+package services
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		sep     string
+		cents   int64
+		wantErr bool
+	}{
+		{in: "12.50", sep: ".", cents: 1250},
+		{in: "-45.5", sep: ".", cents: -4550},
+		{in: "1,234.56", sep: ".", cents: 123456},
+		{in: "1.234,56", sep: ",", cents: 123456},
+		{in: "1 234,56", sep: ",", cents: 123456},
+		{in: "1\u00a0234,56", sep: ",", cents: 123456},
+		{in: "1'234.56", sep: ".", cents: 123456},
+		{in: "£12.50", sep: ".", cents: 1250},
+		{in: "-£12.50", sep: ".", cents: -1250},
+		{in: "£-12.50", sep: ".", cents: -1250},
+		{in: "12,50 €", sep: ",", cents: 1250},
+		{in: "EUR 12.50", sep: ".", cents: 1250},
+		{in: "eur 12.50", sep: ".", cents: 1250},
+		{in: "12.50 usd", sep: ".", cents: 1250},
+		{in: "(45.00)", sep: ".", cents: -4500},
+		{in: "(£45.00)", sep: ".", cents: -4500},
+		{in: "12.50 CR", sep: ".", cents: 1250},
+		{in: "12.50 DR", sep: ".", cents: -1250},
+		{in: "12.50dr", sep: ".", cents: -1250},
+		{in: "12.50-", sep: ".", cents: -1250},
+		{in: "+3", sep: ".", cents: 300},
+		{in: ".05", sep: ".", cents: 5},
+		{in: "0.10000", sep: ".", cents: 10},
+		{in: "0.1", sep: ".", cents: 10},
+		{in: "1.234,56", sep: ".", wantErr: true},
+		{in: "12,50", sep: ".", wantErr: true},
+		{in: "1,23,456.00", sep: ".", wantErr: true},
+		{in: "0.125", sep: ".", wantErr: true},
+		{in: "--12", sep: ".", wantErr: true},
+		{in: "-(12)", sep: ".", wantErr: true},
+		{in: "12ab", sep: ".", wantErr: true},
+		{in: "12abcd", sep: ".", wantErr: true},
+		{in: "12 34", sep: ".", wantErr: true},
+		{in: "€", sep: ".", wantErr: true},
+		{in: "", sep: ".", wantErr: true},
+		{in: "99999999999999999999", sep: ".", wantErr: true},
+	}
+	for _, tt := range tests {
+		cents, err := parseAmount(tt.in, tt.sep)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAmount(%q, %q): expected error, got %d", tt.in, tt.sep, cents)
+			}
+			continue
+		}
+		if err != nil || cents != tt.cents {
+			t.Errorf("parseAmount(%q, %q) = %d, %v; want %d", tt.in, tt.sep, cents, err, tt.cents)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		format string
+		in     string
+		want   string
+	}{
+		{format: "YYYY-MM-DD", in: "2024-01-05", want: "2024-01-05"},
+		{format: "DD/MM/YYYY", in: "05/01/2024", want: "2024-01-05"},
+		{format: "DD/MM/YYYY", in: "5/1/2024", want: "2024-01-05"},
+		{format: "DD.MM.YYYY", in: "05.01.2024", want: "2024-01-05"},
+		{format: "DD-MM-YY", in: "05-01-24", want: "2024-01-05"},
+		{format: "D MMM YYYY", in: "5 JAN 2024", want: "2024-01-05"},
+		{format: "DD-MMM-YY", in: "05-jan-24", want: "2024-01-05"},
+		{format: "D MMMM YYYY", in: "5  January 2024", want: "2024-01-05"},
+		{format: "DD/MM/YYYY|YYYY-MM-DD", in: "2024-01-05", want: "2024-01-05"},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.in, dateLayouts(tt.format))
+		if err != nil || got.Format("2006-01-02") != tt.want {
+			t.Errorf("parseDate(%q) with %s = %v, %v; want %s", tt.in, tt.format, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseDate("31/02/2024", dateLayouts("DD/MM/YYYY")); err == nil {
+		t.Error("expected an impossible date to be rejected")
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetectImportFormat reports whether an uploaded file is "ofx", "qif" or
+// "csv". QFX is Quicken's name for OFX and is parsed the same way. The start
+// of the content is sniffed first, since banks are not consistent about file
+// names; failing that, an .ofx, .qfx or .qif extension is trusted.
+func DetectImportFormat(filename string, head []byte) string {
+	upper := bytes.ToUpper(bytes.TrimLeft(head, "\ufeff \t\r\n"))
+	if bytes.HasPrefix(upper, []byte("OFXHEADER")) || bytes.Contains(upper, []byte("<OFX>")) || bytes.Contains(upper, []byte("<?OFX")) {
+		return "ofx"
+	}
+	if bytes.HasPrefix(upper, []byte("!TYPE:")) || bytes.HasPrefix(upper, []byte("!ACCOUNT")) || bytes.HasPrefix(upper, []byte("!OPTION:")) {
+		return "qif"
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ofx", ".qfx":
+		return "ofx"
+	case ".qif":
+		return "qif"
+	}
+	return "csv"
+}
+
+// ParseOFX parses the statement transactions (STMTTRN) of an OFX or QFX file.
+// Both the SGML flavour of OFX 1.x, where leaf elements have no closing tag,
+// and the XML of OFX 2.x are accepted. Each transaction's FITID is kept as its
+// ExternalID so that re-imports can be matched exactly.
+func ParseOFX(reader io.Reader, accountID string) ([]models.Transaction, error) {
+	return parseOFX(reader, accountID, MaxCSVRows)
+}
+
+// parseOFX is ParseOFX with a cap on the number of transactions; 0 means no
+// cap.
+func parseOFX(reader io.Reader, accountID string, limit int) ([]models.Transaction, error) {
+	accID, err := strconv.ParseUint(accountID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account_id: %s", accountID)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OFX file: %w", err)
+	}
+	content := string(data)
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("failed to read OFX file: no <OFX> element found")
+	}
+	content = content[start:]
+
+	var transactions []models.Transaction
+	var fields map[string]string
+	finish := func() error {
+		if fields == nil {
+			return nil
+		}
+		if limit > 0 && len(transactions) >= limit {
+			return fmt.Errorf("OFX exceeds maximum of %d transactions", limit)
+		}
+		txn, err := ofxTransaction(fields, len(transactions)+1)
+		if err != nil {
+			return err
+		}
+		txn.AccountID = uint(accID)
+		transactions = append(transactions, txn)
+		fields = nil
+		return nil
+	}
+
+	for {
+		open := strings.IndexByte(content, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(content[open:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(content[open+1 : open+end]))
+		content = content[open+end+1:]
+		value := content
+		if next := strings.IndexByte(content, '<'); next >= 0 {
+			value = content[:next]
+		}
+
+		switch {
+		case tag == "STMTTRN":
+			// A new transaction also closes one left open by a sloppy export
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			fields = map[string]string{}
+		case tag == "/STMTTRN":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+		case fields != nil && !strings.HasPrefix(tag, "/") && !strings.HasPrefix(tag, "?") && !strings.HasPrefix(tag, "!"):
+			if v := strings.TrimSpace(html.UnescapeString(value)); v != "" {
+				if _, seen := fields[tag]; !seen {
+					fields[tag] = v
+				}
+			}
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// ofxTransaction builds a transaction from the leaf elements of one STMTTRN.
+// The sign of TRNAMT decides the type; a zero amount falls back to TRNTYPE.
+func ofxTransaction(fields map[string]string, n int) (models.Transaction, error) {
+	amountStr := fields["TRNAMT"]
+	decimalSep := "."
+	if strings.Contains(amountStr, ",") && !strings.Contains(amountStr, ".") {
+		decimalSep = ","
+	}
+	amountCents, err := parseAmount(amountStr, decimalSep)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("invalid amount in OFX transaction %d: %s", n, amountStr)
+	}
+
+	dateStr := fields["DTPOSTED"]
+	if len(dateStr) < 8 {
+		return models.Transaction{}, fmt.Errorf("invalid date in OFX transaction %d: %s", n, dateStr)
+	}
+	date, err := time.Parse("20060102", dateStr[:8])
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("invalid date in OFX transaction %d: %s", n, dateStr)
+	}
+
+	txnType := "income"
+	switch {
+	case amountCents < 0:
+		txnType = "expense"
+		amountCents = -amountCents
+	case amountCents == 0:
+		switch fields["TRNTYPE"] {
+		case "DEBIT", "PAYMENT", "CHECK", "FEE", "SRVCHG", "ATM", "POS", "DIRECTDEBIT", "CASH":
+			txnType = "expense"
+		}
+	}
+
+	desc := fields["NAME"]
+	if desc == "" {
+		desc = fields["MEMO"]
+	}
+	if len(desc) > 500 {
+		desc = desc[:500]
+	}
+
+	txn := models.Transaction{
+		Amount:      amountCents,
+		Description: desc,
+		Date:        date.Format("2006-01-02"),
+		Type:        txnType,
+	}
+	if fitID := fields["FITID"]; fitID != "" {
+		txn.ExternalID = &fitID
+	}
+	return txn, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+const sgmlOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240115120000[0:GMT]
+<TRNAMT>-45.50
+<FITID>2024011501
+<NAME>TESCO STORES
+<MEMO>CARD 1234
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240116
+<TRNAMT>3000.00
+<FITID>2024011602
+<MEMO>Salary &amp; bonus
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+func TestParseOFX_SGML(t *testing.T) {
+	txns, err := ParseOFX(strings.NewReader(sgmlOFX), "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(txns) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txns))
+	}
+
+	if txns[0].Date != "2024-01-15" || txns[0].Amount != 4550 || txns[0].Type != "expense" {
+		t.Errorf("unexpected first transaction: %+v", txns[0])
+	}
+	if txns[0].Description != "TESCO STORES" {
+		t.Errorf("expected NAME as description, got %q", txns[0].Description)
+	}
+	if txns[0].ExternalID == nil || *txns[0].ExternalID != "2024011501" {
+		t.Errorf("expected FITID to be kept, got %v", txns[0].ExternalID)
+	}
+	if txns[0].AccountID != 1 {
+		t.Errorf("expected account_id 1, got %d", txns[0].AccountID)
+	}
+
+	if txns[1].Type != "income" || txns[1].Amount != 300000 {
+		t.Errorf("unexpected second transaction: %+v", txns[1])
+	}
+	if txns[1].Description != "Salary & bonus" {
+		t.Errorf("expected MEMO fallback with entities decoded, got %q", txns[1].Description)
+	}
+}
+
+func TestParseOFX_XML(t *testing.T) {
+	ofx := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>
+<STMTTRN><TRNTYPE>POS</TRNTYPE><DTPOSTED>20240201</DTPOSTED><TRNAMT>-3.20</TRNAMT><FITID>abc-1</FITID><NAME>Coffee</NAME></STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	txns, err := ParseOFX(strings.NewReader(ofx), "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(txns) != 1 || txns[0].Amount != 320 || txns[0].Description != "Coffee" || *txns[0].ExternalID != "abc-1" {
+		t.Errorf("unexpected transactions: %+v", txns)
+	}
+}
+
+func TestParseOFX_InvalidAmount(t *testing.T) {
+	ofx := "<OFX><STMTTRN><DTPOSTED>20240201<TRNAMT>lots<FITID>1</STMTTRN></OFX>"
+
+	_, err := ParseOFX(strings.NewReader(ofx), "1")
+	if err == nil || !strings.Contains(err.Error(), "invalid amount") {
+		t.Errorf("expected 'invalid amount' error, got %v", err)
+	}
+}
+
+func TestParseOFX_NotOFX(t *testing.T) {
+	_, err := ParseOFX(strings.NewReader("date,description,amount\n"), "1")
+	if err == nil {
+		t.Fatal("expected error for a file without an OFX element")
+	}
+}
+
+func TestDetectImportFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		head     string
+		want     string
+	}{
+		{"statement.qfx", "", "ofx"},
+		{"statement.OFX", "", "ofx"},
+		{"export.csv", "OFXHEADER:100", "ofx"},
+		{"export.csv", "date,description,amount", "csv"},
+		{"download", "OFXHEADER:100\nDATA:OFXSGML", "ofx"},
+		{"download", "<?xml version=\"1.0\"?>\n<?OFX OFXHEADER=\"200\"?>", "ofx"},
+		{"download", "date,description,amount", "csv"},
+	}
+	for _, tt := range tests {
+		if got := DetectImportFormat(tt.filename, []byte(tt.head)); got != tt.want {
+			t.Errorf("DetectImportFormat(%q, %q) = %s, want %s", tt.filename, tt.head, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// qifSections maps the QIF section headers whose records are transactions to
+// the account types they come from. Other sections (investments, memorized
+// transactions, category and account lists) are skipped on import.
+var qifSections = map[string]bool{"bank": true, "ccard": true, "cash": true}
+
+// ParseQIF parses the bank, credit card and cash sections of a QIF file. QIF
+// dates carry no order marker, so dayFirst says whether they are D/M/Y rather
+// than Quicken's M/D/Y. Categories are returned by name in each transaction's
+// Category (and each split's), with no ID; ResolveCategoryNames maps them.
+// Transfers, written as an account name in brackets, are left uncategorized.
+func ParseQIF(reader io.Reader, accountID string, dayFirst bool) ([]models.Transaction, error) {
+	return parseQIF(reader, accountID, dayFirst, MaxCSVRows)
+}
+
+// parseQIF is ParseQIF with a cap on the number of transactions; 0 means no
+// cap.
+func parseQIF(reader io.Reader, accountID string, dayFirst bool, limit int) ([]models.Transaction, error) {
+	accID, err := strconv.ParseUint(accountID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account_id: %s", accountID)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var transactions []models.Transaction
+	inTransactions := false
+	var record []string
+	recordNum := 0
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			header := strings.ToLower(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(header, "!type:"):
+				inTransactions = qifSections[strings.TrimSpace(strings.TrimPrefix(header, "!type:"))]
+			case header == "!account":
+				inTransactions = false
+			}
+			// !Option and !Clear lines don't change the section
+			record = nil
+			continue
+		}
+
+		if line != "^" {
+			record = append(record, line)
+			continue
+		}
+		if inTransactions && len(record) > 0 {
+			recordNum++
+			if limit > 0 && len(transactions) >= limit {
+				return nil, fmt.Errorf("QIF exceeds maximum of %d transactions", limit)
+			}
+			txn, err := qifTransaction(record, recordNum, dayFirst)
+			if err != nil {
+				return nil, err
+			}
+			txn.AccountID = uint(accID)
+			transactions = append(transactions, txn)
+		}
+		record = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read QIF file: %w", err)
+	}
+	if inTransactions && len(record) > 0 {
+		return nil, fmt.Errorf("QIF record %d is missing its closing ^", recordNum+1)
+	}
+	return transactions, nil
+}
+
+// qifTransaction builds a transaction from the lines of one QIF record.
+func qifTransaction(lines []string, n int, dayFirst bool) (models.Transaction, error) {
+	var dateStr, amountStr, payee, memo, category string
+	var splits []models.TransactionSplit
+	var splitAmounts []string
+	for _, line := range lines {
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch code {
+		case 'D':
+			dateStr = value
+		case 'T':
+			amountStr = value
+		case 'U':
+			if amountStr == "" {
+				amountStr = value
+			}
+		case 'P':
+			payee = value
+		case 'M':
+			memo = value
+		case 'L':
+			category = value
+		case 'S':
+			split := models.TransactionSplit{}
+			if name := qifCategory(value); name != "" {
+				split.Category = &models.Category{Name: name}
+			}
+			splits = append(splits, split)
+			splitAmounts = append(splitAmounts, "")
+		case 'E':
+			if len(splits) > 0 {
+				splits[len(splits)-1].Memo = value
+			}
+		case '$':
+			if len(splits) > 0 {
+				splitAmounts[len(splits)-1] = value
+			}
+		}
+	}
+
+	amountCents, err := parseAmount(amountStr, ".")
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("invalid amount in QIF record %d: %s", n, amountStr)
+	}
+	date, err := parseQIFDate(dateStr, dayFirst)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("invalid date in QIF record %d: %s", n, dateStr)
+	}
+
+	sign := int64(1)
+	txnType := "income"
+	if amountCents < 0 {
+		sign = -1
+		txnType = "expense"
+		amountCents = -amountCents
+	}
+
+	desc := payee
+	if desc == "" {
+		desc = memo
+	}
+	if len(desc) > 500 {
+		desc = desc[:500]
+	}
+
+	txn := models.Transaction{
+		Amount:      amountCents,
+		Description: desc,
+		Date:        date.Format("2006-01-02"),
+		Type:        txnType,
+	}
+
+	if len(splits) > 0 {
+		var sum int64
+		kept := splits[:0]
+		for i, split := range splits {
+			a, err := parseAmount(splitAmounts[i], ".")
+			if err != nil {
+				return models.Transaction{}, fmt.Errorf("invalid split amount in QIF record %d: %s", n, splitAmounts[i])
+			}
+			// Split lines carry the same sign as the total
+			a *= sign
+			if a < 0 {
+				return models.Transaction{}, fmt.Errorf("QIF record %d has a split against the direction of the transaction, which is not supported", n)
+			}
+			if a == 0 {
+				continue
+			}
+			split.Amount = a
+			sum += a
+			kept = append(kept, split)
+		}
+		if sum != amountCents {
+			return models.Transaction{}, fmt.Errorf("split amounts in QIF record %d don't add up to the total", n)
+		}
+		txn.Splits = kept
+		return txn, nil
+	}
+
+	if name := qifCategory(category); name != "" {
+		txn.Category = &models.Category{Name: name}
+	}
+	return txn, nil
+}
+
+// qifCategory returns the category name of an L or S field, dropping any
+// "/class" suffix. Transfers, written as [Account], have no category.
+func qifCategory(value string) string {
+	if i := strings.Index(value, "/"); i >= 0 {
+		value = value[:i]
+	}
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") {
+		return ""
+	}
+	return value
+}
+
+// parseQIFDate reads dates such as 1/15/2024, 01/15/24, 1/15'24 and 1/ 5/24,
+// or 15/1/2024 when dayFirst is set. ISO dates are accepted as they are.
+// Two-digit years are taken as 19xx from 70 and 20xx below.
+func parseQIFDate(s string, dayFirst bool) (time.Time, error) {
+	s = strings.ReplaceAll(s, " ", "")
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '\'' || r == '-' || r == '.' })
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid QIF date: %s", s)
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid QIF date: %s", s)
+		}
+		nums[i] = n
+	}
+	month, day, year := nums[0], nums[1], nums[2]
+	if dayFirst {
+		month, day = day, month
+	}
+	if len(parts[2]) <= 2 {
+		if year >= 70 {
+			year += 1900
+		} else {
+			year += 2000
+		}
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Month() != time.Month(month) || t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid QIF date: %s", s)
+	}
+	return t, nil
+}
+
+// writeQIF writes transactions as QIF, one !Account block per account in the
+// order the accounts first appear. Credit accounts are written as CCard
+// sections and everything else as Bank. Transfers name the other account in
+// brackets so the pair can be rebuilt by a desktop tool.
+func writeQIF(w io.Writer, transactions []models.Transaction, accounts map[uint]models.Account) error {
+	bw := bufio.NewWriter(w)
+	var order []uint
+	byAccount := map[uint][]models.Transaction{}
+	for _, t := range transactions {
+		if _, ok := byAccount[t.AccountID]; !ok {
+			order = append(order, t.AccountID)
+		}
+		byAccount[t.AccountID] = append(byAccount[t.AccountID], t)
+	}
+
+	for _, accountID := range order {
+		account := accounts[accountID]
+		section := "Bank"
+		if account.Type == "credit" {
+			section = "CCard"
+		}
+		fmt.Fprintf(bw, "!Account\nN%s\nT%s\n^\n!Type:%s\n", qifText(account.Name), section, section)
+
+		for _, t := range byAccount[accountID] {
+			date, _ := time.Parse("2006-01-02", t.Date)
+			sign := int64(1)
+			if t.Type == "expense" {
+				sign = -1
+			}
+			fmt.Fprintf(bw, "D%s\nT%s\nP%s\n", date.Format("01/02/2006"), formatQIFAmount(sign*t.Amount), qifText(t.Description))
+			switch {
+			case t.TransferAccountID != nil:
+				fmt.Fprintf(bw, "L[%s]\n", qifText(accounts[*t.TransferAccountID].Name))
+			case t.Category != nil:
+				fmt.Fprintf(bw, "L%s\n", qifText(t.Category.Name))
+			}
+			for _, split := range t.Splits {
+				name := ""
+				if split.Category != nil {
+					name = split.Category.Name
+				}
+				fmt.Fprintf(bw, "S%s\n", qifText(name))
+				if split.Memo != "" {
+					fmt.Fprintf(bw, "E%s\n", qifText(split.Memo))
+				}
+				fmt.Fprintf(bw, "$%s\n", formatQIFAmount(sign*split.Amount))
+			}
+			fmt.Fprint(bw, "^\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// formatQIFAmount formats signed cents as a decimal such as -45.50.
+func formatQIFAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// qifText keeps a value on one line; QIF has no escaping.
+func qifText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
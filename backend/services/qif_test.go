@@ -0,0 +1,207 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const sampleQIF = `!Type:Bank
+D1/15'24
+T-45.50
+PTesco
+LGroceries/Household
+^
+D01/16/2024
+T3,000.00
+PACME Ltd
+LSalary
+^
+D1/17/24
+T-100.00
+PTransfer to savings
+L[Savings]
+^
+D1/18/2024
+T-60.00
+PSupermarket
+SGroceries
+EFood
+$-40.00
+SHousehold
+$-20.00
+^
+!Type:Invst
+D1/19/2024
+NBuy
+^
+`
+
+func TestParseQIF(t *testing.T) {
+	txns, err := ParseQIF(strings.NewReader(sampleQIF), "1", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(txns) != 4 {
+		t.Fatalf("expected 4 transactions (investments skipped), got %d", len(txns))
+	}
+
+	if txns[0].Date != "2024-01-15" || txns[0].Amount != 4550 || txns[0].Type != "expense" || txns[0].Description != "Tesco" {
+		t.Errorf("unexpected first transaction: %+v", txns[0])
+	}
+	if txns[0].Category == nil || txns[0].Category.Name != "Groceries" {
+		t.Errorf("expected category Groceries without class, got %+v", txns[0].Category)
+	}
+	if txns[1].Amount != 300000 || txns[1].Type != "income" {
+		t.Errorf("unexpected second transaction: %+v", txns[1])
+	}
+	if txns[2].Category != nil {
+		t.Errorf("expected transfer to be uncategorized, got %+v", txns[2].Category)
+	}
+
+	splits := txns[3].Splits
+	if len(splits) != 2 || splits[0].Amount != 4000 || splits[1].Amount != 2000 || splits[0].Memo != "Food" {
+		t.Errorf("unexpected splits: %+v", splits)
+	}
+	if splits[1].Category == nil || splits[1].Category.Name != "Household" {
+		t.Errorf("expected split category Household, got %+v", splits[1].Category)
+	}
+}
+
+func TestParseQIF_DayFirst(t *testing.T) {
+	qif := "!Type:CCard\nD15/01/2024\nT-5.00\nPCoffee\n^\n"
+
+	txns, err := ParseQIF(strings.NewReader(qif), "1", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if txns[0].Date != "2024-01-15" {
+		t.Errorf("expected 2024-01-15, got %s", txns[0].Date)
+	}
+	if _, err := ParseQIF(strings.NewReader(qif), "1", false); err == nil {
+		t.Error("expected month 15 to be rejected without dayFirst")
+	}
+}
+
+func TestParseQIF_SplitMismatch(t *testing.T) {
+	qif := "!Type:Bank\nD1/18/2024\nT-60.00\nSGroceries\n$-40.00\n^\n"
+
+	_, err := ParseQIF(strings.NewReader(qif), "1", false)
+	if err == nil || !strings.Contains(err.Error(), "add up") {
+		t.Errorf("expected split sum error, got %v", err)
+	}
+}
+
+func TestTransactionService_ResolveCategoryNames(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+	groceries := models.Category{Name: "Groceries", Colour: "#00FF00"}
+	svc.db.Create(&groceries)
+
+	parsed := func() []models.Transaction {
+		txns, _ := ParseQIF(strings.NewReader(sampleQIF), "1", false)
+		for i := range txns {
+			txns[i].AccountID = account.ID
+		}
+		return txns
+	}
+
+	txns := parsed()
+	if err := svc.ResolveCategoryNames(txns, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if txns[0].CategoryID == nil || *txns[0].CategoryID != groceries.ID || txns[0].Category != nil {
+		t.Errorf("expected Groceries to map to %d, got %v", groceries.ID, txns[0].CategoryID)
+	}
+	if txns[1].CategoryID != nil || txns[3].Splits[1].CategoryID != nil {
+		t.Error("expected unknown categories to be dropped without create")
+	}
+
+	txns = parsed()
+	if err := svc.ResolveCategoryNames(txns, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var count int64
+	svc.db.Model(&models.Category{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected nothing to be created before the import commits, got %d categories", count)
+	}
+
+	result, err := svc.ImportCSV(txns, ImportOptions{Duplicates: "skip"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	svc.db.Model(&models.Category{}).Count(&count)
+	if count != 3 {
+		t.Errorf("expected Salary and Household to be created, got %d categories", count)
+	}
+	var split models.Transaction
+	svc.db.Preload("Splits").First(&split, *result.Rows[3].TransactionID)
+	if len(split.Splits) != 2 {
+		t.Errorf("expected split lines to be imported, got %d", len(split.Splits))
+	}
+}
+
+func TestTransactionService_PreviewCreatesCategoriesOnConfirm(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+	txns, _ := ParseQIF(strings.NewReader(sampleQIF), "1", false)
+	for i := range txns {
+		txns[i].AccountID = account.ID
+	}
+	if err := svc.ResolveCategoryNames(txns, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	preview, err := svc.PreviewImport(account.ID, txns, ImportOptions{})
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	var count int64
+	svc.db.Model(&models.Category{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected a preview to create no categories, got %d", count)
+	}
+	if preview.Rows[0].CategoryName != "Groceries" {
+		t.Errorf("expected the row to keep its category name, got %q", preview.Rows[0].CategoryName)
+	}
+
+	result, err := svc.ConfirmPreview(preview.Token)
+	if err != nil {
+		t.Fatalf("confirm failed: %v", err)
+	}
+	var imported models.Transaction
+	svc.db.Preload("Category").First(&imported, *result.Rows[0].TransactionID)
+	if imported.Category == nil || imported.Category.Name != "Groceries" {
+		t.Errorf("expected Groceries to be created on confirm, got %+v", imported.Category)
+	}
+	svc.db.Model(&models.Category{}).Count(&count)
+	if count != 3 {
+		t.Errorf("expected Groceries, Salary and Household to be created once each, got %d categories", count)
+	}
+}
+
+func TestTransactionService_ExportQIF(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+	food := models.Category{Name: "Food", Colour: "#00FF00"}
+	svc.db.Create(&food)
+	svc.Create(&models.Transaction{AccountID: account.ID, CategoryID: &food.ID, Amount: 1250, Description: "Lunch", Date: "2024-01-15", Type: "expense"})
+	svc.Create(&models.Transaction{AccountID: account.ID, Amount: 5000, Description: "Refund", Date: "2024-02-01", Type: "income"})
+
+	var buf bytes.Buffer
+	if err := svc.ExportQIF(&buf, TransactionListParams{DateTo: "2024-01-31"}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "!Type:Bank\nD01/15/2024\nT-12.50\nPLunch\nLFood\n^\n") {
+		t.Errorf("unexpected QIF:\n%s", out)
+	}
+	if strings.Contains(out, "Refund") {
+		t.Error("expected date filter to be honoured")
+	}
+
+	// What we write, we can read back
+	txns, err := ParseQIF(strings.NewReader(out), "1", false)
+	if err != nil || len(txns) != 1 || txns[0].Amount != 1250 || txns[0].Category.Name != "Food" {
+		t.Errorf("expected round trip, got %+v (err %v)", txns, err)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/testutil"
+	"errors"
+	"testing"
+)
+
+func setupReconcileTest(t *testing.T) (*AccountService, *TransactionService, models.Account) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	account := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&account)
+	txns := []models.Transaction{
+		{AccountID: account.ID, Amount: 100000, Description: "Salary", Date: "2024-01-01", Type: "income", Status: "cleared"},
+		{AccountID: account.ID, Amount: 2500, Description: "Groceries", Date: "2024-01-05", Type: "expense", Status: "cleared"},
+		{AccountID: account.ID, Amount: 1200, Description: "Cinema", Date: "2024-01-06", Type: "expense"},
+		{AccountID: account.ID, Amount: 800, Description: "Lunch", Date: "2024-02-02", Type: "expense", Status: "cleared"},
+	}
+	db.Create(&txns)
+	return NewAccountService(db), NewTransactionService(db), account
+}
+
+func TestAccountService_ListClearedBalances(t *testing.T) {
+	accounts, _, account := setupReconcileTest(t)
+
+	list, err := accounts.List(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != account.ID {
+		t.Fatalf("unexpected accounts: %+v", list)
+	}
+	if list[0].ClearedBalance != 96700 || list[0].UnclearedBalance != -1200 || !list[0].HasTransactions {
+		t.Errorf("expected cleared 96700 and uncleared -1200, got %+v", list[0])
+	}
+}
+
+func TestAccountService_ReconcileMismatch(t *testing.T) {
+	accounts, txns, account := setupReconcileTest(t)
+
+	input := ReconcileInput{StatementDate: "2024-01-31", StatementBalance: 97000}
+	result, err := accounts.Reconcile(account.ID, input)
+	if !errors.Is(err, ErrReconcileMismatch) {
+		t.Fatalf("expected ErrReconcileMismatch, got %v", err)
+	}
+	// Only cleared rows up to the statement date count
+	if result.ClearedBalance != 97500 || result.Difference != -500 {
+		t.Fatalf("unexpected mismatch result: %+v", result)
+	}
+	list, _, _ := txns.List(TransactionListParams{})
+	for _, txn := range list {
+		if txn.Status == "reconciled" {
+			t.Fatalf("expected nothing to be reconciled on a mismatch, got %s", txn.Description)
+		}
+	}
+
+	input.Adjust = true
+	result, err = accounts.Reconcile(account.ID, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	adj := result.Adjustment
+	if adj == nil || adj.Amount != 500 || adj.Type != "expense" || adj.Date != "2024-01-31" || adj.Status != "reconciled" {
+		t.Fatalf("unexpected adjustment: %+v", adj)
+	}
+	if result.Reconciled != 2 {
+		t.Errorf("expected 2 transactions reconciled, got %d", result.Reconciled)
+	}
+	checkpoints, _ := accounts.Checkpoints(account.ID)
+	if len(checkpoints) != 1 || checkpoints[0].Balance != 97000 || checkpoints[0].Difference != 500 || checkpoints[0].ImportBatchID != nil {
+		t.Errorf("unexpected checkpoints: %+v", checkpoints)
+	}
+}
+
+func TestAccountService_ReconcileMatches(t *testing.T) {
+	accounts, txns, account := setupReconcileTest(t)
+
+	result, err := accounts.Reconcile(account.ID, ReconcileInput{StatementDate: "2024-01-31", StatementBalance: 97500})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Reconciled != 2 || result.Adjustment != nil || result.CheckpointID == 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	list, _, _ := txns.List(TransactionListParams{})
+	statuses := map[string]string{}
+	for _, txn := range list {
+		statuses[txn.Description] = txn.Status
+	}
+	want := map[string]string{"Salary": "reconciled", "Groceries": "reconciled", "Cinema": "uncleared", "Lunch": "cleared"}
+	for desc, status := range want {
+		if statuses[desc] != status {
+			t.Errorf("expected %s to be %s, got %s", desc, status, statuses[desc])
+		}
+	}
+}
+
+func TestTransactionService_ReconciledLocked(t *testing.T) {
+	accounts, txns, account := setupReconcileTest(t)
+	if _, err := accounts.Reconcile(account.ID, ReconcileInput{StatementDate: "2024-01-31", StatementBalance: 97500}); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	var salary models.Transaction
+	txns.db.Where("description = ?", "Salary").First(&salary)
+
+	amount := int64(90000)
+	if _, err := txns.Update(salary.ID, UpdateTransactionInput{Amount: &amount}); !errors.Is(err, ErrTransactionReconciled) {
+		t.Errorf("expected amount change to be refused, got %v", err)
+	}
+	status := "uncleared"
+	if _, err := txns.Update(salary.ID, UpdateTransactionInput{Status: &status}); !errors.Is(err, ErrTransactionReconciled) {
+		t.Errorf("expected status change to be refused, got %v", err)
+	}
+	if err := txns.Delete(salary.ID); !errors.Is(err, ErrTransactionReconciled) {
+		t.Errorf("expected delete to be refused, got %v", err)
+	}
+
+	// Descriptions don't affect the balance
+	desc := "January salary"
+	if _, err := txns.Update(salary.ID, UpdateTransactionInput{Description: &desc}); err != nil {
+		t.Errorf("expected description change to be allowed, got %v", err)
+	}
+
+	unlocked, err := txns.Unlock(salary.ID)
+	if err != nil || unlocked.Status != "cleared" {
+		t.Fatalf("expected unlock to clear the row, got %v, %v", unlocked.Status, err)
+	}
+	if _, err := txns.Update(salary.ID, UpdateTransactionInput{Amount: &amount}); err != nil {
+		t.Errorf("expected amount change after unlock, got %v", err)
+	}
+}
+
+func TestTransactionService_ReconciledTransferLeg(t *testing.T) {
+	accounts, txns, account := setupReconcileTest(t)
+	savings := models.Account{Name: "Savings", Type: "savings"}
+	txns.db.Create(&savings)
+	legs, err := txns.CreateTransfer(CreateTransferInput{FromAccountID: account.ID, ToAccountID: savings.ID, Amount: 5000, Date: "2024-01-10"})
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	cleared := "cleared"
+	txns.Update(legs[1].ID, UpdateTransactionInput{Status: &cleared})
+	if _, err := accounts.Reconcile(savings.ID, ReconcileInput{StatementDate: "2024-01-31", StatementBalance: 5000}); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	// The checking leg isn't reconciled, but its amount is shared
+	amount := int64(6000)
+	if _, err := txns.Update(legs[0].ID, UpdateTransactionInput{Amount: &amount}); !errors.Is(err, ErrTransactionReconciled) {
+		t.Errorf("expected shared amount change to be refused, got %v", err)
+	}
+	if err := txns.Delete(legs[0].ID); !errors.Is(err, ErrTransactionReconciled) {
+		t.Errorf("expected transfer delete to be refused, got %v", err)
+	}
+}
+
+func TestImportCSV_MarksRowsCleared(t *testing.T) {
+	_, txns, account := setupReconcileTest(t)
+
+	result, err := txns.ImportCSV([]models.Transaction{
+		{AccountID: account.ID, Amount: 999, Description: "Imported", Date: "2024-02-03", Type: "expense"},
+	}, ImportOptions{Duplicates: "skip"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	var imported models.Transaction
+	txns.db.First(&imported, *result.Rows[0].TransactionID)
+	if imported.Status != "cleared" {
+		t.Errorf("expected imported row to be cleared, got %s", imported.Status)
+	}
+}
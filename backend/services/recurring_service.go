@@ -0,0 +1,286 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RecurringService struct {
+	db           *gorm.DB
+	transactions *TransactionService
+}
+
+func NewRecurringService(db *gorm.DB, transactions *TransactionService) *RecurringService {
+	return &RecurringService{db: db, transactions: transactions}
+}
+
+type RecurringInput struct {
+	AccountID   uint    `json:"account_id" binding:"required"`
+	CategoryID  *uint   `json:"category_id"`
+	Amount      int64   `json:"amount"`
+	Description string  `json:"description" binding:"required"`
+	Type        string  `json:"type" binding:"required"`
+	Frequency   string  `json:"frequency" binding:"required"`
+	Interval    int     `json:"interval"`
+	DayOfMonth  int     `json:"day_of_month"`
+	StartDate   string  `json:"start_date" binding:"required"`
+	EndDate     *string `json:"end_date"`
+}
+
+func (s *RecurringService) List() ([]models.RecurringTransaction, error) {
+	var rules []models.RecurringTransaction
+	err := s.db.Preload("Account").Preload("Category").Order("next_date, id").Find(&rules).Error
+	return rules, err
+}
+
+// Create adds a rule. It returns ErrRecurringReference if the account or
+// category doesn't exist.
+func (s *RecurringService) Create(input RecurringInput) (models.RecurringTransaction, error) {
+	rule := models.RecurringTransaction{}
+	if err := checkRecurringReferences(s.db, input); err != nil {
+		return rule, err
+	}
+	applyRecurringInput(&rule, input)
+	rule.NextDate = firstOccurrence(rule, rule.StartDate)
+
+	if err := s.db.Create(&rule).Error; err != nil {
+		return rule, err
+	}
+	err := s.db.Preload("Account").Preload("Category").First(&rule, rule.ID).Error
+	return rule, err
+}
+
+// Update replaces a rule's definition. The next occurrence is recomputed from
+// the new schedule, but never lands on or before an occurrence that has
+// already been posted. Like Create, it checks the account and category exist.
+func (s *RecurringService) Update(id uint, input RecurringInput) (models.RecurringTransaction, error) {
+	var rule models.RecurringTransaction
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return rule, err
+	}
+	if err := checkRecurringReferences(s.db, input); err != nil {
+		return rule, err
+	}
+	applyRecurringInput(&rule, input)
+
+	from := rule.StartDate
+	var lastPosted *string
+	if err := s.db.Model(&models.Transaction{}).Where("recurring_id = ?", rule.ID).
+		Select("MAX(recurring_date)").Scan(&lastPosted).Error; err != nil {
+		return rule, err
+	}
+	if lastPosted != nil {
+		if after := addDays(*lastPosted, 1); after > from {
+			from = after
+		}
+	}
+	rule.NextDate = firstOccurrence(rule, from)
+
+	if err := s.db.Save(&rule).Error; err != nil {
+		return rule, err
+	}
+	err := s.db.Preload("Account").Preload("Category").First(&rule, rule.ID).Error
+	return rule, err
+}
+
+// checkRecurringReferences returns ErrRecurringReference if a rule's account
+// or category doesn't exist.
+func checkRecurringReferences(db *gorm.DB, input RecurringInput) error {
+	if err := db.First(&models.Account{}, input.AccountID).Error; err == gorm.ErrRecordNotFound {
+		return ErrRecurringReference
+	} else if err != nil {
+		return err
+	}
+	if input.CategoryID != nil {
+		if err := db.First(&models.Category{}, *input.CategoryID).Error; err == gorm.ErrRecordNotFound {
+			return ErrRecurringReference
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a rule. Transactions it already posted are kept.
+func (s *RecurringService) Delete(id uint) error {
+	var rule models.RecurringTransaction
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return err
+	}
+	return s.db.Delete(&rule).Error
+}
+
+// Materialize posts every occurrence due on or before today. Each posted row
+// records its rule and scheduled date, which are unique together, so a crash
+// between posting and advancing next_date cannot cause a double post: the
+// occurrence is found on the next run and skipped. Rules for closed accounts
+// are left alone until the account is reopened, and rules whose next date is
+// past their end date have ended and are no longer picked up. A rule that
+// fails to post is logged and skipped, so it doesn't hold up the others; it
+// is retried on the next run.
+func (s *RecurringService) Materialize(today string) (int, error) {
+	var rules []models.RecurringTransaction
+	if err := s.db.Where("next_date <= ? AND (end_date IS NULL OR next_date <= end_date) AND account_id NOT IN (?)", today,
+		s.db.Model(&models.Account{}).Select("id").Where("closed_date IS NOT NULL")).
+		Order("id").Find(&rules).Error; err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	for _, rule := range rules {
+		n, err := s.materializeRule(rule, today)
+		posted += n
+		if err != nil {
+			slog.Error("Failed to post recurring transaction", "rule", rule.ID, "date", rule.NextDate, "error", err)
+		}
+	}
+	return posted, nil
+}
+
+// materializeRule posts one rule's due occurrences and returns how many it
+// posted.
+func (s *RecurringService) materializeRule(rule models.RecurringTransaction, today string) (int, error) {
+	posted := 0
+	for rule.NextDate <= today && (rule.EndDate == nil || rule.NextDate <= *rule.EndDate) {
+		occurrence := rule.NextDate
+		var count int64
+		if err := s.db.Model(&models.Transaction{}).
+			Where("recurring_id = ? AND recurring_date = ?", rule.ID, occurrence).
+			Count(&count).Error; err != nil {
+			return posted, err
+		}
+		if count == 0 {
+			ruleID := rule.ID
+			txn := models.Transaction{
+				AccountID:     rule.AccountID,
+				CategoryID:    rule.CategoryID,
+				Amount:        rule.Amount,
+				Description:   rule.Description,
+				Date:          occurrence,
+				Type:          rule.Type,
+				RecurringID:   &ruleID,
+				RecurringDate: &occurrence,
+			}
+			if err := s.transactions.Create(&txn); err != nil {
+				return posted, err
+			}
+			posted++
+		}
+		rule.NextDate = nextOccurrence(rule, occurrence)
+		if err := s.db.Model(&rule).Update("next_date", rule.NextDate).Error; err != nil {
+			return posted, err
+		}
+	}
+	return posted, nil
+}
+
+// Run materializes due transactions immediately and then on every tick until
+// ctx is cancelled.
+func (s *RecurringService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Materialize(time.Now().Format("2006-01-02")); err != nil {
+			slog.Error("Failed to materialize recurring transactions", "error", err)
+		} else if n > 0 {
+			slog.Info("Posted recurring transactions", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func applyRecurringInput(rule *models.RecurringTransaction, input RecurringInput) {
+	rule.AccountID = input.AccountID
+	rule.CategoryID = input.CategoryID
+	rule.Amount = input.Amount
+	rule.Description = input.Description
+	rule.Type = input.Type
+	rule.Frequency = input.Frequency
+	rule.Interval = input.Interval
+	if rule.Interval < 1 {
+		rule.Interval = 1
+	}
+	rule.DayOfMonth = input.DayOfMonth
+	if rule.Frequency == "monthly" && rule.DayOfMonth == 0 {
+		start, _ := time.Parse("2006-01-02", input.StartDate)
+		rule.DayOfMonth = start.Day()
+	}
+	rule.StartDate = input.StartDate
+	rule.EndDate = input.EndDate
+}
+
+// firstOccurrence returns the first date on or after from that the rule's
+// schedule falls on.
+func firstOccurrence(rule models.RecurringTransaction, from string) string {
+	f, _ := time.Parse("2006-01-02", from)
+	switch rule.Frequency {
+	case "weekly":
+		// Keep the weekday anchored to the start date
+		start, _ := time.Parse("2006-01-02", rule.StartDate)
+		d := start
+		step := 7 * rule.Interval
+		for d.Before(f) {
+			d = d.AddDate(0, 0, step)
+		}
+		return d.Format("2006-01-02")
+	case "last_business_day":
+		d := lastBusinessDay(f.Year(), f.Month())
+		if d.Before(f) {
+			d = lastBusinessDay(f.Year(), f.Month()+1)
+		}
+		return d.Format("2006-01-02")
+	default: // monthly
+		d := dayInMonth(f.Year(), f.Month(), rule.DayOfMonth)
+		if d.Before(f) {
+			d = dayInMonth(f.Year(), f.Month()+1, rule.DayOfMonth)
+		}
+		return d.Format("2006-01-02")
+	}
+}
+
+// nextOccurrence returns the occurrence that follows the given one.
+func nextOccurrence(rule models.RecurringTransaction, occurrence string) string {
+	o, _ := time.Parse("2006-01-02", occurrence)
+	switch rule.Frequency {
+	case "weekly":
+		return o.AddDate(0, 0, 7*rule.Interval).Format("2006-01-02")
+	case "last_business_day":
+		return lastBusinessDay(o.Year(), o.Month()+time.Month(rule.Interval)).Format("2006-01-02")
+	default: // monthly
+		return dayInMonth(o.Year(), o.Month()+time.Month(rule.Interval), rule.DayOfMonth).Format("2006-01-02")
+	}
+}
+
+// dayInMonth returns the given day of a month, clamped to the month's last
+// day. Months outside 1-12 are normalised.
+func dayInMonth(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	if day > last.Day() {
+		day = last.Day()
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// lastBusinessDay returns the last Monday-Friday of a month. Bank holidays
+// are not taken into account.
+func lastBusinessDay(year int, month time.Month) time.Time {
+	d := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, -1)
+	}
+	return d
+}
+
+func addDays(date string, days int) string {
+	t, _ := time.Parse("2006-01-02", date)
+	return t.AddDate(0, 0, days).Format("2006-01-02")
+}
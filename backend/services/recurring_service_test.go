@@ -0,0 +1,217 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/testutil"
+	"testing"
+)
+
+func setupRecurringTest(t *testing.T) (*RecurringService, *models.Account) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	svc := NewRecurringService(db, NewTransactionService(db))
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+
+	return svc, &account
+}
+
+func TestRecurringService_Schedules(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      models.RecurringTransaction
+		from      string
+		wantFirst string
+		wantNext  string
+	}{
+		{
+			name:      "monthly clamps to month end",
+			rule:      models.RecurringTransaction{Frequency: "monthly", Interval: 1, DayOfMonth: 31, StartDate: "2024-01-31"},
+			from:      "2024-01-31",
+			wantFirst: "2024-01-31",
+			wantNext:  "2024-02-29",
+		},
+		{
+			name:      "monthly rolls to next month when day has passed",
+			rule:      models.RecurringTransaction{Frequency: "monthly", Interval: 1, DayOfMonth: 1, StartDate: "2024-01-15"},
+			from:      "2024-01-15",
+			wantFirst: "2024-02-01",
+			wantNext:  "2024-03-01",
+		},
+		{
+			name:      "every two weeks",
+			rule:      models.RecurringTransaction{Frequency: "weekly", Interval: 2, StartDate: "2024-01-05"},
+			from:      "2024-01-10",
+			wantFirst: "2024-01-19",
+			wantNext:  "2024-02-02",
+		},
+		{
+			name:      "last business day skips weekend",
+			rule:      models.RecurringTransaction{Frequency: "last_business_day", Interval: 1, StartDate: "2024-03-01"},
+			from:      "2024-03-01",
+			wantFirst: "2024-03-29",
+			wantNext:  "2024-04-30",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := firstOccurrence(tt.rule, tt.from)
+			if first != tt.wantFirst {
+				t.Errorf("first occurrence: expected %s, got %s", tt.wantFirst, first)
+			}
+			next := nextOccurrence(tt.rule, first)
+			if next != tt.wantNext {
+				t.Errorf("next occurrence: expected %s, got %s", tt.wantNext, next)
+			}
+		})
+	}
+}
+
+func TestRecurringService_MaterializeIdempotent(t *testing.T) {
+	svc, account := setupRecurringTest(t)
+
+	rule, err := svc.Create(RecurringInput{
+		AccountID: account.ID, Amount: 120000, Description: "Rent", Type: "expense",
+		Frequency: "monthly", DayOfMonth: 1, StartDate: "2024-01-01",
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if rule.NextDate != "2024-01-01" {
+		t.Errorf("expected next date 2024-01-01, got %s", rule.NextDate)
+	}
+
+	posted, err := svc.Materialize("2024-03-15")
+	if err != nil {
+		t.Fatalf("materialize failed: %v", err)
+	}
+	if posted != 3 {
+		t.Errorf("expected 3 posted, got %d", posted)
+	}
+
+	// Simulate a crash before next_date was saved: rewind and run again
+	svc.db.Model(&models.RecurringTransaction{}).Where("id = ?", rule.ID).Update("next_date", "2024-01-01")
+	posted, err = svc.Materialize("2024-03-15")
+	if err != nil {
+		t.Fatalf("second materialize failed: %v", err)
+	}
+	if posted != 0 {
+		t.Errorf("expected 0 posted on rerun, got %d", posted)
+	}
+
+	var count int64
+	svc.db.Model(&models.Transaction{}).Where("recurring_id = ?", rule.ID).Count(&count)
+	if count != 3 {
+		t.Errorf("expected 3 transactions, got %d", count)
+	}
+	var check models.RecurringTransaction
+	svc.db.First(&check, rule.ID)
+	if check.NextDate != "2024-04-01" {
+		t.Errorf("expected next date 2024-04-01, got %s", check.NextDate)
+	}
+}
+
+func TestRecurringService_EndDate(t *testing.T) {
+	svc, account := setupRecurringTest(t)
+
+	end := "2024-02-15"
+	svc.Create(RecurringInput{
+		AccountID: account.ID, Amount: 999, Description: "Trial", Type: "expense",
+		Frequency: "weekly", StartDate: "2024-02-01", EndDate: &end,
+	})
+
+	posted, err := svc.Materialize("2024-03-31")
+	if err != nil {
+		t.Fatalf("materialize failed: %v", err)
+	}
+	// 1st, 8th and 15th of February
+	if posted != 3 {
+		t.Errorf("expected 3 posted, got %d", posted)
+	}
+}
+
+func TestRecurringService_UpdateDoesNotRepost(t *testing.T) {
+	svc, account := setupRecurringTest(t)
+
+	input := RecurringInput{
+		AccountID: account.ID, Amount: 250000, Description: "Salary", Type: "income",
+		Frequency: "last_business_day", StartDate: "2024-01-01",
+	}
+	rule, _ := svc.Create(input)
+	svc.Materialize("2024-02-10")
+
+	input.Amount = 260000
+	updated, err := svc.Update(rule.ID, input)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if updated.NextDate != "2024-02-29" {
+		t.Errorf("expected next date 2024-02-29, got %s", updated.NextDate)
+	}
+}
+
+func TestRecurringService_SkipsClosedAccounts(t *testing.T) {
+	svc, account := setupRecurringTest(t)
+
+	svc.Create(RecurringInput{
+		AccountID: account.ID, Amount: 999, Description: "Fee", Type: "expense",
+		Frequency: "monthly", StartDate: "2024-01-01",
+	})
+	closed := "2023-12-31"
+	svc.db.Model(account).Update("closed_date", closed)
+
+	posted, err := svc.Materialize("2024-03-31")
+	if err != nil {
+		t.Fatalf("materialize failed: %v", err)
+	}
+	if posted != 0 {
+		t.Errorf("expected nothing posted to a closed account, got %d", posted)
+	}
+}
+
+func TestRecurringService_BrokenRuleDoesNotBlockOthers(t *testing.T) {
+	svc, account := setupRecurringTest(t)
+	category := models.Category{Name: "Subscriptions", Colour: "#FF0000"}
+	svc.db.Create(&category)
+
+	broken, _ := svc.Create(RecurringInput{
+		AccountID: account.ID, CategoryID: &category.ID, Amount: 999, Description: "Streaming", Type: "expense",
+		Frequency: "monthly", StartDate: "2024-01-01",
+	})
+	svc.Create(RecurringInput{
+		AccountID: account.ID, Amount: 120000, Description: "Rent", Type: "expense",
+		Frequency: "monthly", StartDate: "2024-01-01",
+	})
+	// Leave the first rule pointing at a category that no longer exists
+	svc.db.Exec("PRAGMA foreign_keys = OFF")
+	svc.db.Model(&models.RecurringTransaction{}).Where("id = ?", broken.ID).UpdateColumn("category_id", 99)
+	svc.db.Exec("PRAGMA foreign_keys = ON")
+
+	posted, err := svc.Materialize("2024-02-15")
+	if err != nil {
+		t.Fatalf("materialize failed: %v", err)
+	}
+	if posted != 2 {
+		t.Errorf("expected the working rule's 2 occurrences posted, got %d", posted)
+	}
+	var check models.RecurringTransaction
+	svc.db.First(&check, broken.ID)
+	if check.NextDate != "2024-01-01" {
+		t.Errorf("expected the broken rule to stay due for a retry, got %s", check.NextDate)
+	}
+}
+
+func TestRecurringService_UnknownReferences(t *testing.T) {
+	svc, account := setupRecurringTest(t)
+	missing := uint(99)
+
+	input := RecurringInput{AccountID: missing, Amount: 999, Description: "Fee", Type: "expense", Frequency: "monthly", StartDate: "2024-01-01"}
+	if _, err := svc.Create(input); err != ErrRecurringReference {
+		t.Errorf("expected ErrRecurringReference for a missing account, got %v", err)
+	}
+	input.AccountID, input.CategoryID = account.ID, &missing
+	if _, err := svc.Create(input); err != ErrRecurringReference {
+		t.Errorf("expected ErrRecurringReference for a missing category, got %v", err)
+	}
+}
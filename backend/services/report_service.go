@@ -1,6 +1,11 @@
 package services
 
-import "gorm.io/gorm"
+import (
+	"budgetting-app/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type ReportService struct {
 	db *gorm.DB
@@ -14,6 +19,8 @@ type CategoryReport struct {
 	CategoryID   *uint   `json:"category_id"`
 	CategoryName *string `json:"category_name"`
 	Colour       *string `json:"colour"`
+	GroupID      *uint   `json:"group_id"`
+	GroupName    *string `json:"group_name"`
 	Total        int64   `json:"total"`
 	Count        int64   `json:"count"`
 }
@@ -30,23 +37,35 @@ type ReportParams struct {
 	DateFrom string
 	DateTo   string
 	Type     string
+	GroupBy  string // category (the default) | group; only ByCategory uses it
 }
 
+// ByCategory totals transactions per category, or per category group when
+// params.GroupBy is "group". Rolled up by group, the category fields are
+// empty, and uncategorized transactions share the row of categories without
+// a group.
 func (s *ReportService) ByCategory(params ReportParams) ([]CategoryReport, error) {
 	var results []CategoryReport
-	query := s.db.Table("transactions").
-		Select("transactions.category_id, categories.name as category_name, categories.colour, SUM(transactions.amount) as total, COUNT(*) as count").
-		Joins("LEFT JOIN categories ON categories.id = transactions.category_id").
-		Group("transactions.category_id")
+	query := s.db.Table(categoryLinesSQL).
+		Joins("LEFT JOIN categories ON categories.id = lines.category_id").
+		Joins("LEFT JOIN category_groups ON category_groups.id = categories.group_id").
+		Where("lines.transfer_id IS NULL AND lines.kind <> ?", "opening_balance")
+	if params.GroupBy == "group" {
+		query = query.Select("categories.group_id, category_groups.name as group_name, SUM(lines.amount) as total, COUNT(*) as count").
+			Group("categories.group_id")
+	} else {
+		query = query.Select("lines.category_id, categories.name as category_name, categories.colour, categories.group_id, category_groups.name as group_name, SUM(lines.amount) as total, COUNT(*) as count").
+			Group("lines.category_id")
+	}
 
 	if params.DateFrom != "" {
-		query = query.Where("transactions.date >= ?", params.DateFrom)
+		query = query.Where("lines.date >= ?", params.DateFrom)
 	}
 	if params.DateTo != "" {
-		query = query.Where("transactions.date <= ?", params.DateTo)
+		query = query.Where("lines.date <= ?", params.DateTo)
 	}
 	if params.Type != "" {
-		query = query.Where("transactions.type = ?", params.Type)
+		query = query.Where("lines.type = ?", params.Type)
 	}
 
 	err := query.Find(&results).Error
@@ -58,6 +77,7 @@ func (s *ReportService) ByAccount(params ReportParams) ([]AccountReport, error)
 	query := s.db.Table("transactions").
 		Select("transactions.account_id, accounts.name as account_name, accounts.type as account_type, SUM(transactions.amount) as total, COUNT(*) as count").
 		Joins("LEFT JOIN accounts ON accounts.id = transactions.account_id").
+		Where("transactions.transfer_id IS NULL AND transactions.kind <> ?", "opening_balance").
 		Group("transactions.account_id")
 
 	if params.DateFrom != "" {
@@ -73,3 +93,118 @@ func (s *ReportService) ByAccount(params ReportParams) ([]AccountReport, error)
 	err := query.Find(&results).Error
 	return results, err
 }
+
+// MaxNetWorthMonths caps the length of a net worth series.
+const MaxNetWorthMonths = 1200
+
+type NetWorthParams struct {
+	DateFrom string // YYYY-MM-DD; defaults to eleven months before DateTo
+	DateTo   string // YYYY-MM-DD; defaults to today
+}
+
+// NetWorthMonth is the position at the end of a month, or at DateTo for the
+// last month. Liabilities are the amounts owed on credit accounts, so a
+// card's negative balance counts as a positive liability.
+type NetWorthMonth struct {
+	Month       string            `json:"month"` // YYYY-MM
+	Assets      int64             `json:"assets"`
+	Liabilities int64             `json:"liabilities"`
+	NetWorth    int64             `json:"net_worth"`
+	Accounts    []NetWorthAccount `json:"accounts"`
+}
+
+type NetWorthAccount struct {
+	AccountID   uint   `json:"account_id"`
+	AccountName string `json:"account_name"`
+	AccountType string `json:"account_type"`
+	Kind        string `json:"kind"`    // asset | liability
+	Balance     int64  `json:"balance"` // the account's balance, negative when in debt
+}
+
+// NetWorth returns assets minus liabilities for every month from
+// params.DateFrom to params.DateTo, with each account's balance. Credit
+// accounts are liabilities and every other account type is an asset.
+// Transactions are summed per account and month in the database. A series
+// longer than MaxNetWorthMonths is refused with ErrRangeTooLarge.
+func (s *ReportService) NetWorth(params NetWorthParams) ([]NetWorthMonth, error) {
+	to := params.DateTo
+	if to == "" {
+		to = time.Now().Format("2006-01-02")
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Date(end.Year(), end.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+	if params.DateFrom != "" {
+		from, err := time.Parse("2006-01-02", params.DateFrom)
+		if err != nil {
+			return nil, err
+		}
+		start = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	months := 0
+	for t := start; !t.After(end); t = t.AddDate(0, 1, 0) {
+		if months++; months > MaxNetWorthMonths {
+			return nil, ErrRangeTooLarge
+		}
+	}
+
+	var accounts []models.Account
+	if err := s.db.Order("name, id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	signed := "SUM(CASE WHEN type = 'income' THEN amount ELSE -amount END)"
+	var opening []struct {
+		AccountID uint
+		Total     int64
+	}
+	if err := s.db.Model(&models.Transaction{}).Select("account_id, "+signed+" AS total").
+		Where("date < ?", start.Format("2006-01-02")).
+		Group("account_id").Scan(&opening).Error; err != nil {
+		return nil, err
+	}
+	var totals []struct {
+		AccountID uint
+		Month     string
+		Total     int64
+	}
+	if err := s.db.Model(&models.Transaction{}).Select("account_id, substr(date, 1, 7) AS month, "+signed+" AS total").
+		Where("date >= ? AND date <= ?", start.Format("2006-01-02"), to).
+		Group("account_id, month").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]int64, len(accounts))
+	for _, o := range opening {
+		balances[o.AccountID] = o.Total
+	}
+	type accountMonth struct {
+		accountID uint
+		month     string
+	}
+	byMonth := make(map[accountMonth]int64, len(totals))
+	for _, t := range totals {
+		byMonth[accountMonth{t.AccountID, t.Month}] = t.Total
+	}
+
+	series := make([]NetWorthMonth, 0, months)
+	for t := start; !t.After(end); t = t.AddDate(0, 1, 0) {
+		month := NetWorthMonth{Month: t.Format("2006-01"), Accounts: make([]NetWorthAccount, 0, len(accounts))}
+		for _, a := range accounts {
+			balances[a.ID] += byMonth[accountMonth{a.ID, month.Month}]
+			line := NetWorthAccount{AccountID: a.ID, AccountName: a.Name, AccountType: a.Type, Kind: "asset", Balance: balances[a.ID]}
+			if a.Type == "credit" {
+				line.Kind = "liability"
+				month.Liabilities -= line.Balance
+			} else {
+				month.Assets += line.Balance
+			}
+			month.Accounts = append(month.Accounts, line)
+		}
+		month.NetWorth = month.Assets - month.Liabilities
+		series = append(series, month)
+	}
+	return series, nil
+}
@@ -64,6 +64,36 @@ func TestReportService_ByCategory_Aggregation(t *testing.T) {
 	}
 }
 
+func TestReportService_ByCategory_Splits(t *testing.T) {
+	svc, account, category := setupReportTest(t)
+	household := models.Category{Name: "Household", Colour: "#00FF00"}
+	svc.db.Create(&household)
+
+	svc.db.Create(&models.Transaction{
+		AccountID: account.ID, Amount: 5000, Description: "Tesco",
+		Date: "2024-01-15", Type: "expense",
+		Splits: []models.TransactionSplit{
+			{CategoryID: &category.ID, Amount: 3000},
+			{CategoryID: &household.ID, Amount: 2000},
+		},
+	})
+
+	results, err := svc.ByCategory(ReportParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 category groups, got %d", len(results))
+	}
+	totals := map[uint]int64{}
+	for _, r := range results {
+		totals[*r.CategoryID] = r.Total
+	}
+	if totals[category.ID] != 3000 || totals[household.ID] != 2000 {
+		t.Errorf("expected split totals 3000/2000, got %v", totals)
+	}
+}
+
 func TestReportService_ByCategory_DateFilter(t *testing.T) {
 	svc, account, category := setupReportTest(t)
 
@@ -163,6 +193,34 @@ func TestReportService_ByAccount_Aggregation(t *testing.T) {
 	}
 }
 
+func TestReportService_ByAccount_ExcludesTransfers(t *testing.T) {
+	svc, account, category := setupReportTest(t)
+	savings := models.Account{Name: "Savings", Type: "savings"}
+	svc.db.Create(&savings)
+
+	svc.db.Create(&models.Transaction{
+		AccountID: account.ID, CategoryID: &category.ID,
+		Amount: 5000, Description: "Groceries",
+		Date: "2024-01-15", Type: "expense",
+	})
+	txnSvc := NewTransactionService(svc.db)
+	txnSvc.CreateTransfer(CreateTransferInput{
+		FromAccountID: account.ID, ToAccountID: savings.ID,
+		Amount: 20000, Description: "To savings", Date: "2024-01-20",
+	})
+
+	results, err := svc.ByAccount(ReportParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 account group, got %d", len(results))
+	}
+	if results[0].Total != 5000 {
+		t.Errorf("expected total 5000, got %d", results[0].Total)
+	}
+}
+
 func TestReportService_ByAccount_DateFilter(t *testing.T) {
 	svc, account, category := setupReportTest(t)
 
@@ -218,3 +276,89 @@ func TestReportService_UncategorizedTransactions(t *testing.T) {
 		t.Errorf("expected total 2000, got %d", results[0].Total)
 	}
 }
+
+func TestReportService_NetWorth(t *testing.T) {
+	svc, account, _ := setupReportTest(t)
+	card := models.Account{Name: "Card", Type: "credit"}
+	svc.db.Create(&card)
+
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 100000, Description: "Salary", Date: "2023-12-28", Type: "income"})
+	svc.db.Create(&models.Transaction{AccountID: card.ID, Amount: 20000, Description: "Shopping", Date: "2024-01-10", Type: "expense"})
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 5000, Description: "Bills", Date: "2024-03-02", Type: "expense"})
+	// After date_to, so not counted
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 7000, Description: "Later", Date: "2024-03-20", Type: "expense"})
+
+	months, err := svc.NetWorth(NetWorthParams{DateFrom: "2024-01-15", DateTo: "2024-03-15"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(months) != 3 {
+		t.Fatalf("expected 3 months, got %d", len(months))
+	}
+	want := []struct {
+		month                      string
+		assets, liabilities, worth int64
+	}{
+		{"2024-01", 100000, 20000, 80000},
+		{"2024-02", 100000, 20000, 80000},
+		{"2024-03", 95000, 20000, 75000},
+	}
+	for i, w := range want {
+		m := months[i]
+		if m.Month != w.month || m.Assets != w.assets || m.Liabilities != w.liabilities || m.NetWorth != w.worth {
+			t.Errorf("month %d: expected %+v, got %+v", i, w, m)
+		}
+	}
+	if len(months[0].Accounts) != 2 || months[0].Accounts[0].Kind != "liability" || months[0].Accounts[0].Balance != -20000 {
+		t.Errorf("unexpected account breakdown: %+v", months[0].Accounts)
+	}
+}
+
+func TestReportService_NetWorthRangeTooLarge(t *testing.T) {
+	svc, _, _ := setupReportTest(t)
+
+	_, err := svc.NetWorth(NetWorthParams{DateFrom: "1900-01-01", DateTo: "2024-01-01"})
+	if err != ErrRangeTooLarge {
+		t.Errorf("expected ErrRangeTooLarge, got %v", err)
+	}
+}
+
+func TestReportService_ByCategory_GroupRollup(t *testing.T) {
+	svc, account, category := setupReportTest(t)
+	group, _ := NewCategoryGroupService(svc.db).Create("Everyday")
+	eatingOut := models.Category{Name: "Eating Out", Colour: "#FF00FF", GroupID: &group.ID}
+	svc.db.Create(&eatingOut)
+	NewCategoryGroupService(svc.db).SetCategories(group.ID, []uint{category.ID, eatingOut.ID})
+	svc.db.Create(&models.Transaction{AccountID: account.ID, CategoryID: &category.ID, Amount: 5000, Description: "Groceries", Date: "2024-01-15", Type: "expense"})
+	svc.db.Create(&models.Transaction{AccountID: account.ID, CategoryID: &eatingOut.ID, Amount: 3000, Description: "Pizza", Date: "2024-01-20", Type: "expense"})
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 1000, Description: "Cash", Date: "2024-01-21", Type: "expense"})
+
+	results, err := svc.ByCategory(ReportParams{GroupBy: "group"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected the group and the ungrouped row, got %+v", results)
+	}
+	for _, r := range results {
+		if r.CategoryID != nil {
+			t.Errorf("expected no category on a group row, got %d", *r.CategoryID)
+		}
+		if r.GroupID == nil {
+			if r.Total != 1000 || r.Count != 1 {
+				t.Errorf("unexpected ungrouped row: %+v", r)
+			}
+			continue
+		}
+		if *r.GroupID != group.ID || *r.GroupName != "Everyday" || r.Total != 8000 || r.Count != 2 {
+			t.Errorf("unexpected group row: %+v", r)
+		}
+	}
+
+	results, _ = svc.ByCategory(ReportParams{})
+	for _, r := range results {
+		if r.CategoryID != nil && (r.GroupID == nil || *r.GroupID != group.ID) {
+			t.Errorf("expected category rows to carry their group, got %+v", r)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type RuleService struct {
+	db *gorm.DB
+}
+
+func NewRuleService(db *gorm.DB) *RuleService {
+	return &RuleService{db: db}
+}
+
+type RuleInput struct {
+	Name             string  `json:"name" binding:"required"`
+	Priority         int     `json:"priority"`
+	Enabled          *bool   `json:"enabled"`
+	DescriptionOp    *string `json:"description_op"`
+	DescriptionValue *string `json:"description_value"`
+	AmountMin        *int64  `json:"amount_min"`
+	AmountMax        *int64  `json:"amount_max"`
+	AccountID        *uint   `json:"account_id"`
+	Type             *string `json:"type"`
+	SetCategoryID    *uint   `json:"set_category_id"`
+	SetDescription   *string `json:"set_description"`
+	SetType          *string `json:"set_type"`
+}
+
+func (s *RuleService) List() ([]models.Rule, error) {
+	var rules []models.Rule
+	err := s.db.Order("priority, id").Find(&rules).Error
+	return rules, err
+}
+
+// Create adds a rule. It returns ErrRuleReference if the rule names a
+// category or account that doesn't exist.
+func (s *RuleService) Create(input RuleInput) (models.Rule, error) {
+	rule := models.Rule{}
+	if err := checkRuleReferences(s.db, input); err != nil {
+		return rule, err
+	}
+	applyRuleInput(&rule, input)
+	err := s.db.Create(&rule).Error
+	return rule, err
+}
+
+func (s *RuleService) Update(id uint, input RuleInput) (models.Rule, error) {
+	var rule models.Rule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return rule, err
+	}
+	if err := checkRuleReferences(s.db, input); err != nil {
+		return rule, err
+	}
+	applyRuleInput(&rule, input)
+	err := s.db.Save(&rule).Error
+	return rule, err
+}
+
+// checkRuleReferences makes sure the category and account a rule names
+// exist, since a rule that can't be applied would fail every transaction it
+// matches.
+func checkRuleReferences(db *gorm.DB, input RuleInput) error {
+	if input.SetCategoryID != nil {
+		if err := db.First(&models.Category{}, *input.SetCategoryID).Error; err == gorm.ErrRecordNotFound {
+			return ErrRuleReference
+		} else if err != nil {
+			return err
+		}
+	}
+	if input.AccountID != nil {
+		if err := db.First(&models.Account{}, *input.AccountID).Error; err == gorm.ErrRecordNotFound {
+			return ErrRuleReference
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RuleService) Delete(id uint) error {
+	var rule models.Rule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return err
+	}
+	return s.db.Delete(&rule).Error
+}
+
+// Reapply runs the enabled rules over every existing uncategorized
+// transaction and returns how many were changed. Reconciled rows and opening
+// balances are left alone.
+func (s *RuleService) Reapply() (int64, error) {
+	rules, err := loadRules(s.db)
+	if err != nil || len(rules) == 0 {
+		return 0, err
+	}
+
+	var changed int64
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var txns []models.Transaction
+		return tx.Where("category_id IS NULL AND transfer_id IS NULL AND status <> ? AND kind = ?", "reconciled", "regular").
+			Where("id NOT IN (SELECT transaction_id FROM transaction_splits)").
+			FindInBatches(&txns, 500, func(batch *gorm.DB, _ int) error {
+				for i := range txns {
+					if !applyRules(rules, &txns[i]) {
+						continue
+					}
+					if err := tx.Model(&txns[i]).Updates(map[string]interface{}{
+						"category_id": txns[i].CategoryID,
+						"description": txns[i].Description,
+						"type":        txns[i].Type,
+					}).Error; err != nil {
+						return err
+					}
+					changed++
+				}
+				return nil
+			}).Error
+	})
+	return changed, err
+}
+
+func applyRuleInput(rule *models.Rule, input RuleInput) {
+	rule.Name = input.Name
+	rule.Priority = input.Priority
+	rule.Enabled = input.Enabled == nil || *input.Enabled
+	rule.DescriptionOp = input.DescriptionOp
+	rule.DescriptionValue = input.DescriptionValue
+	rule.AmountMin = input.AmountMin
+	rule.AmountMax = input.AmountMax
+	rule.AccountID = input.AccountID
+	rule.Type = input.Type
+	rule.SetCategoryID = input.SetCategoryID
+	rule.SetDescription = input.SetDescription
+	rule.SetType = input.SetType
+}
+
+type compiledRule struct {
+	models.Rule
+	pattern *regexp.Regexp
+}
+
+// loadRules returns the enabled rules in the order they should run.
+func loadRules(db *gorm.DB) ([]compiledRule, error) {
+	var rules []models.Rule
+	if err := db.Where("enabled = ?", true).Order("priority, id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, r := range rules {
+		cr := compiledRule{Rule: r}
+		if r.DescriptionOp != nil && *r.DescriptionOp == "regex" && r.DescriptionValue != nil {
+			re, err := regexp.Compile("(?i)" + *r.DescriptionValue)
+			if err != nil {
+				// Validated on save; skip rather than fail every import
+				continue
+			}
+			cr.pattern = re
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, nil
+}
+
+// applyRules runs rules against an uncategorized transaction in priority
+// order. Each action is taken from the first matching rule that sets it, so a
+// higher-priority rule is never overridden by a later one. Transactions that
+// already have a category, splits, or belong to a transfer are left alone.
+// It reports whether the transaction was changed.
+func applyRules(rules []compiledRule, txn *models.Transaction) bool {
+	// A Category without an ID is one an import will create for the row
+	if txn.CategoryID != nil || txn.Category != nil || txn.TransferID != nil || len(txn.Splits) > 0 {
+		return false
+	}
+	var setCategory, setDescription, setType bool
+	changed := false
+	for _, r := range rules {
+		if !r.matches(txn) {
+			continue
+		}
+		if r.SetCategoryID != nil && !setCategory {
+			id := *r.SetCategoryID
+			txn.CategoryID = &id
+			setCategory = true
+			changed = true
+		}
+		if r.SetDescription != nil && !setDescription {
+			txn.Description = *r.SetDescription
+			setDescription = true
+			changed = true
+		}
+		if r.SetType != nil && !setType {
+			txn.Type = *r.SetType
+			setType = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (r compiledRule) matches(txn *models.Transaction) bool {
+	if r.AccountID != nil && *r.AccountID != txn.AccountID {
+		return false
+	}
+	if r.Type != nil && *r.Type != txn.Type {
+		return false
+	}
+	if r.AmountMin != nil && txn.Amount < *r.AmountMin {
+		return false
+	}
+	if r.AmountMax != nil && txn.Amount > *r.AmountMax {
+		return false
+	}
+	if r.DescriptionOp != nil && r.DescriptionValue != nil {
+		desc := strings.ToLower(txn.Description)
+		value := strings.ToLower(*r.DescriptionValue)
+		switch *r.DescriptionOp {
+		case "contains":
+			return strings.Contains(desc, value)
+		case "equals":
+			return strings.TrimSpace(desc) == strings.TrimSpace(value)
+		case "regex":
+			return r.pattern != nil && r.pattern.MatchString(txn.Description)
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/testutil"
+	"testing"
+)
+
+func setupRuleTest(t *testing.T) (*RuleService, *models.Account, *models.Category) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	svc := NewRuleService(db)
+
+	account := models.Account{Name: "Test", Type: "checking"}
+	db.Create(&account)
+	category := models.Category{Name: "Food", Colour: "#FF0000"}
+	db.Create(&category)
+
+	return svc, &account, &category
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestRuleService_Matching(t *testing.T) {
+	min, max := int64(1000), int64(5000)
+	tests := []struct {
+		name string
+		rule models.Rule
+		txn  models.Transaction
+		want bool
+	}{
+		{"contains is case-insensitive", models.Rule{DescriptionOp: strPtr("contains"), DescriptionValue: strPtr("tesco")}, models.Transaction{Description: "TESCO STORES 1234"}, true},
+		{"equals needs whole description", models.Rule{DescriptionOp: strPtr("equals"), DescriptionValue: strPtr("tesco")}, models.Transaction{Description: "Tesco Stores"}, false},
+		{"regex", models.Rule{DescriptionOp: strPtr("regex"), DescriptionValue: strPtr(`^amzn\s+mktp`)}, models.Transaction{Description: "AMZN Mktp UK"}, true},
+		{"amount in range", models.Rule{AmountMin: &min, AmountMax: &max}, models.Transaction{Amount: 5000}, true},
+		{"amount out of range", models.Rule{AmountMin: &min, AmountMax: &max}, models.Transaction{Amount: 5001}, false},
+		{"type", models.Rule{Type: strPtr("income")}, models.Transaction{Type: "expense"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Enabled = true
+			tt.rule.SetDescription = strPtr("matched")
+			db := testutil.SetupTestDB(t)
+			db.Create(&tt.rule)
+			rules, err := loadRules(db)
+			if err != nil {
+				t.Fatalf("load rules failed: %v", err)
+			}
+			if got := applyRules(rules, &tt.txn); got != tt.want {
+				t.Errorf("expected match %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRuleService_PriorityOrder(t *testing.T) {
+	svc, account, category := setupRuleTest(t)
+	other := models.Category{Name: "Shopping", Colour: "#00FF00"}
+	svc.db.Create(&other)
+
+	svc.Create(RuleInput{Name: "Generic", Priority: 10, DescriptionOp: strPtr("contains"), DescriptionValue: strPtr("tesco"), SetCategoryID: &other.ID})
+	svc.Create(RuleInput{Name: "Specific", Priority: 1, DescriptionOp: strPtr("contains"), DescriptionValue: strPtr("tesco express"), SetCategoryID: &category.ID, SetDescription: strPtr("Tesco")})
+
+	txnSvc := NewTransactionService(svc.db)
+	txn := models.Transaction{AccountID: account.ID, Amount: 500, Description: "TESCO EXPRESS 123", Date: "2024-01-15", Type: "expense"}
+	if err := txnSvc.Create(&txn); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if txn.CategoryID == nil || *txn.CategoryID != category.ID {
+		t.Errorf("expected higher-priority category %d, got %v", category.ID, txn.CategoryID)
+	}
+	if txn.Description != "Tesco" {
+		t.Errorf("expected rewritten description, got %q", txn.Description)
+	}
+}
+
+func TestRuleService_ExplicitCategoryWins(t *testing.T) {
+	svc, account, category := setupRuleTest(t)
+	other := models.Category{Name: "Shopping", Colour: "#00FF00"}
+	svc.db.Create(&other)
+	svc.Create(RuleInput{Name: "Tesco", DescriptionOp: strPtr("contains"), DescriptionValue: strPtr("tesco"), SetCategoryID: &category.ID})
+
+	txnSvc := NewTransactionService(svc.db)
+	txn := models.Transaction{AccountID: account.ID, CategoryID: &other.ID, Amount: 500, Description: "Tesco", Date: "2024-01-15", Type: "expense"}
+	txnSvc.Create(&txn)
+	if *txn.CategoryID != other.ID {
+		t.Errorf("expected chosen category to be kept, got %d", *txn.CategoryID)
+	}
+}
+
+func TestRuleService_DisabledRuleSkipped(t *testing.T) {
+	svc, account, category := setupRuleTest(t)
+	disabled := false
+	svc.Create(RuleInput{Name: "Tesco", Enabled: &disabled, DescriptionOp: strPtr("contains"), DescriptionValue: strPtr("tesco"), SetCategoryID: &category.ID})
+
+	txnSvc := NewTransactionService(svc.db)
+	txns := []models.Transaction{{AccountID: account.ID, Amount: 500, Description: "Tesco", Date: "2024-01-15", Type: "expense"}}
+	if _, err := txnSvc.ImportCSV(txns, ImportOptions{}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if txns[0].CategoryID != nil {
+		t.Error("expected disabled rule not to categorize")
+	}
+}
+
+func TestRuleService_Reapply(t *testing.T) {
+	svc, account, category := setupRuleTest(t)
+
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 500, Description: "Tesco", Date: "2024-01-15", Type: "expense"})
+	svc.db.Create(&models.Transaction{AccountID: account.ID, Amount: 900, Description: "Shell", Date: "2024-01-16", Type: "expense"})
+	locked := models.Transaction{AccountID: account.ID, Amount: 700, Description: "Tesco Express", Date: "2024-01-17", Type: "expense", Status: "reconciled"}
+	svc.db.Create(&locked)
+	svc.Create(RuleInput{Name: "Tesco", DescriptionOp: strPtr("contains"), DescriptionValue: strPtr("tesco"), SetCategoryID: &category.ID})
+
+	updated, err := svc.Reapply()
+	if err != nil {
+		t.Fatalf("reapply failed: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("expected 1 updated, got %d", updated)
+	}
+	var count int64
+	svc.db.Model(&models.Transaction{}).Where("category_id = ?", category.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 categorized transaction, got %d", count)
+	}
+	svc.db.First(&locked, locked.ID)
+	if locked.CategoryID != nil {
+		t.Error("expected the reconciled transaction to keep its category")
+	}
+}
+
+func TestRuleService_UnknownReferences(t *testing.T) {
+	svc, account, category := setupRuleTest(t)
+	missing := uint(99)
+
+	if _, err := svc.Create(RuleInput{Name: "Groceries", SetCategoryID: &missing}); err != ErrRuleReference {
+		t.Errorf("expected ErrRuleReference for a missing category, got %v", err)
+	}
+	if _, err := svc.Create(RuleInput{Name: "Groceries", AccountID: &missing, SetCategoryID: &category.ID}); err != ErrRuleReference {
+		t.Errorf("expected ErrRuleReference for a missing account, got %v", err)
+	}
+	rule, err := svc.Create(RuleInput{Name: "Groceries", AccountID: &account.ID, SetCategoryID: &category.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Update(rule.ID, RuleInput{Name: "Groceries", SetCategoryID: &missing}); err != ErrRuleReference {
+		t.Errorf("expected ErrRuleReference on update, got %v", err)
+	}
+}
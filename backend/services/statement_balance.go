@@ -0,0 +1,152 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// BalanceCheck compares the running balance column of an imported statement
+// with the account's transactions over the same days.
+type BalanceCheck struct {
+	Checked         int                `json:"checked"`         // rows that carried a balance
+	OpeningBalance  int64              `json:"opening_balance"` // the statement's balance before its first row
+	ClosingBalance  int64              `json:"closing_balance"` // the last balance on the statement
+	ClosingDate     string             `json:"closing_date"`
+	FirstDivergence *BalanceDivergence `json:"first_divergence"` // nil when every balance matched
+	CheckpointID    uint               `json:"checkpoint_id"`
+}
+
+// BalanceDivergence is the first row whose balance the account's
+// transactions don't reproduce.
+type BalanceDivergence struct {
+	Row      int    `json:"row"` // 1-based position within the import
+	Date     string `json:"date"`
+	Expected int64  `json:"expected"` // the bank's balance after the row
+	Computed int64  `json:"computed"` // the account's balance after the row
+}
+
+// statementRow is one imported row as the balance check sees it.
+type statementRow struct {
+	row     int
+	date    string
+	signed  int64  // positive for money in
+	balance *int64 // the bank's balance after the row, if given
+}
+
+// checkStatementBalances runs after an import's rows are inserted. Starting
+// from the statement's opening balance, it adds up the account's
+// transactions, both imported and already there, and compares the total with
+// the bank's balance after each row. A row missing from the account, or one
+// counted twice, shows up as the first divergence. Within a day only the
+// file's rows are counted until the day's last row, since other transactions
+// on the same day can't be placed among them. The closing balance is saved
+// as a reconciliation checkpoint. It returns nil if no row has a balance.
+func checkStatementBalances(tx *gorm.DB, accountID uint, batchID *uint, rows []statementRow) (*BalanceCheck, error) {
+	hasBalance := false
+	for _, r := range rows {
+		if r.balance != nil {
+			hasBalance = true
+			break
+		}
+	}
+	if !hasBalance {
+		return nil, nil
+	}
+
+	if statementNewestFirst(rows) {
+		reversed := make([]statementRow, len(rows))
+		for i, r := range rows {
+			reversed[len(rows)-1-i] = r
+		}
+		rows = reversed
+	}
+
+	check := &BalanceCheck{}
+	var prefix int64
+	for _, r := range rows {
+		prefix += r.signed
+		if r.balance != nil {
+			check.OpeningBalance = *r.balance - prefix
+			break
+		}
+	}
+
+	var days []struct {
+		Date  string
+		Total int64
+	}
+	if err := tx.Model(&models.Transaction{}).
+		Select("date, SUM(CASE WHEN type = 'income' THEN amount ELSE -amount END) AS total").
+		Where("account_id = ? AND date BETWEEN ? AND ?", accountID, rows[0].date, rows[len(rows)-1].date).
+		Group("date").Order("date").Scan(&days).Error; err != nil {
+		return nil, err
+	}
+
+	// dayStart is the computed balance before the current day, made up of
+	// the opening balance and the account's totals for days[:next]
+	dayStart, next := check.OpeningBalance, 0
+	var dayTotal, closingComputed int64
+	for i, r := range rows {
+		if i == 0 || r.date != rows[i-1].date {
+			for next < len(days) && days[next].Date < r.date {
+				dayStart += days[next].Total
+				next++
+			}
+			dayTotal = 0
+		}
+		dayTotal += r.signed
+		computed := dayStart + dayTotal
+		if i == len(rows)-1 || rows[i+1].date != r.date {
+			computed = dayStart
+			if next < len(days) && days[next].Date == r.date {
+				computed += days[next].Total
+			}
+		}
+		if r.balance == nil {
+			continue
+		}
+		check.Checked++
+		check.ClosingBalance, check.ClosingDate, closingComputed = *r.balance, r.date, computed
+		if computed != *r.balance && check.FirstDivergence == nil {
+			check.FirstDivergence = &BalanceDivergence{Row: r.row, Date: r.date, Expected: *r.balance, Computed: computed}
+		}
+	}
+
+	checkpoint := models.ReconciliationCheckpoint{
+		AccountID:     accountID,
+		ImportBatchID: batchID,
+		Date:          check.ClosingDate,
+		Balance:       check.ClosingBalance,
+		Difference:    closingComputed - check.ClosingBalance,
+	}
+	if err := tx.Create(&checkpoint).Error; err != nil {
+		return nil, err
+	}
+	check.CheckpointID = checkpoint.ID
+	return check, nil
+}
+
+// statementNewestFirst reports whether a statement lists its rows from the
+// newest. Dates decide when they differ; a statement within one day is
+// judged by which order its first two balances follow from.
+func statementNewestFirst(rows []statementRow) bool {
+	first, last := rows[0], rows[len(rows)-1]
+	if first.date != last.date {
+		return first.date > last.date
+	}
+	if len(rows) < 2 || rows[0].balance == nil || rows[1].balance == nil {
+		return false
+	}
+	oldestFirst := *rows[1].balance == *rows[0].balance+rows[1].signed
+	newestFirst := *rows[0].balance == *rows[1].balance+rows[0].signed
+	return newestFirst && !oldestFirst
+}
+
+// signedAmount returns a transaction's effect on its account's balance.
+func signedAmount(t models.Transaction) int64 {
+	if t.Type == "income" {
+		return t.Amount
+	}
+	return -t.Amount
+}
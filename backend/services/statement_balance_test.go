@@ -0,0 +1,131 @@
+package services
+
+import (
+	"budgetting-app/backend/models"
+	"budgetting-app/backend/testutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func importStatement(t *testing.T, svc *TransactionService, accountID uint, csv string) *ImportResult {
+	t.Helper()
+	txns, err := ParseCSV(strings.NewReader(csv), strconv.FormatUint(uint64(accountID), 10))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	result, err := svc.ImportCSV(txns, ImportOptions{Duplicates: "allow"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	return result
+}
+
+func TestImportCSV_BalanceCheckMatches(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewTransactionService(db)
+	account := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&account)
+
+	// Newest first, as many banks export
+	result := importStatement(t, svc, account.ID, `date,description,amount,balance
+2024-01-05,Salary,2000.00,2946.50
+2024-01-03,Cinema,-12.00,946.50
+2024-01-03,Coffee,-3.50,958.50
+2024-01-02,Groceries,-38.00,962.00`)
+
+	check := result.BalanceCheck
+	if check == nil {
+		t.Fatal("expected a balance check")
+	}
+	if check.FirstDivergence != nil {
+		t.Fatalf("expected no divergence, got %+v", check.FirstDivergence)
+	}
+	if check.Checked != 4 || check.OpeningBalance != 100000 || check.ClosingBalance != 294650 || check.ClosingDate != "2024-01-05" {
+		t.Errorf("unexpected check: %+v", check)
+	}
+
+	var checkpoint models.ReconciliationCheckpoint
+	db.First(&checkpoint, check.CheckpointID)
+	if checkpoint.AccountID != account.ID || checkpoint.Balance != 294650 || checkpoint.Date != "2024-01-05" || checkpoint.Difference != 0 {
+		t.Errorf("unexpected checkpoint: %+v", checkpoint)
+	}
+	if checkpoint.ImportBatchID == nil || *checkpoint.ImportBatchID != *result.BatchID {
+		t.Errorf("expected checkpoint to belong to the import's batch")
+	}
+}
+
+func TestImportCSV_BalanceCheckMissingRow(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewTransactionService(db)
+	account := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&account)
+
+	// A 20.00 payment between the second and third rows is missing
+	result := importStatement(t, svc, account.ID, `date,description,amount,balance
+2024-01-02,Groceries,-38.00,962.00
+2024-01-03,Coffee,-3.50,958.50
+2024-01-05,Salary,2000.00,2938.50`)
+
+	d := result.BalanceCheck.FirstDivergence
+	if d == nil || d.Row != 3 || d.Date != "2024-01-05" || d.Expected != 293850 || d.Computed != 295850 {
+		t.Fatalf("expected a divergence on row 3, got %+v", d)
+	}
+	var checkpoint models.ReconciliationCheckpoint
+	db.First(&checkpoint, result.BalanceCheck.CheckpointID)
+	if checkpoint.Difference != 2000 {
+		t.Errorf("expected the checkpoint to record a 2000 difference, got %d", checkpoint.Difference)
+	}
+}
+
+func TestImportCSV_BalanceCheckExistingDuplicate(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewTransactionService(db)
+	account := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&account)
+	// Entered by hand before the statement arrived, then imported again
+	db.Create(&models.Transaction{AccountID: account.ID, Amount: 350, Description: "Coffee shop", Date: "2024-01-03", Type: "expense"})
+
+	result := importStatement(t, svc, account.ID, `date,description,amount,balance
+2024-01-02,Groceries,-38.00,962.00
+2024-01-03,Coffee,-3.50,958.50
+2024-01-04,Lunch,-8.00,950.50`)
+
+	d := result.BalanceCheck.FirstDivergence
+	if d == nil || d.Row != 2 || d.Computed != 95500 {
+		t.Fatalf("expected the duplicated row to diverge, got %+v", d)
+	}
+}
+
+func TestImportCSV_NoBalanceColumn(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewTransactionService(db)
+	account := models.Account{Name: "Current", Type: "checking"}
+	db.Create(&account)
+
+	result := importStatement(t, svc, account.ID, "date,description,amount\n2024-01-02,Groceries,-38.00\n")
+	if result.BalanceCheck != nil {
+		t.Errorf("expected no balance check, got %+v", result.BalanceCheck)
+	}
+	var count int64
+	db.Model(&models.ReconciliationCheckpoint{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no checkpoint, got %d", count)
+	}
+}
+
+func TestImportBatchService_RollbackRemovesCheckpoint(t *testing.T) {
+	batches, txns, account := setupImportBatchTest(t)
+	result := importStatement(t, txns, account.ID, "date,description,amount,balance\n2024-01-02,Groceries,-38.00,962.00\n")
+
+	if _, err := batches.Rollback(*result.BatchID, false); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	checkpoints, err := NewAccountService(batches.db).Checkpoints(account.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(checkpoints) != 0 {
+		t.Errorf("expected the checkpoint to be rolled back, got %+v", checkpoints)
+	}
+}
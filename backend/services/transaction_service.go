@@ -2,7 +2,12 @@ package services
 
 import (
 	"budgetting-app/backend/models"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
 	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -22,6 +27,18 @@ type UpdateTransactionInput struct {
 	Description *string `json:"description"`
 	Date        *string `json:"date"`
 	Type        *string `json:"type"`
+	Status      *string `json:"status"` // uncleared | cleared; reconciling is done per account
+	// Splits replaces the transaction's split lines when non-nil; an empty
+	// slice removes them.
+	Splits *[]models.TransactionSplit `json:"splits"`
+}
+
+type CreateTransferInput struct {
+	FromAccountID uint   `json:"from_account_id" binding:"required"`
+	ToAccountID   uint   `json:"to_account_id" binding:"required"`
+	Amount        int64  `json:"amount"`
+	Description   string `json:"description"`
+	Date          string `json:"date" binding:"required"`
 }
 
 type TransactionListParams struct {
@@ -36,17 +53,97 @@ type TransactionListParams struct {
 
 const MaxPageSize = 200
 
+// List returns a page of transactions matching params, newest first, and the
+// total number that match. When filtered to one account, each row carries the
+// account's running balance after it, counting every transaction in the
+// account in date then ID order whatever the other filters.
 func (s *TransactionService) List(params TransactionListParams) ([]models.Transaction, int64, error) {
-	query := s.db.Preload("Account").Preload("Category").Order("date DESC")
+	query := applyListFilters(s.db.Preload("Account").Preload("Category").Preload("Splits.Category").Order("date DESC, id DESC"), params)
+
+	var total int64
+	query.Model(&models.Transaction{}).Count(&total)
+
+	if params.Limit > 0 {
+		limit := params.Limit
+		if limit > MaxPageSize {
+			limit = MaxPageSize
+		}
+		query = query.Limit(limit)
+	}
+	if params.Offset > 0 {
+		query = query.Offset(params.Offset)
+	}
 
+	var transactions []models.Transaction
+	if err := query.Find(&transactions).Error; err != nil {
+		return nil, 0, err
+	}
+	if params.AccountID != "" && len(transactions) > 0 {
+		if err := s.setRunningBalances(params.AccountID, transactions); err != nil {
+			return nil, 0, err
+		}
+	}
+	return transactions, total, nil
+}
+
+// setRunningBalances fills in RunningBalance for a page of one account's
+// transactions. The window runs over the whole account, using the
+// (account_id, date) index, but only the page's rows are returned.
+func (s *TransactionService) setRunningBalances(accountID string, transactions []models.Transaction) error {
+	ids := make([]uint, len(transactions))
+	for i, t := range transactions {
+		ids[i] = t.ID
+	}
+	var balances []struct {
+		ID             uint
+		RunningBalance int64
+	}
+	if err := s.db.Raw(`SELECT id, running_balance FROM (
+			SELECT id, SUM(CASE WHEN type = 'income' THEN amount ELSE -amount END) OVER (ORDER BY date, id) AS running_balance
+			FROM transactions WHERE account_id = ?
+		) WHERE id IN ?`, accountID, ids).Scan(&balances).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]int64, len(balances))
+	for _, b := range balances {
+		byID[b.ID] = b.RunningBalance
+	}
+	for i := range transactions {
+		if balance, ok := byID[transactions[i].ID]; ok {
+			transactions[i].RunningBalance = &balance
+		}
+	}
+	return nil
+}
+
+// ExportQIF writes every transaction matching the list filters as QIF,
+// oldest first. Limit and offset are ignored.
+func (s *TransactionService) ExportQIF(w io.Writer, params TransactionListParams) error {
+	var transactions []models.Transaction
+	if err := applyListFilters(s.db.Preload("Category").Preload("Splits.Category").Order("date, id"), params).
+		Find(&transactions).Error; err != nil {
+		return err
+	}
+	var accounts []models.Account
+	if err := s.db.Find(&accounts).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Account, len(accounts))
+	for _, a := range accounts {
+		byID[a.ID] = a
+	}
+	return writeQIF(w, transactions, byID)
+}
+
+func applyListFilters(query *gorm.DB, params TransactionListParams) *gorm.DB {
 	if params.AccountID != "" {
 		query = query.Where("account_id = ?", params.AccountID)
 	}
 	if params.CategoryID != "" {
 		if params.CategoryID == "none" {
-			query = query.Where("category_id IS NULL")
+			query = query.Where("category_id IS NULL AND transfer_id IS NULL AND id NOT IN (SELECT transaction_id FROM transaction_splits)")
 		} else {
-			query = query.Where("category_id = ?", params.CategoryID)
+			query = query.Where("category_id = ? OR id IN (SELECT transaction_id FROM transaction_splits WHERE category_id = ?)", params.CategoryID, params.CategoryID)
 		}
 	}
 	if params.DateFrom != "" {
@@ -58,43 +155,101 @@ func (s *TransactionService) List(params TransactionListParams) ([]models.Transa
 	if params.Search != "" {
 		query = query.Where("description LIKE ?", "%"+params.Search+"%")
 	}
+	return query
+}
 
-	var total int64
-	query.Model(&models.Transaction{}).Count(&total)
-
-	if params.Limit > 0 {
-		limit := params.Limit
-		if limit > MaxPageSize {
-			limit = MaxPageSize
+// Create saves a transaction. When it arrives without a category, the
+// categorization rules are applied first.
+func (s *TransactionService) Create(txn *models.Transaction) error {
+	if err := checkAccountsOpen(s.db, txn.AccountID); err != nil {
+		return err
+	}
+	if txn.CategoryID == nil && txn.TransferID == nil && len(txn.Splits) == 0 {
+		rules, err := loadRules(s.db)
+		if err != nil {
+			return err
 		}
-		query = query.Limit(limit)
+		applyRules(rules, txn)
 	}
-	if params.Offset > 0 {
-		query = query.Offset(params.Offset)
+	if len(txn.Splits) > 0 {
+		if err := validateSplits(txn.Amount, txn.Splits); err != nil {
+			return err
+		}
+		if err := checkSplitCategories(s.db, txn.Splits); err != nil {
+			return err
+		}
+		// A split transaction is categorized by its lines, not the parent
+		txn.CategoryID = nil
+		for i := range txn.Splits {
+			txn.Splits[i].ID = 0
+		}
 	}
-
-	var transactions []models.Transaction
-	err := query.Find(&transactions).Error
-	return transactions, total, err
-}
-
-func (s *TransactionService) Create(txn *models.Transaction) error {
 	if err := s.db.Create(txn).Error; err != nil {
 		return err
 	}
-	return s.db.Preload("Account").Preload("Category").First(txn, txn.ID).Error
+	return s.db.Preload("Account").Preload("Category").Preload("Splits.Category").First(txn, txn.ID).Error
 }
 
+// Update applies the fields set in input. A reconciled transaction keeps its
+// amount, date, account, type and status, returning ErrTransactionReconciled,
+// until it is unlocked; its description and category can still be changed.
 func (s *TransactionService) Update(id uint, input UpdateTransactionInput) (models.Transaction, error) {
 	var txn models.Transaction
 	if err := s.db.First(&txn, id).Error; err != nil {
 		return txn, err
 	}
+	if txn.TransferID != nil {
+		return s.updateTransfer(txn, input)
+	}
+	if txn.Status == "reconciled" && changesReconciledFields(txn, input) {
+		return txn, ErrTransactionReconciled
+	}
+	if input.AccountID != nil && *input.AccountID != txn.AccountID {
+		if err := checkAccountsOpen(s.db, *input.AccountID); err != nil {
+			return txn, err
+		}
+	}
+
+	var splitCount int64
+	if err := s.db.Model(&models.TransactionSplit{}).Where("transaction_id = ?", txn.ID).Count(&splitCount).Error; err != nil {
+		return txn, err
+	}
+	amount := txn.Amount
+	if input.Amount != nil {
+		amount = *input.Amount
+	}
+	replaceSplits := input.Splits != nil
+	if replaceSplits && len(*input.Splits) > 0 {
+		if err := validateSplits(amount, *input.Splits); err != nil {
+			return txn, err
+		}
+		if err := checkSplitCategories(s.db, *input.Splits); err != nil {
+			return txn, err
+		}
+	} else if !replaceSplits && splitCount > 0 {
+		if input.CategoryID != nil {
+			// Setting a single category collapses the split
+			empty := []models.TransactionSplit{}
+			input.Splits = &empty
+			replaceSplits = true
+		} else if input.Amount != nil {
+			var splits []models.TransactionSplit
+			if err := s.db.Where("transaction_id = ?", txn.ID).Find(&splits).Error; err != nil {
+				return txn, err
+			}
+			if err := validateSplits(amount, splits); err != nil {
+				return txn, err
+			}
+		}
+	}
 
-	updates := map[string]interface{}{}
+	updates := map[string]interface{}{"edited_at": time.Now()}
 	if input.CategoryID != nil {
 		updates["category_id"] = input.CategoryID
 	}
+	if replaceSplits && len(*input.Splits) > 0 {
+		updates["category_id"] = nil
+	}
 	if input.AccountID != nil {
 		updates["account_id"] = *input.AccountID
 	}
@@ -110,43 +265,529 @@ func (s *TransactionService) Update(id uint, input UpdateTransactionInput) (mode
 	if input.Type != nil {
 		updates["type"] = *input.Type
 	}
+	if input.Status != nil {
+		updates["status"] = *input.Status
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&txn).Updates(updates).Error; err != nil {
+			return err
+		}
+		if !replaceSplits {
+			return nil
+		}
+		if err := tx.Where("transaction_id = ?", txn.ID).Delete(&models.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		if len(*input.Splits) == 0 {
+			return nil
+		}
+		splits := *input.Splits
+		for i := range splits {
+			splits[i].ID = 0
+			splits[i].TransactionID = txn.ID
+		}
+		return tx.Create(&splits).Error
+	})
+	if err != nil {
+		return txn, err
+	}
+	err = s.db.Preload("Account").Preload("Category").Preload("Splits.Category").First(&txn, txn.ID).Error
+	return txn, err
+}
+
+// updateTransfer applies an update to one leg of a transfer. Amount, date and
+// description are shared, so they are written to both legs; the account only
+// moves this leg and the other leg's transfer_account_id follows it.
+func (s *TransactionService) updateTransfer(txn models.Transaction, input UpdateTransactionInput) (models.Transaction, error) {
+	if input.Type != nil && *input.Type != txn.Type {
+		return txn, ErrTransferTypeChange
+	}
+	if input.CategoryID != nil || input.Splits != nil {
+		return txn, ErrTransferCategory
+	}
+
+	if txn.Status == "reconciled" && (changesReconciledFields(txn, input) || (input.AccountID != nil && *input.AccountID != txn.AccountID)) {
+		return txn, ErrTransactionReconciled
+	}
 
-	if err := s.db.Model(&txn).Updates(updates).Error; err != nil {
+	shared := map[string]interface{}{"edited_at": time.Now()}
+	if input.Amount != nil {
+		shared["amount"] = *input.Amount
+	}
+	if input.Description != nil {
+		shared["description"] = *input.Description
+	}
+	if input.Date != nil {
+		shared["date"] = *input.Date
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var peer models.Transaction
+		if err := tx.Where("transfer_id = ? AND id <> ?", *txn.TransferID, txn.ID).First(&peer).Error; err != nil {
+			return err
+		}
+		// The amount and date are shared, so a reconciled other leg keeps them
+		if peer.Status == "reconciled" && ((input.Amount != nil && *input.Amount != peer.Amount) || (input.Date != nil && *input.Date != peer.Date)) {
+			return ErrTransactionReconciled
+		}
+		if input.Status != nil {
+			if err := tx.Model(&txn).Update("status", *input.Status).Error; err != nil {
+				return err
+			}
+		}
+		if input.AccountID != nil && *input.AccountID != txn.AccountID {
+			if *input.AccountID == peer.AccountID {
+				return ErrTransferSameAccount
+			}
+			var account models.Account
+			if err := tx.First(&account, *input.AccountID).Error; err != nil {
+				return err
+			}
+			if account.ClosedDate != nil {
+				return ErrAccountClosed
+			}
+			if err := tx.Model(&txn).Update("account_id", account.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&peer).Update("transfer_account_id", account.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Transaction{}).Where("transfer_id = ?", *txn.TransferID).Updates(shared).Error
+	})
+	if err != nil {
+		return txn, err
+	}
+	err = s.db.Preload("Account").Preload("Category").Preload("Splits.Category").First(&txn, txn.ID).Error
+	return txn, err
+}
+
+// changesReconciledFields reports whether input would change a field that a
+// reconciled transaction keeps: one that moves its account's balance, or its
+// status.
+func changesReconciledFields(txn models.Transaction, input UpdateTransactionInput) bool {
+	return (input.Amount != nil && *input.Amount != txn.Amount) ||
+		(input.Date != nil && *input.Date != txn.Date) ||
+		(input.AccountID != nil && *input.AccountID != txn.AccountID && txn.TransferID == nil) ||
+		(input.Type != nil && *input.Type != txn.Type) ||
+		(input.Status != nil && *input.Status != txn.Status)
+}
+
+// Unlock returns a reconciled transaction to cleared so that it can be
+// edited or deleted. Other transactions are returned unchanged.
+func (s *TransactionService) Unlock(id uint) (models.Transaction, error) {
+	var txn models.Transaction
+	if err := s.db.First(&txn, id).Error; err != nil {
 		return txn, err
 	}
-	err := s.db.Preload("Account").Preload("Category").First(&txn, txn.ID).Error
+	if txn.Status == "reconciled" {
+		if err := s.db.Model(&txn).Update("status", "cleared").Error; err != nil {
+			return txn, err
+		}
+	}
+	err := s.db.Preload("Account").Preload("Category").Preload("Splits.Category").First(&txn, txn.ID).Error
 	return txn, err
 }
 
+// Delete removes a transaction, or both legs of a transfer. Reconciled
+// transactions are refused with ErrTransactionReconciled until unlocked.
 func (s *TransactionService) Delete(id uint) error {
 	var txn models.Transaction
 	if err := s.db.First(&txn, id).Error; err != nil {
 		return err
 	}
-	return s.db.Delete(&txn).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var reconciled int64
+		locked := tx.Model(&models.Transaction{}).Where("status = ?", "reconciled")
+		if txn.TransferID != nil {
+			locked = locked.Where("transfer_id = ?", *txn.TransferID)
+		} else {
+			locked = locked.Where("id = ?", txn.ID)
+		}
+		if err := locked.Count(&reconciled).Error; err != nil {
+			return err
+		}
+		if reconciled > 0 {
+			return ErrTransactionReconciled
+		}
+		if txn.TransferID != nil {
+			// Deleting either leg removes the whole transfer
+			return tx.Where("transfer_id = ?", *txn.TransferID).Delete(&models.Transaction{}).Error
+		}
+		if err := tx.Where("transaction_id = ?", txn.ID).Delete(&models.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&txn).Error
+	})
+}
+
+// CreateTransfer records a movement of money between two accounts as a linked
+// pair: an expense leg on the source account and an income leg on the
+// destination, sharing a transfer ID. Transfers are excluded from budget and
+// report totals, except that one to or from a tracking account moves money
+// out of or into the budget.
+func (s *TransactionService) CreateTransfer(input CreateTransferInput) ([]models.Transaction, error) {
+	if input.FromAccountID == input.ToAccountID {
+		return nil, ErrTransferSameAccount
+	}
+	transferID, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := input.FromAccountID, input.ToAccountID
+	legs := []models.Transaction{
+		{
+			AccountID:         from,
+			Amount:            input.Amount,
+			Description:       input.Description,
+			Date:              input.Date,
+			Type:              "expense",
+			TransferID:        &transferID,
+			TransferAccountID: &to,
+		},
+		{
+			AccountID:         to,
+			Amount:            input.Amount,
+			Description:       input.Description,
+			Date:              input.Date,
+			Type:              "income",
+			TransferID:        &transferID,
+			TransferAccountID: &from,
+		},
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Account{}).Where("id IN ?", []uint{from, to}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != 2 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := checkAccountsOpen(tx, from, to); err != nil {
+			return err
+		}
+		return tx.Create(&legs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Preload("Account").Preload("Category").Where("transfer_id = ?", transferID).Order("id").Find(&legs).Error
+	return legs, err
 }
 
+// BulkUpdateCategory assigns a single category to each transaction, collapsing
+// any existing splits.
 func (s *TransactionService) BulkUpdateCategory(transactionIDs []uint, categoryID *uint) (int64, error) {
-	result := s.db.Model(&models.Transaction{}).
-		Where("id IN ?", transactionIDs).
-		Update("category_id", categoryID)
-	return result.RowsAffected, result.Error
+	var affected int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("transaction_id IN (?)", tx.Model(&models.Transaction{}).Select("id").Where("id IN ? AND transfer_id IS NULL", transactionIDs)).
+			Delete(&models.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.Transaction{}).
+			Where("id IN ? AND transfer_id IS NULL", transactionIDs).
+			Updates(map[string]interface{}{"category_id": categoryID, "edited_at": time.Now()})
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
 }
 
-func (s *TransactionService) ImportCSV(transactions []models.Transaction) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		batchSize := 100
-		for i := 0; i < len(transactions); i += batchSize {
-			end := i + batchSize
-			if end > len(transactions) {
-				end = len(transactions)
-			}
-			if err := tx.Create(transactions[i:end]).Error; err != nil {
+type ImportOptions struct {
+	// Duplicates controls rows that match an existing transaction:
+	// "skip" (default) drops them, "flag" imports them marked with
+	// duplicate_of_id, and "allow" imports them unmarked.
+	Duplicates string
+	// DuplicateWindowDays lets a duplicate's date differ by up to this many
+	// days, for banks that shift posting dates between exports.
+	DuplicateWindowDays int
+	// Filename is the uploaded file's name, recorded on the import batch.
+	Filename string
+	// BatchID adds the rows to an existing batch instead of starting a new
+	// one, for imports committed in several chunks. Rows already in the batch
+	// are not treated as duplicates of the new ones.
+	BatchID *uint
+	// Rejected holds the rows a lenient parse left out. They are recorded on
+	// the new import batch so they can be downloaded, fixed and re-uploaded.
+	// It is not used together with BatchID.
+	Rejected *RejectedRows
+	// Chunked marks one chunk of a larger import. Checks that need the whole
+	// file, such as the statement balance check, are skipped and left to the
+	// caller to run once every chunk is in.
+	Chunked bool
+}
+
+type ImportRowResult struct {
+	Row           int    `json:"row"`    // 1-based position within the import
+	Status        string `json:"status"` // imported | skipped | flagged
+	TransactionID *uint  `json:"transaction_id,omitempty"`
+	DuplicateOfID *uint  `json:"duplicate_of_id,omitempty"`
+}
+
+type ImportResult struct {
+	Imported int               `json:"imported"`
+	Skipped  int               `json:"skipped"`
+	Flagged  int               `json:"flagged"`
+	Rejected int               `json:"rejected"`
+	BatchID  *uint             `json:"batch_id,omitempty"` // nil when nothing was imported or rejected
+	Rows     []ImportRowResult `json:"rows"`
+	Errors   []ImportRowError  `json:"errors,omitempty"` // why each rejected row was left out
+	// BalanceCheck is set when the file had a running balance column
+	BalanceCheck *BalanceCheck `json:"balance_check,omitempty"`
+}
+
+// ImportCSV applies categorization rules to parsed rows, checks them against
+// existing transactions for likely duplicates, and inserts the rest in a
+// single database transaction. Flagged rows are inserted and counted in both
+// Imported and Flagged. Rows whose bank ID is already recorded for the
+// account are always skipped. The inserted rows are tagged with a new import
+// batch so they can be rolled back together; rows rejected by a lenient parse
+// are recorded on the same batch. When the rows carry the bank's running
+// balance, the result is checked against it and the closing balance recorded
+// as a reconciliation checkpoint.
+func (s *TransactionService) ImportCSV(transactions []models.Transaction, opts ImportOptions) (*ImportResult, error) {
+	rules, err := loadRules(s.db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range transactions {
+		applyRules(rules, &transactions[i])
+	}
+
+	result := &ImportResult{Rows: make([]ImportRowResult, len(transactions))}
+	if opts.Rejected != nil {
+		result.Rejected, result.Errors = len(opts.Rejected.Errors), opts.Rejected.Errors
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkAccountsOpen(tx, importAccountIDs(transactions)...); err != nil {
+			return err
+		}
+		existing := tx
+		if opts.BatchID != nil {
+			existing = tx.Where("COALESCE(import_batch_id, 0) <> ?", *opts.BatchID).Session(&gorm.Session{})
+		}
+		// Rows the bank has already given us are skipped whatever the mode
+		exact, err := findExternalDuplicates(existing, transactions)
+		if err != nil {
+			return err
+		}
+		duplicates := make([]*uint, len(transactions))
+		if opts.Duplicates != "allow" {
+			if duplicates, err = findDuplicates(existing, transactions, opts.DuplicateWindowDays); err != nil {
 				return err
 			}
 		}
-		return nil
+
+		var toInsert []*models.Transaction
+		for i := range transactions {
+			row := ImportRowResult{Row: i + 1, Status: "imported", DuplicateOfID: duplicates[i]}
+			if exact[i] != nil {
+				row.Status = "skipped"
+				row.DuplicateOfID = exact[i]
+			} else if duplicates[i] != nil {
+				if opts.Duplicates == "flag" {
+					row.Status = "flagged"
+					transactions[i].DuplicateOfID = duplicates[i]
+				} else {
+					row.Status = "skipped"
+				}
+			}
+			if row.Status != "skipped" {
+				// The bank has the row, so it has cleared
+				transactions[i].Status = "cleared"
+				toInsert = append(toInsert, &transactions[i])
+			}
+			result.Rows[i] = row
+		}
+
+		if err := createImportedCategories(tx, toInsert); err != nil {
+			return err
+		}
+		if result.BatchID, err = recordImportBatch(tx, opts.BatchID, opts.Filename, opts.Rejected, toInsert); err != nil {
+			return err
+		}
+		if err := createInBatches(tx, toInsert); err != nil {
+			return err
+		}
+		if opts.Chunked || len(transactions) == 0 {
+			return nil
+		}
+		rows := make([]statementRow, len(transactions))
+		for i, t := range transactions {
+			rows[i] = statementRow{row: i + 1, date: t.Date, signed: signedAmount(t), balance: t.StatementBalance}
+		}
+		result.BalanceCheck, err = checkStatementBalances(tx, transactions[0].AccountID, result.BatchID, rows)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Rows {
+		switch result.Rows[i].Status {
+		case "skipped":
+			result.Skipped++
+			continue
+		case "flagged":
+			result.Flagged++
+		}
+		result.Imported++
+		id := transactions[i].ID
+		result.Rows[i].TransactionID = &id
+	}
+	return result, nil
+}
+
+// ImportedCategoryColour is given to categories created by an import.
+const ImportedCategoryColour = "#94A3B8"
+
+// ResolveCategoryNames maps the category names a parser left in each
+// transaction's and split's Category to category IDs, matching names
+// case-insensitively, without writing anything. Unknown names are kept as a
+// Category with no ID when create is set, for the import to create when it
+// commits, and otherwise dropped, leaving the row for the rules to
+// categorize. Matched names have their Category cleared so it isn't saved as
+// a new category on insert.
+func (s *TransactionService) ResolveCategoryNames(transactions []models.Transaction, create bool) error {
+	var categories []models.Category
+	if err := s.db.Find(&categories).Error; err != nil {
+		return err
+	}
+	ids := make(map[string]uint, len(categories))
+	for _, c := range categories {
+		ids[strings.ToLower(c.Name)] = c.ID
+	}
+
+	resolve := func(category *models.Category) (*uint, *models.Category) {
+		if category == nil || category.Name == "" {
+			return nil, nil
+		}
+		if id, ok := ids[strings.ToLower(category.Name)]; ok {
+			return &id, nil
+		}
+		if !create {
+			return nil, nil
+		}
+		return nil, &models.Category{Name: category.Name}
+	}
+
+	for i := range transactions {
+		t := &transactions[i]
+		t.CategoryID, t.Category = resolve(t.Category)
+		for j := range t.Splits {
+			t.Splits[j].CategoryID, t.Splits[j].Category = resolve(t.Splits[j].Category)
+		}
+	}
+	return nil
+}
+
+// createImportedCategories creates the categories that ResolveCategoryNames
+// left pending on rows about to be inserted, inside the import's database
+// transaction, and points the rows and their splits at them.
+func createImportedCategories(tx *gorm.DB, transactions []*models.Transaction) error {
+	ids := make(map[string]uint)
+	resolve := func(category *models.Category) (*uint, error) {
+		key := strings.ToLower(category.Name)
+		if id, ok := ids[key]; ok {
+			return &id, nil
+		}
+		// Another import may have created it since the file was parsed
+		var existing []models.Category
+		if err := tx.Where("LOWER(name) = ?", key).Limit(1).Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		if len(existing) == 0 {
+			existing = append(existing, models.Category{Name: category.Name, Colour: ImportedCategoryColour})
+			if err := tx.Create(&existing[0]).Error; err != nil {
+				return nil, err
+			}
+		}
+		ids[key] = existing[0].ID
+		return &existing[0].ID, nil
+	}
+
+	for _, t := range transactions {
+		if t.Category != nil {
+			id, err := resolve(t.Category)
+			if err != nil {
+				return err
+			}
+			t.CategoryID, t.Category = id, nil
+		}
+		for j := range t.Splits {
+			if t.Splits[j].Category == nil {
+				continue
+			}
+			id, err := resolve(t.Splits[j].Category)
+			if err != nil {
+				return err
+			}
+			t.Splits[j].CategoryID, t.Splits[j].Category = id, nil
+		}
+	}
+	return nil
+}
+
+// ImportProfile returns the profile an import into accountID should be parsed
+// with. profileID overrides the account's default when set.
+func (s *TransactionService) ImportProfile(accountID uint, profileID *uint) (models.ImportProfile, error) {
+	return resolveImportProfile(s.db, accountID, profileID)
+}
+
+// recordImportBatch creates the batch for an import's new rows, or adds them
+// to batchID when set, and tags each row with it. A new batch also keeps any
+// rejected rows as CSV. It is not recorded for an import that creates and
+// rejects no rows.
+func recordImportBatch(tx *gorm.DB, batchID *uint, filename string, rejected *RejectedRows, transactions []*models.Transaction) (*uint, error) {
+	if batchID != nil {
+		if err := tx.Model(&models.ImportBatch{}).Where("id = ?", *batchID).
+			Update("row_count", gorm.Expr("row_count + ?", len(transactions))).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		if rejected != nil && len(rejected.Errors) == 0 {
+			rejected = nil
+		}
+		if len(transactions) == 0 && rejected == nil {
+			return nil, nil
+		}
+		batch := models.ImportBatch{Filename: filename, RowCount: len(transactions)}
+		if len(transactions) > 0 {
+			batch.AccountID = transactions[0].AccountID
+		}
+		if rejected != nil {
+			data, err := rejected.CSV()
+			if err != nil {
+				return nil, err
+			}
+			batch.AccountID, batch.Rejected, batch.RejectedCSV = rejected.AccountID, len(rejected.Errors), data
+		}
+		if err := tx.Create(&batch).Error; err != nil {
+			return nil, err
+		}
+		batchID = &batch.ID
+	}
+	for _, t := range transactions {
+		t.ImportBatchID = batchID
+	}
+	return batchID, nil
+}
+
+func createInBatches(tx *gorm.DB, transactions []*models.Transaction) error {
+	batchSize := 100
+	for i := 0; i < len(transactions); i += batchSize {
+		end := i + batchSize
+		if end > len(transactions) {
+			end = len(transactions)
+		}
+		if err := tx.Create(transactions[i:end]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func CreateTransactionFromInput(accountID uint, categoryID *uint, amount int64, description, date, txnType string) models.Transaction {
@@ -160,6 +801,60 @@ func CreateTransactionFromInput(accountID uint, categoryID *uint, amount int64,
 	}
 }
 
+func validateSplits(amount int64, splits []models.TransactionSplit) error {
+	var sum int64
+	for _, sp := range splits {
+		sum += sp.Amount
+	}
+	if sum != amount {
+		return ErrSplitSumMismatch
+	}
+	return nil
+}
+
+// checkSplitCategories returns ErrSplitCategory if a split line names a
+// category that doesn't exist.
+func checkSplitCategories(db *gorm.DB, splits []models.TransactionSplit) error {
+	ids := map[uint]bool{}
+	for _, sp := range splits {
+		if sp.CategoryID != nil {
+			ids[*sp.CategoryID] = true
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	unique := make([]uint, 0, len(ids))
+	for id := range ids {
+		unique = append(unique, id)
+	}
+	var count int64
+	if err := db.Model(&models.Category{}).Where("id IN ?", unique).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != int64(len(unique)) {
+		return ErrSplitCategory
+	}
+	return nil
+}
+
+// categoryLinesSQL expands split transactions into one row per split line so
+// per-category totals can be computed with a plain GROUP BY. Unsplit
+// transactions pass through unchanged.
+const categoryLinesSQL = `(SELECT t.id, t.account_id, t.date, t.type, t.kind, t.transfer_id,
+	COALESCE(s.category_id, t.category_id) AS category_id,
+	COALESCE(s.amount, t.amount) AS amount
+	FROM transactions t LEFT JOIN transaction_splits s ON s.transaction_id = t.id) AS lines`
+
+// randomToken returns a random 128-bit hex string for use as an opaque ID.
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func ParseListParams(queryFn func(string) string) TransactionListParams {
 	params := TransactionListParams{
 		AccountID:  queryFn("account_id"),
@@ -180,3 +875,16 @@ func ParseListParams(queryFn func(string) string) TransactionListParams {
 	}
 	return params
 }
+
+// importAccountIDs returns the distinct accounts that rows are imported into.
+func importAccountIDs(transactions []models.Transaction) []uint {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, t := range transactions {
+		if !seen[t.AccountID] {
+			seen[t.AccountID] = true
+			ids = append(ids, t.AccountID)
+		}
+	}
+	return ids
+}
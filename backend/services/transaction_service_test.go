@@ -3,6 +3,7 @@ package services
 import (
 	"budgetting-app/backend/models"
 	"budgetting-app/backend/testutil"
+	"strconv"
 	"testing"
 )
 
@@ -131,9 +132,13 @@ func TestTransactionService_ImportCSV(t *testing.T) {
 		{AccountID: account.ID, Amount: 1000, Description: "A", Date: "2024-01-01", Type: "expense"},
 		{AccountID: account.ID, Amount: 2000, Description: "B", Date: "2024-01-02", Type: "income"},
 	}
-	if err := svc.ImportCSV(txns); err != nil {
+	result, err := svc.ImportCSV(txns, ImportOptions{})
+	if err != nil {
 		t.Fatalf("import failed: %v", err)
 	}
+	if result.Imported != 2 {
+		t.Errorf("expected 2 imported, got %d", result.Imported)
+	}
 
 	_, total, _ := svc.List(TransactionListParams{})
 	if total != 2 {
@@ -186,3 +191,280 @@ func TestTransactionService_ListFilters(t *testing.T) {
 		t.Errorf("expected 1 matching search, got %d", total)
 	}
 }
+
+func TestTransactionService_Transfer(t *testing.T) {
+	svc, checking := setupTransactionTest(t)
+	savings := models.Account{Name: "Savings", Type: "savings"}
+	svc.db.Create(&savings)
+
+	legs, err := svc.CreateTransfer(CreateTransferInput{
+		FromAccountID: checking.ID, ToAccountID: savings.ID,
+		Amount: 5000, Description: "To savings", Date: "2024-01-15",
+	})
+	if err != nil {
+		t.Fatalf("create transfer failed: %v", err)
+	}
+	if len(legs) != 2 {
+		t.Fatalf("expected 2 legs, got %d", len(legs))
+	}
+	if legs[0].Type != "expense" || legs[0].AccountID != checking.ID {
+		t.Errorf("expected expense leg on checking, got %s on %d", legs[0].Type, legs[0].AccountID)
+	}
+	if legs[1].Type != "income" || legs[1].AccountID != savings.ID {
+		t.Errorf("expected income leg on savings, got %s on %d", legs[1].Type, legs[1].AccountID)
+	}
+	if *legs[0].TransferID != *legs[1].TransferID {
+		t.Error("expected both legs to share a transfer ID")
+	}
+
+	// Updating one leg updates both
+	amount := int64(7500)
+	if _, err := svc.Update(legs[1].ID, UpdateTransactionInput{Amount: &amount}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	var peer models.Transaction
+	svc.db.First(&peer, legs[0].ID)
+	if peer.Amount != 7500 {
+		t.Errorf("expected peer amount 7500, got %d", peer.Amount)
+	}
+
+	// Changing the type or category is rejected
+	income := "income"
+	if _, err := svc.Update(legs[0].ID, UpdateTransactionInput{Type: &income}); err != ErrTransferTypeChange {
+		t.Errorf("expected ErrTransferTypeChange, got %v", err)
+	}
+
+	// Deleting one leg removes both
+	if err := svc.Delete(legs[0].ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	_, total, _ := svc.List(TransactionListParams{})
+	if total != 0 {
+		t.Errorf("expected 0 transactions after delete, got %d", total)
+	}
+}
+
+func TestTransactionService_TransferSameAccount(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+
+	_, err := svc.CreateTransfer(CreateTransferInput{
+		FromAccountID: account.ID, ToAccountID: account.ID,
+		Amount: 5000, Date: "2024-01-15",
+	})
+	if err != ErrTransferSameAccount {
+		t.Errorf("expected ErrTransferSameAccount, got %v", err)
+	}
+}
+
+func TestTransactionService_Splits(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+
+	food := models.Category{Name: "Food", Colour: "#FF0000"}
+	home := models.Category{Name: "Household", Colour: "#00FF00"}
+	svc.db.Create(&food)
+	svc.db.Create(&home)
+
+	// Splits must sum to the parent amount
+	bad := models.Transaction{
+		AccountID: account.ID, Amount: 5000, Description: "Tesco", Date: "2024-01-15", Type: "expense",
+		Splits: []models.TransactionSplit{{CategoryID: &food.ID, Amount: 3000}, {CategoryID: &home.ID, Amount: 1000}},
+	}
+	if err := svc.Create(&bad); err != ErrSplitSumMismatch {
+		t.Fatalf("expected ErrSplitSumMismatch, got %v", err)
+	}
+	missing := uint(99)
+	bad.Splits = []models.TransactionSplit{{CategoryID: &food.ID, Amount: 3000}, {CategoryID: &missing, Amount: 2000}}
+	if err := svc.Create(&bad); err != ErrSplitCategory {
+		t.Fatalf("expected ErrSplitCategory, got %v", err)
+	}
+
+	txn := models.Transaction{
+		AccountID: account.ID, CategoryID: &food.ID, Amount: 5000, Description: "Tesco", Date: "2024-01-15", Type: "expense",
+		Splits: []models.TransactionSplit{{CategoryID: &food.ID, Amount: 3000}, {CategoryID: &home.ID, Amount: 2000}},
+	}
+	if err := svc.Create(&txn); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if txn.CategoryID != nil {
+		t.Error("expected split parent to have no category")
+	}
+	if len(txn.Splits) != 2 {
+		t.Fatalf("expected 2 splits, got %d", len(txn.Splits))
+	}
+
+	// Filtering by a split category finds the parent
+	_, total, _ := svc.List(TransactionListParams{CategoryID: strconv.FormatUint(uint64(home.ID), 10)})
+	if total != 1 {
+		t.Errorf("expected 1 transaction in split category, got %d", total)
+	}
+	_, total, _ = svc.List(TransactionListParams{CategoryID: "none"})
+	if total != 0 {
+		t.Errorf("expected split transaction not to be uncategorized, got %d", total)
+	}
+
+	// Changing the amount alone no longer matches the splits
+	amount := int64(6000)
+	if _, err := svc.Update(txn.ID, UpdateTransactionInput{Amount: &amount}); err != ErrSplitSumMismatch {
+		t.Errorf("expected ErrSplitSumMismatch, got %v", err)
+	}
+
+	stale := []models.TransactionSplit{{CategoryID: &missing, Amount: 5000}}
+	if _, err := svc.Update(txn.ID, UpdateTransactionInput{Splits: &stale}); err != ErrSplitCategory {
+		t.Errorf("expected ErrSplitCategory, got %v", err)
+	}
+
+	// Amount and splits can be edited together
+	splits := []models.TransactionSplit{{CategoryID: &food.ID, Amount: 2500}, {CategoryID: &home.ID, Amount: 3500}}
+	updated, err := svc.Update(txn.ID, UpdateTransactionInput{Amount: &amount, Splits: &splits})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if len(updated.Splits) != 2 || updated.Splits[1].Amount != 3500 {
+		t.Errorf("expected replaced splits, got %+v", updated.Splits)
+	}
+
+	// Bulk categorizing collapses the split
+	if _, err := svc.BulkUpdateCategory([]uint{txn.ID}, &food.ID); err != nil {
+		t.Fatalf("bulk update failed: %v", err)
+	}
+	var count int64
+	svc.db.Model(&models.TransactionSplit{}).Where("transaction_id = ?", txn.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("expected splits removed after bulk update, got %d", count)
+	}
+
+	// Deleting removes the transaction
+	if err := svc.Delete(txn.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+}
+
+func TestTransactionService_ImportCSVDuplicates(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+
+	svc.Create(&models.Transaction{AccountID: account.ID, Amount: 350, Description: "COSTA COFFEE #12", Date: "2024-01-02", Type: "expense"})
+
+	rows := func() []models.Transaction {
+		return []models.Transaction{
+			{AccountID: account.ID, Amount: 350, Description: "Costa Coffee 12", Date: "2024-01-03", Type: "expense"},
+			{AccountID: account.ID, Amount: 350, Description: "Costa Coffee 12", Date: "2024-01-03", Type: "expense"},
+			{AccountID: account.ID, Amount: 1200, Description: "Cinema", Date: "2024-01-03", Type: "expense"},
+		}
+	}
+
+	// Outside the window nothing matches
+	result, err := svc.ImportCSV(rows()[:1], ImportOptions{Duplicates: "skip"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Skipped != 0 {
+		t.Errorf("expected 0 skipped with no window, got %d", result.Skipped)
+	}
+	svc.Delete(*result.Rows[0].TransactionID)
+
+	// Within the window the first matching row is skipped; the repeat is not,
+	// because the existing row can only match once
+	result, err = svc.ImportCSV(rows(), ImportOptions{Duplicates: "skip", DuplicateWindowDays: 1})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Imported != 2 || result.Skipped != 1 {
+		t.Errorf("expected 2 imported and 1 skipped, got %d and %d", result.Imported, result.Skipped)
+	}
+	if result.Rows[0].Status != "skipped" || result.Rows[0].DuplicateOfID == nil {
+		t.Errorf("expected row 1 skipped as a duplicate, got %+v", result.Rows[0])
+	}
+
+	// Re-importing the same file in flag mode inserts and marks every row
+	result, err = svc.ImportCSV(rows(), ImportOptions{Duplicates: "flag", DuplicateWindowDays: 1})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Flagged != 3 || result.Imported != 3 {
+		t.Errorf("expected 3 flagged and imported, got %d and %d", result.Flagged, result.Imported)
+	}
+	var flagged models.Transaction
+	svc.db.First(&flagged, *result.Rows[2].TransactionID)
+	if flagged.DuplicateOfID == nil {
+		t.Error("expected flagged row to record duplicate_of_id")
+	}
+}
+
+func TestTransactionService_ImportExternalIDs(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+
+	rows := func() []models.Transaction {
+		a, b := "FIT-1", "FIT-2"
+		return []models.Transaction{
+			{AccountID: account.ID, Amount: 350, Description: "Coffee", Date: "2024-01-03", Type: "expense", ExternalID: &a},
+			{AccountID: account.ID, Amount: 350, Description: "Coffee", Date: "2024-01-03", Type: "expense", ExternalID: &b},
+		}
+	}
+
+	// Same fingerprint but different bank IDs: both are real purchases
+	result, err := svc.ImportCSV(rows(), ImportOptions{Duplicates: "skip", DuplicateWindowDays: 3})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Imported != 2 {
+		t.Errorf("expected 2 imported, got %d", result.Imported)
+	}
+
+	// A re-import is skipped by bank ID even when duplicates are allowed
+	result, err = svc.ImportCSV(rows(), ImportOptions{Duplicates: "allow"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Imported != 0 || result.Skipped != 2 {
+		t.Errorf("expected both rows skipped, got %d imported and %d skipped", result.Imported, result.Skipped)
+	}
+	if result.Rows[1].DuplicateOfID == nil {
+		t.Error("expected skipped row to name the existing transaction")
+	}
+
+	// The same bank ID in another account is a different transaction
+	other := models.Account{Name: "Other", Type: "checking"}
+	svc.db.Create(&other)
+	moved := rows()
+	moved[0].AccountID = other.ID
+	result, err = svc.ImportCSV(moved[:1], ImportOptions{Duplicates: "skip"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Imported != 1 {
+		t.Errorf("expected import into another account, got %+v", result)
+	}
+}
+
+func TestTransactionService_ListRunningBalance(t *testing.T) {
+	svc, account := setupTransactionTest(t)
+	other := models.Account{Name: "Other", Type: "checking"}
+	svc.db.Create(&other)
+	svc.db.Create(&[]models.Transaction{
+		{AccountID: account.ID, Amount: 10000, Description: "Pay", Date: "2024-01-01", Type: "income"},
+		{AccountID: account.ID, Amount: 2500, Description: "Shop", Date: "2024-01-03", Type: "expense"},
+		{AccountID: other.ID, Amount: 999, Description: "Elsewhere", Date: "2024-01-02", Type: "expense"},
+		{AccountID: account.ID, Amount: 1000, Description: "Cafe", Date: "2024-01-03", Type: "expense"},
+	})
+
+	// The running balance counts rows outside the page and the search
+	txns, _, err := svc.List(TransactionListParams{AccountID: strconv.FormatUint(uint64(account.ID), 10), Search: "Shop"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(txns) != 1 || txns[0].RunningBalance == nil || *txns[0].RunningBalance != 7500 {
+		t.Fatalf("expected Shop with running balance 7500, got %+v", txns)
+	}
+
+	txns, _, _ = svc.List(TransactionListParams{AccountID: strconv.FormatUint(uint64(account.ID), 10), Limit: 1})
+	if len(txns) != 1 || txns[0].Description != "Cafe" || *txns[0].RunningBalance != 6500 {
+		t.Errorf("expected newest row Cafe at 6500, got %+v", txns)
+	}
+
+	txns, _, _ = svc.List(TransactionListParams{})
+	for _, txn := range txns {
+		if txn.RunningBalance != nil {
+			t.Errorf("expected no running balance without an account filter")
+		}
+	}
+}
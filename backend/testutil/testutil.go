@@ -14,7 +14,7 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("failed to open test database: %v", err)
 	}
-	err = db.AutoMigrate(&models.Account{}, &models.Category{}, &models.Transaction{}, &models.BudgetAllocation{}, &models.CategoryTarget{})
+	err = db.AutoMigrate(&models.Account{}, &models.CategoryGroup{}, &models.Category{}, &models.Transaction{}, &models.TransactionSplit{}, &models.BudgetAllocation{}, &models.CategoryTarget{}, &models.RecurringTransaction{}, &models.Rule{}, &models.ImportPreview{}, &models.ImportProfile{}, &models.ImportBatch{}, &models.ImportJob{}, &models.ReconciliationCheckpoint{}, &models.BudgetMove{})
 	if err != nil {
 		t.Fatalf("failed to migrate test database: %v", err)
 	}